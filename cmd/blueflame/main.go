@@ -2,20 +2,28 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/kylegalloway/blueflame/internal/agent"
+	"github.com/kylegalloway/blueflame/internal/audit"
 	"github.com/kylegalloway/blueflame/internal/config"
+	"github.com/kylegalloway/blueflame/internal/egress"
+	"github.com/kylegalloway/blueflame/internal/estimate"
+	"github.com/kylegalloway/blueflame/internal/ledger"
 	"github.com/kylegalloway/blueflame/internal/locks"
 	"github.com/kylegalloway/blueflame/internal/memory"
 	"github.com/kylegalloway/blueflame/internal/orchestrator"
+	"github.com/kylegalloway/blueflame/internal/repocontext"
 	"github.com/kylegalloway/blueflame/internal/state"
 	"github.com/kylegalloway/blueflame/internal/tasks"
 	"github.com/kylegalloway/blueflame/internal/ui"
@@ -26,18 +34,39 @@ var (
 	version = "dev"
 )
 
+// exitRateLimited is the exit status when the session stops for a rate or
+// usage limit (EX_TEMPFAIL): retrying later with --resume will succeed.
+const exitRateLimited = 75
+
 func main() {
 	// Subcommands
 	if len(os.Args) > 1 && os.Args[1] == "cleanup" {
 		runCleanup()
 		return
 	}
+	if len(os.Args) > 1 && os.Args[1] == "policy" {
+		os.Exit(runPolicy())
+	}
+	if len(os.Args) > 1 && os.Args[1] == "audit" {
+		os.Exit(runAudit())
+	}
+	if len(os.Args) > 1 && os.Args[1] == agent.HookCommand {
+		// Internal: the PreToolUse watcher hook agents' settings invoke.
+		os.Exit(agent.RunHook(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == agent.SandboxExecCommand {
+		// Internal: confines and execs an agent command; returns only on failure.
+		err := agent.SandboxExec(os.Args[2:])
+		fmt.Fprintf(os.Stderr, "blueflame %s: %v\n", agent.SandboxExecCommand, err)
+		os.Exit(126)
+	}
 
 	configPath := flag.String("config", "blueflame.yaml", "path to blueflame.yaml config file")
 	task := flag.String("task", "", "task description for the planner")
 	dryRun := flag.Bool("dry-run", false, "show what would happen without spawning agents")
 	decisionsFile := flag.String("decisions-file", "", "path to decisions file for automated testing")
 	showVersion := flag.Bool("version", false, "print version and exit")
+	resume := flag.Bool("resume", false, "resume the interrupted or rate-limited session without prompting")
 	flag.Parse()
 
 	if *showVersion {
@@ -45,10 +74,12 @@ func main() {
 		os.Exit(0)
 	}
 
-	if *task == "" && flag.NArg() == 0 {
+	if *task == "" && flag.NArg() == 0 && !*resume {
 		fmt.Fprintln(os.Stderr, "Usage: blueflame --task 'description' [--config blueflame.yaml]")
 		fmt.Fprintln(os.Stderr, "       blueflame 'description'")
+		fmt.Fprintln(os.Stderr, "       blueflame --resume [--config blueflame.yaml]")
 		fmt.Fprintln(os.Stderr, "       blueflame cleanup [--config blueflame.yaml]")
+		fmt.Fprintln(os.Stderr, "       blueflame audit [--session id] [--task id] [--agent id] [--decision allow|block] [--records]")
 		os.Exit(1)
 	}
 
@@ -63,8 +94,14 @@ func main() {
 		os.Exit(1)
 	}
 
+	promptRenderer, err := newPromptRenderer(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading prompt templates: %v\n", err)
+		os.Exit(1)
+	}
+
 	if *dryRun {
-		printDryRun(cfg, taskDesc)
+		printDryRun(cfg, taskDesc, promptRenderer)
 		return
 	}
 
@@ -129,7 +166,14 @@ func main() {
 
 	// Handle crash recovery prompt
 	var recoveryState *state.OrchestratorState
-	if cleanupResult != nil && cleanupResult.RecoveryState != nil {
+	if *resume {
+		if cleanupResult == nil || cleanupResult.RecoveryState == nil {
+			fmt.Fprintln(os.Stderr, "Error: --resume: no interrupted session to resume")
+			lockMgr.ReleaseAll()
+			os.Exit(1)
+		}
+		recoveryState = cleanupResult.RecoveryState
+	} else if cleanupResult != nil && cleanupResult.RecoveryState != nil {
 		decision := prompter.CrashRecoveryPrompt(cleanupResult.RecoveryState)
 		switch decision {
 		case ui.RecoveryResume:
@@ -141,15 +185,33 @@ func main() {
 
 	// Create spawner and orchestrator
 	spawner := &agent.ProductionSpawner{
-		PromptRenderer: &agent.DefaultPromptRenderer{},
+		PromptRenderer: promptRenderer,
 		HooksDir:       filepath.Join(stateDir, "hooks"),
 	}
+	if sb := cfg.Sandbox; sb.Egress.Enabled && !sb.AllowNetwork {
+		proxy, err := egress.New(sb.Egress.AllowedHosts)
+		if err != nil {
+			log.Fatalf("egress proxy: %v", err)
+		}
+		defer proxy.Close()
+		spawner.Egress = proxy
+	}
 
 	orch := orchestrator.New(cfg, spawner, prompter, taskStore, stateMgr)
 	orch.SetLifecycleManager(lifecycleMgr)
 	orch.SetWorktreeManager(wtMgr)
 	orch.SetLockManager(lockMgr)
-	orch.SetHooksDir(filepath.Join(stateDir, "hooks"), agent.DefaultWatcherTemplate())
+	exe, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Failed to locate blueflame executable for hooks: %v", err)
+	}
+	orch.SetHooksDir(filepath.Join(stateDir, "hooks"), exe)
+	orch.SetSessionsDir(filepath.Join(stateDir, "sessions"))
+	if est, err := estimate.Open(filepath.Join(stateDir, estimate.FileName)); err != nil {
+		log.Printf("Warning: cost estimates: %v", err)
+	} else {
+		orch.SetEstimator(est)
+	}
 	if recoveryState != nil {
 		orch.SetRecoveryState(recoveryState)
 	}
@@ -192,6 +254,16 @@ func main() {
 	// Run orchestrator
 	startTime := time.Now()
 	if err := orch.Run(ctx, taskDesc); err != nil {
+		if errors.Is(err, orchestrator.ErrRateLimited) {
+			summary := orch.SessionSummary()
+			summary.Duration = time.Since(startTime)
+			summary.CostLimit = cfg.Limits.MaxSessionCostUSD
+			summary.TokenLimit = cfg.Limits.MaxSessionTokens
+			fmt.Print(ui.FormatCostSummary(summary))
+			fmt.Fprintf(os.Stderr, "Paused: %v\nResume later with: blueflame --resume\n", err)
+			lockMgr.ReleaseAll()
+			os.Exit(exitRateLimited)
+		}
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		lockMgr.ReleaseAll()
 		os.Exit(1)
@@ -267,7 +339,141 @@ func runCleanup() {
 	fmt.Println("\nCleanup complete.")
 }
 
-func printDryRun(cfg *config.Config, taskDesc string) {
+// runPolicy implements "blueflame policy test": it explains how the policy
+// decides one simulated tool call, or checks a fixtures file of them.
+func runPolicy() int {
+	if len(os.Args) < 3 || os.Args[2] != "test" {
+		fmt.Fprintln(os.Stderr, "usage: blueflame policy test [flags]")
+		return 2
+	}
+	policyFlags := flag.NewFlagSet("policy test", flag.ExitOnError)
+	configPath := policyFlags.String("config", "blueflame.yaml", "path to blueflame.yaml config file")
+	role := policyFlags.String("role", agent.RoleWorker, "role making the call")
+	taskID := policyFlags.String("task", "", "ID of a task in the session's tasks file")
+	fileLocks := policyFlags.String("file-locks", "", "comma-separated file locks, instead of a task from the tasks file")
+	tool := policyFlags.String("tool", "", "tool name, such as Bash or Write")
+	input := policyFlags.String("input", "{}", `tool input JSON, such as '{"command":"go test ./..."}'`)
+	fixtures := policyFlags.String("fixtures", "", "YAML file of cases to check instead of a single call")
+	policyFlags.Parse(os.Args[3:])
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		return 1
+	}
+
+	if *fixtures != "" {
+		f, err := agent.LoadPolicyFixtures(*fixtures)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		failed, err := agent.RunPolicyFixtures(f, cfg, os.Stdout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if failed > 0 {
+			return 1
+		}
+		return 0
+	}
+
+	c := agent.PolicyCase{Role: *role, Tool: *tool}
+	if err := json.Unmarshal([]byte(*input), &c.Input); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --input: %v\n", err)
+		return 1
+	}
+	if *taskID != "" && *fileLocks == "" {
+		store := tasks.NewTaskStore(filepath.Join(cfg.Project.Repo, cfg.Project.TasksFile))
+		if err := store.Load(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading tasks: %v\n", err)
+			return 1
+		}
+		if c.Task = store.FindTask(*taskID); c.Task == nil {
+			fmt.Fprintf(os.Stderr, "Error: task %s is not in %s\n", *taskID, cfg.Project.TasksFile)
+			return 1
+		}
+	} else if *taskID != "" || *fileLocks != "" {
+		c.Task = &tasks.Task{ID: *taskID}
+		if *fileLocks != "" {
+			c.Task.FileLocks = strings.Split(*fileLocks, ",")
+		}
+	}
+	e, err := agent.ExplainPolicy(c, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Print(agent.FormatPolicyExplanation(e))
+	return 0
+}
+
+// runAudit implements "blueflame audit": it summarizes what agents did, per
+// agent, from their audit logs, or lists the matching records.
+func runAudit() int {
+	auditFlags := flag.NewFlagSet("audit", flag.ExitOnError)
+	configPath := auditFlags.String("config", "blueflame.yaml", "path to blueflame.yaml config file")
+	session := auditFlags.String("session", "", "only agents of this session")
+	taskID := auditFlags.String("task", "", "only agents that worked on this task")
+	agentID := auditFlags.String("agent", "", "only this agent")
+	decision := auditFlags.String("decision", "", "only records with this decision: allow or block")
+	records := auditFlags.Bool("records", false, "list the matching records instead of summarizing them")
+	auditFlags.Parse(os.Args[2:])
+
+	if *decision != "" && *decision != audit.DecisionAllow && *decision != audit.DecisionBlock {
+		fmt.Fprintf(os.Stderr, "Error: --decision must be %s or %s\n", audit.DecisionAllow, audit.DecisionBlock)
+		return 2
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		return 1
+	}
+	stateDir := filepath.Join(cfg.Project.Repo, ".blueflame")
+
+	// The session ledgers say which session, role and tasks each agent had.
+	ledgers, _ := filepath.Glob(filepath.Join(stateDir, "sessions", "*", ledger.FileName))
+	var entries []ledger.Entry
+	for _, path := range ledgers {
+		e, err := ledger.Load(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			continue
+		}
+		entries = append(entries, e...)
+	}
+
+	logs, err := audit.Load(filepath.Join(stateDir, "hooks", "logs"), entries)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	logs = audit.Filter{Session: *session, Task: *taskID, Agent: *agentID, Decision: *decision}.Apply(logs)
+
+	if *records {
+		fmt.Print(audit.FormatRecords(logs))
+		return 0
+	}
+	summaries := make([]audit.Summary, 0, len(logs))
+	for _, l := range logs {
+		summaries = append(summaries, audit.Summarize(l))
+	}
+	fmt.Print(audit.Format(summaries))
+	return 0
+}
+
+// newPromptRenderer returns the file-based renderer when prompts.templates_dir
+// is set, validating every template up front, and the built-in one otherwise.
+func newPromptRenderer(cfg *config.Config) (agent.PromptRenderer, error) {
+	if cfg.Prompts.TemplatesDir == "" {
+		return &agent.DefaultPromptRenderer{}, nil
+	}
+	return agent.NewFilePromptRenderer(cfg.Project.ResolvePath(cfg.Prompts.TemplatesDir), &agent.DefaultPromptRenderer{})
+}
+
+func printDryRun(cfg *config.Config, taskDesc string, renderer agent.PromptRenderer) {
 	fmt.Println("=== BLUE FLAME: Dry Run ===")
 	fmt.Println()
 	fmt.Printf("Config: %s (schema v%d)\n", cfg.Project.Name, cfg.SchemaVersion)
@@ -279,14 +485,28 @@ func printDryRun(cfg *config.Config, taskDesc string) {
 	fmt.Println("Wave Configuration:")
 	fmt.Printf("  Planning: %d agent(s), model=%s, interactive=%v\n",
 		cfg.Concurrency.Planning, cfg.Models.Planner, cfg.Planning.Interactive)
+	if ens := cfg.Planning.Ensemble; cfg.Concurrency.Planning > 1 {
+		fmt.Printf("    (ensemble: models=%v, variations=%d, judge=%v)\n",
+			ens.Models, len(ens.Variations), ens.Judge)
+	}
 	fmt.Printf("  Development: up to %d workers, model=%s\n",
 		concurrency, cfg.Models.Worker)
+	for _, b := range cfg.Models.Routing.Bands {
+		fmt.Printf("    (route %s: worker=%s, validator=%s)\n", b.Name, orDefault(b.WorkerModel), orDefault(b.ValidatorModel))
+	}
+	if to := cfg.Limits.TaskOverrides; len(to.Models) > 0 || to.MaxTimeout > 0 || to.MaxBudgetUSD > 0 || to.MaxBudgetTokens > 0 {
+		fmt.Printf("    (task overrides: models=%v, max_timeout=%v)\n", to.Models, to.MaxTimeout)
+	}
 	if cfg.Concurrency.Adaptive {
 		fmt.Printf("    (adaptive: configured=%d, effective=%d based on available RAM)\n",
 			cfg.Concurrency.Development, concurrency)
 	}
 	fmt.Printf("  Validation: up to %d validators, model=%s\n",
 		cfg.Concurrency.Validation, cfg.Models.Validator)
+	if q := cfg.Validation.Quorum; q.Validators > 1 || len(q.Overrides) > 0 {
+		fmt.Printf("    (quorum: %d validator(s), policy=%s, %d override(s))\n",
+			q.Validators, q.Policy, len(q.Overrides))
+	}
 	fmt.Printf("  Merge: %d merger, model=%s\n",
 		cfg.Concurrency.Merge, cfg.Models.Merger)
 	fmt.Println()
@@ -301,6 +521,8 @@ func printDryRun(cfg *config.Config, taskDesc string) {
 	fmt.Printf("  Max wave cycles: %d\n", cfg.Limits.MaxWaveCycles)
 	fmt.Printf("  Max retries per task: %d\n", cfg.Limits.MaxRetries)
 	fmt.Printf("  Agent timeout: %v\n", cfg.Limits.AgentTimeout)
+	fmt.Printf("  On rate limit: %s (default wait %v, max wait %v)\n",
+		cfg.Limits.RateLimit.OnLimit, cfg.Limits.RateLimit.DefaultWait, cfg.Limits.RateLimit.MaxWait)
 	fmt.Println()
 
 	// Per-role budgets
@@ -311,6 +533,53 @@ func printDryRun(cfg *config.Config, taskDesc string) {
 	printBudget("  Merger", cfg.Limits.TokenBudget.MergerBudget())
 	fmt.Println()
 
+	fmt.Println("Cost Estimate:")
+	if est, err := estimate.Open(filepath.Join(cfg.Project.Repo, ".blueflame", estimate.FileName)); err != nil {
+		fmt.Printf("  Error: %v\n", err)
+	} else if stats := est.Stats(); stats.Sessions == 0 {
+		fmt.Println("  No history yet: $0.50 - $3.00 per task")
+	} else {
+		fmt.Printf("  History: %d session(s), %d worker run(s), %.2f retries per task, %.0f%% validations passed\n",
+			stats.Sessions, stats.WorkerRuns, stats.RetryRate, stats.PassRate*100)
+		fmt.Printf("  Per task: %s\n", est.Task(orchestrator.EstimateInput(&tasks.Task{}, cfg)))
+	}
+	fmt.Println()
+
+	fmt.Println("Prompts:")
+	if fr, ok := renderer.(*agent.FilePromptRenderer); ok {
+		fmt.Printf("  Templates dir: %s\n", cfg.Prompts.TemplatesDir)
+		fmt.Printf("  Overridden: %v\n", fr.Overrides())
+	} else {
+		fmt.Println("  Built-in")
+	}
+	rc := cfg.Planning.RepoContext
+	if rc.MaxTokens < 0 {
+		fmt.Println("  Repo context: disabled")
+	} else if pack, err := repocontext.Build(cfg.Project.Repo, repocontext.Options{
+		MaxTokens:     rc.MaxTokens,
+		GitLogEntries: rc.GitLogEntries,
+	}); err != nil {
+		fmt.Printf("  Repo context: error (%v)\n", err)
+	} else {
+		fmt.Printf("  Repo context: ~%d of %d tokens, %d sections\n", pack.Tokens(), rc.MaxTokens, len(pack.Sections))
+	}
+	if wc := cfg.Prompts.WorkerContext; wc.Enabled {
+		fmt.Printf("  Worker context: up to %d tokens per worker prompt (up to %d per wave)\n",
+			wc.MaxTokens, wc.MaxTokens*concurrency)
+	} else {
+		fmt.Println("  Worker context: disabled")
+	}
+	fmt.Println()
+
+	fmt.Println("Sandbox:")
+	fmt.Printf("  Memory: %d MB, CPU time: %ds, max CPUs: %v, max pids: %d\n",
+		cfg.Sandbox.MaxMemoryMB, cfg.Sandbox.MaxCPUSeconds, cfg.Sandbox.MaxCPUs, cfg.Sandbox.MaxPids)
+	fmt.Printf("  Cgroups: %s\n", agent.CgroupStatus(cfg.Sandbox))
+	fmt.Printf("  Landlock: %s\n", agent.LandlockStatus(cfg.Sandbox))
+	fmt.Printf("  Network: %s\n", agent.NetworkStatus(cfg.Sandbox))
+	fmt.Printf("  Environment: %s\n", agent.EnvStatus(cfg.Sandbox.Env))
+	fmt.Println()
+
 	fmt.Println("Permissions:")
 	fmt.Printf("  Allowed paths: %v\n", cfg.Permissions.AllowedPaths)
 	fmt.Printf("  Blocked paths: %v\n", cfg.Permissions.BlockedPaths)
@@ -340,3 +609,11 @@ func printBudget(label string, budget config.BudgetSpec) {
 		fmt.Printf("%s: unlimited\n", label)
 	}
 }
+
+// orDefault labels an unset routed model.
+func orDefault(model string) string {
+	if model == "" {
+		return "default"
+	}
+	return model
+}
@@ -0,0 +1,305 @@
+//go:build linux
+
+package agent
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/kylegalloway/blueflame/internal/config"
+)
+
+// cgroupFS is where the cgroup v2 hierarchy is mounted.
+const cgroupFS = "/sys/fs/cgroup"
+
+// cgroupControllers are the controllers agent cgroups need.
+var cgroupControllers = []string{"memory", "cpu", "pids"}
+
+var (
+	cgroupOnce   sync.Once
+	cgroupParent string
+	cgroupErr    error
+)
+
+// delegatedCgroup returns the directory agent cgroups are created under,
+// preparing it on first use. With cgroup_parent "self", the orchestrator
+// moves itself into a "supervisor" leaf of its own cgroup so the controllers
+// can be enabled for siblings (cgroup v2 forbids processes in a cgroup that
+// distributes controllers). That changes the user's session cgroup, so it
+// is only done when asked for.
+func delegatedCgroup(cfg config.SandboxConfig) (string, error) {
+	cgroupOnce.Do(func() {
+		cgroupParent, cgroupErr = prepareCgroupParent(cfg.CgroupParent)
+	})
+	return cgroupParent, cgroupErr
+}
+
+func prepareCgroupParent(parent string) (string, error) {
+	if _, err := os.Stat(filepath.Join(cgroupFS, "cgroup.controllers")); err != nil {
+		return "", errors.New("cgroup v2 is not mounted")
+	}
+	switch parent {
+	case "":
+		return "", errors.New("no sandbox.cgroup_parent configured")
+	case config.CgroupParentSelf:
+		return adoptOwnCgroup()
+	}
+	if err := enableControllers(parent); err != nil {
+		return "", err
+	}
+	return parent, nil
+}
+
+// adoptOwnCgroup moves the orchestrator into a "supervisor" leaf of its own
+// cgroup and enables the agent controllers there. If they can't be enabled,
+// for example because another process still shares the cgroup, the
+// orchestrator moves back and the leaf is removed.
+func adoptOwnCgroup() (string, error) {
+	own, err := ownCgroup()
+	if err != nil {
+		return "", err
+	}
+	if err := syscall.Access(own, 2 /* W_OK */); err != nil {
+		return "", fmt.Errorf("cgroup %s is not delegated to this user", own)
+	}
+	leaf := filepath.Join(own, "supervisor")
+	created := true
+	if err := os.Mkdir(leaf, 0o755); err != nil {
+		if !os.IsExist(err) {
+			return "", fmt.Errorf("create supervisor cgroup: %w", err)
+		}
+		created = false
+	}
+	pid := strconv.Itoa(os.Getpid())
+	if err := writeCgroupFile(leaf, "cgroup.procs", pid); err != nil {
+		if created {
+			os.Remove(leaf)
+		}
+		return "", fmt.Errorf("move orchestrator into supervisor cgroup: %w", err)
+	}
+	if err := enableControllers(own); err != nil {
+		if err := writeCgroupFile(own, "cgroup.procs", pid); err != nil {
+			log.Printf("move orchestrator back to %s: %v", own, err)
+		} else if created {
+			os.Remove(leaf)
+		}
+		return "", err
+	}
+	return own, nil
+}
+
+// ownCgroup returns the cgroup v2 directory of this process.
+func ownCgroup() (string, error) {
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", fmt.Errorf("read own cgroup: %w", err)
+	}
+	path, ok := unifiedCgroupPath(string(data))
+	if !ok {
+		return "", errors.New("not in a cgroup v2 hierarchy")
+	}
+	return filepath.Join(cgroupFS, path), nil
+}
+
+// unifiedCgroupPath extracts the "0::/path" entry of /proc/self/cgroup.
+func unifiedCgroupPath(procCgroup string) (string, bool) {
+	for _, line := range strings.Split(procCgroup, "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// enableControllers turns on the agent controllers for dir's children.
+func enableControllers(dir string) error {
+	available, err := os.ReadFile(filepath.Join(dir, "cgroup.controllers"))
+	if err != nil {
+		return fmt.Errorf("read controllers: %w", err)
+	}
+	have := strings.Fields(string(available))
+	var enable []string
+	for _, c := range cgroupControllers {
+		if !slices.Contains(have, c) {
+			return fmt.Errorf("cgroup %s does not delegate the %s controller", dir, c)
+		}
+		enable = append(enable, "+"+c)
+	}
+	if err := writeCgroupFile(dir, "cgroup.subtree_control", strings.Join(enable, " ")); err != nil {
+		return fmt.Errorf("enable controllers: %w", err)
+	}
+	return nil
+}
+
+// agentCgroup is one agent's cgroup. The directory stays open so the agent
+// can be started inside it with CLONE_INTO_CGROUP.
+type agentCgroup struct {
+	path string
+	dir  *os.File
+}
+
+// newAgentCgroup creates a cgroup for agentID with the configured limits.
+func newAgentCgroup(agentID string, cfg config.SandboxConfig) (*agentCgroup, error) {
+	parent, err := delegatedCgroup(cfg)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(parent, "agent-"+agentID)
+	if err := os.Mkdir(path, 0o755); err != nil {
+		return nil, fmt.Errorf("create agent cgroup: %w", err)
+	}
+	if err := writeCgroupLimits(path, cfg); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	dir, err := os.Open(path)
+	if err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("open agent cgroup: %w", err)
+	}
+	return &agentCgroup{path: path, dir: dir}, nil
+}
+
+// writeCgroupLimits writes memory.max, cpu.max and pids.max for cfg. The
+// whole group is OOM-killed together so no half-dead tool processes
+// linger.
+func writeCgroupLimits(dir string, cfg config.SandboxConfig) error {
+	if cfg.MaxMemoryMB > 0 {
+		if err := writeCgroupFile(dir, "memory.max", strconv.FormatInt(int64(cfg.MaxMemoryMB)<<20, 10)); err != nil {
+			return fmt.Errorf("set memory.max: %w", err)
+		}
+		// Swap accounting may be disabled, leaving no memory.swap.max; the
+		// limit above still holds.
+		if err := writeCgroupFile(dir, "memory.swap.max", "0"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("set memory.swap.max in %s: %v", dir, err)
+		}
+		if err := writeCgroupFile(dir, "memory.oom.group", "1"); err != nil {
+			return fmt.Errorf("set memory.oom.group: %w", err)
+		}
+	}
+	if cfg.MaxCPUs > 0 {
+		const period = 100000
+		quota := int64(cfg.MaxCPUs * period)
+		if err := writeCgroupFile(dir, "cpu.max", fmt.Sprintf("%d %d", quota, period)); err != nil {
+			return fmt.Errorf("set cpu.max: %w", err)
+		}
+	}
+	if cfg.MaxPids > 0 {
+		if err := writeCgroupFile(dir, "pids.max", strconv.Itoa(cfg.MaxPids)); err != nil {
+			return fmt.Errorf("set pids.max: %w", err)
+		}
+	}
+	return nil
+}
+
+func writeCgroupFile(dir, name, value string) error {
+	return os.WriteFile(filepath.Join(dir, name), []byte(value), 0o644)
+}
+
+// Usage reads peak memory, CPU time and OOM kills.
+func (c *agentCgroup) Usage() (ResourceUsage, error) {
+	return readCgroupUsage(c.path)
+}
+
+// readCgroupUsage reads memory.peak (kernel 5.19+), cpu.stat and
+// memory.events from dir.
+func readCgroupUsage(dir string) (ResourceUsage, error) {
+	var u ResourceUsage
+	if data, err := os.ReadFile(filepath.Join(dir, "memory.peak")); err == nil {
+		u.PeakMemoryBytes, _ = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	}
+	cpu, err := readKeyedFile(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return u, fmt.Errorf("read cpu.stat: %w", err)
+	}
+	u.CPUTime = time.Duration(cpu["usage_usec"]) * time.Microsecond
+	events, err := readKeyedFile(filepath.Join(dir, "memory.events"))
+	if err != nil {
+		return u, fmt.Errorf("read memory.events: %w", err)
+	}
+	u.OOMKilled = events["oom_kill"] > 0 || events["oom_group_kill"] > 0
+	return u, nil
+}
+
+// readKeyedFile parses a cgroup "key value" file.
+func readKeyedFile(path string) (map[string]int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	values := make(map[string]int64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if n, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			values[fields[0]] = n
+		}
+	}
+	return values, scanner.Err()
+}
+
+// Close kills anything left in the group and removes it.
+func (c *agentCgroup) Close() error {
+	c.dir.Close()
+	// cgroup.kill needs kernel 5.14; without it stragglers keep the
+	// directory busy until they exit.
+	writeCgroupFile(c.path, "cgroup.kill", "1")
+	var err error
+	for i := 0; i < 10; i++ {
+		if err = os.Remove(c.path); err == nil || os.IsNotExist(err) {
+			return nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return fmt.Errorf("remove agent cgroup: %w", err)
+}
+
+// CgroupStatus describes whether agents will get their own cgroup, for
+// --dry-run. It doesn't change any cgroup.
+func CgroupStatus(cfg config.SandboxConfig) string {
+	if cfg.Cgroup != config.SandboxAuto {
+		return "off (rlimits only)"
+	}
+	if cfg.CgroupParent == "" {
+		return "off: no cgroup_parent set (rlimits only)"
+	}
+	if _, err := os.Stat(filepath.Join(cgroupFS, "cgroup.controllers")); err != nil {
+		return "unavailable: cgroup v2 is not mounted (rlimits only)"
+	}
+	parent := cfg.CgroupParent
+	if parent == config.CgroupParentSelf {
+		own, err := ownCgroup()
+		if err != nil {
+			return fmt.Sprintf("unavailable: %v (rlimits only)", err)
+		}
+		parent = own
+	}
+	if err := syscall.Access(parent, 2 /* W_OK */); err != nil {
+		return fmt.Sprintf("unavailable: %s is not delegated (rlimits only)", parent)
+	}
+	available, err := os.ReadFile(filepath.Join(parent, "cgroup.controllers"))
+	if err != nil {
+		return fmt.Sprintf("unavailable: %v (rlimits only)", err)
+	}
+	for _, c := range cgroupControllers {
+		if !slices.Contains(strings.Fields(string(available)), c) {
+			return fmt.Sprintf("unavailable: %s controller not delegated (rlimits only)", c)
+		}
+	}
+	return "per-agent cgroups under " + parent
+}
@@ -0,0 +1,80 @@
+//go:build linux
+
+package agent
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kylegalloway/blueflame/internal/config"
+)
+
+func TestUnifiedCgroupPath(t *testing.T) {
+	path, ok := unifiedCgroupPath("12:memory:/legacy\n0::/user.slice/user-1000.slice/app.scope\n")
+	if !ok || path != "/user.slice/user-1000.slice/app.scope" {
+		t.Errorf("path = %q, %v", path, ok)
+	}
+	if _, ok := unifiedCgroupPath("4:memory:/legacy\n"); ok {
+		t.Error("v1-only hierarchy should not yield a path")
+	}
+}
+
+func TestWriteCgroupLimits(t *testing.T) {
+	dir := t.TempDir()
+	err := writeCgroupLimits(dir, config.SandboxConfig{MaxMemoryMB: 512, MaxCPUs: 1.5, MaxPids: 256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for file, want := range map[string]string{
+		"memory.max":       "536870912",
+		"memory.oom.group": "1",
+		"cpu.max":          "150000 100000",
+		"pids.max":         "256",
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q (%v), want %q", file, data, err, want)
+		}
+	}
+}
+
+func TestReadCgroupUsage(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "memory.peak"), []byte("104857600\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "cpu.stat"), []byte("usage_usec 2500000\nuser_usec 2000000\nsystem_usec 500000\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "memory.events"), []byte("low 0\nhigh 0\nmax 3\noom 1\noom_kill 1\noom_group_kill 0\n"), 0o644)
+
+	u, err := readCgroupUsage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.PeakMemoryBytes != 100<<20 || u.CPUTime != 2500*time.Millisecond || !u.OOMKilled {
+		t.Errorf("usage = %+v", u)
+	}
+}
+
+func TestApplySandboxLimitsMemoryFallback(t *testing.T) {
+	for name, cfg := range map[string]config.SandboxConfig{
+		"cgroup off":       {MaxMemoryMB: 1024, Cgroup: config.SandboxOff},
+		"no cgroup parent": {MaxMemoryMB: 1024, Cgroup: config.SandboxAuto},
+	} {
+		cmd := exec.Command("claude", "--print")
+		group := applySandboxLimits(cmd, cfg, sandboxOptions{agentID: "test"})
+		if group != nil {
+			t.Errorf("%s: should not create a resource group", name)
+		}
+		if !strings.Contains(cmd.Args[2], "ulimit -v 1048576") {
+			t.Errorf("%s: memory limit should fall back to ulimit -v: %s", name, cmd.Args[2])
+		}
+		if cmd.SysProcAttr.UseCgroupFD {
+			t.Errorf("%s: cgroup fd should not be used without a cgroup", name)
+		}
+	}
+	if got := CgroupStatus(config.SandboxConfig{Cgroup: config.SandboxAuto}); got != "off: no cgroup_parent set (rlimits only)" {
+		t.Errorf("CgroupStatus = %q", got)
+	}
+}
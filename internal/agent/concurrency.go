@@ -2,6 +2,8 @@ package agent
 
 import (
 	"log"
+	"math"
+	"runtime"
 
 	"github.com/kylegalloway/blueflame/internal/config"
 )
@@ -42,3 +44,52 @@ func EffectiveConcurrency(cfg *config.ConcurrencyConfig) int {
 
 	return configured
 }
+
+// ramHeadroom is the margin recommendations add to the worst observed
+// worker memory peak.
+const ramHeadroom = 1.25
+
+// ConcurrencyAdvice recommends concurrency settings from measured worker
+// usage.
+type ConcurrencyAdvice struct {
+	// Development is how many workers the host can run at once.
+	Development int
+	// MinRAMPerAgentMB is the worst observed worker peak plus headroom,
+	// for adaptive_min_ram_per_agent_mb.
+	MinRAMPerAgentMB int
+	// LimitedBy is "memory" or "cpu", whichever bounds Development.
+	LimitedBy string
+}
+
+// HostResources returns the host's available RAM in MB (0 if unknown) and
+// its CPU count.
+func HostResources() (int, int) {
+	return getAvailableRAMMB(), runtime.NumCPU()
+}
+
+// RecommendConcurrency sizes development concurrency from workers' worst
+// memory peak and their average CPU use in cores, against the host's
+// available RAM and CPUs. It returns false when there is nothing to size
+// from.
+func RecommendConcurrency(peakMemoryBytes int64, avgCores float64, availableRAMMB, cpus int) (ConcurrencyAdvice, bool) {
+	var advice ConcurrencyAdvice
+	byRAM, byCPU := math.MaxInt, math.MaxInt
+	if peakMemoryBytes > 0 {
+		advice.MinRAMPerAgentMB = max(1, int(math.Ceil(float64(peakMemoryBytes>>20)*ramHeadroom)))
+		if availableRAMMB > 0 {
+			byRAM = availableRAMMB / advice.MinRAMPerAgentMB
+		}
+	}
+	if avgCores > 0 && cpus > 0 {
+		byCPU = int(float64(cpus) / avgCores)
+	}
+	if byRAM == math.MaxInt && byCPU == math.MaxInt {
+		return advice, false
+	}
+	advice.Development, advice.LimitedBy = byRAM, "memory"
+	if byCPU < byRAM {
+		advice.Development, advice.LimitedBy = byCPU, "cpu"
+	}
+	advice.Development = max(1, advice.Development)
+	return advice, true
+}
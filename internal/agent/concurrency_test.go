@@ -50,3 +50,25 @@ func TestGetAvailableRAMMB(t *testing.T) {
 	}
 	t.Logf("Available RAM: %d MB", ram)
 }
+
+func TestRecommendConcurrency(t *testing.T) {
+	tests := []struct {
+		name      string
+		peak      int64
+		cores     float64
+		ram, cpus int
+		want      ConcurrencyAdvice
+		ok        bool
+	}{
+		{"memory bound", 800 << 20, 0.5, 4000, 16, ConcurrencyAdvice{Development: 4, MinRAMPerAgentMB: 1000, LimitedBy: "memory"}, true},
+		{"cpu bound", 100 << 20, 2, 64000, 8, ConcurrencyAdvice{Development: 4, MinRAMPerAgentMB: 125, LimitedBy: "cpu"}, true},
+		{"at least one", 8000 << 20, 0, 4000, 8, ConcurrencyAdvice{Development: 1, MinRAMPerAgentMB: 10000, LimitedBy: "memory"}, true},
+		{"nothing measured", 0, 0, 4000, 8, ConcurrencyAdvice{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := RecommendConcurrency(tt.peak, tt.cores, tt.ram, tt.cpus)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("%s: got %+v, %v; want %+v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
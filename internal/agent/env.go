@@ -0,0 +1,184 @@
+package agent
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kylegalloway/blueflame/internal/config"
+)
+
+// redacted replaces secret values in the environment audit record.
+const redacted = "[redacted]"
+
+// secretNameParts mark a variable as holding a secret when one is a word of
+// its name, as in ANTHROPIC_API_KEY or CLAUDE_CODE_OAUTH_TOKEN.
+var secretNameParts = []string{
+	"KEY", "APIKEY", "TOKEN", "SECRET", "PASSWORD", "PASSWD", "PASSPHRASE",
+	"CREDENTIAL", "CREDENTIALS", "AUTH", "OAUTH", "COOKIE", "PRIVATE", "SESSION",
+}
+
+// secretName reports whether name looks like it holds a secret.
+func secretName(name string) bool {
+	for _, word := range strings.Split(strings.ToUpper(name), "_") {
+		if slices.Contains(secretNameParts, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterEnv applies the allow and deny parts of an environment policy to
+// env. Blue Flame's own variables always pass, since agents and their hooks
+// rely on them.
+func filterEnv(env []string, policy config.EnvConfig) []string {
+	var out []string
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		own := name == EnvAgentID || name == EnvRole || name == EnvTaskID
+		if !own && (matchesAny(policy.Deny, name) || policy.Allow != nil && !matchesAny(policy.Allow, name)) {
+			continue
+		}
+		out = append(out, kv)
+	}
+	return out
+}
+
+// matchesAny reports whether name matches one of the glob patterns.
+func matchesAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// setEnv replaces or adds name in env.
+func setEnv(env []string, name, value string) []string {
+	out := slices.DeleteFunc(env, func(kv string) bool {
+		return strings.HasPrefix(kv, name+"=")
+	})
+	return append(out, name+"="+value)
+}
+
+// applyEnvPolicy rewrites cmd.Env according to the sandbox.env policy and
+// returns the agent's scratch directory if a value used $AGENT_TMP, so it
+// can be removed when the agent finishes.
+func applyEnvPolicy(cmd *exec.Cmd, policy config.EnvConfig, agentID string) (string, error) {
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	env := filterEnv(cmd.Env, policy)
+
+	var scratch string
+	var scratchErr error
+	expand := func(name string) string {
+		switch name {
+		case "WORKTREE":
+			if cmd.Dir != "" {
+				return cmd.Dir
+			}
+			wd, _ := os.Getwd()
+			return wd
+		case "AGENT_ID":
+			return agentID
+		case "AGENT_TMP":
+			if scratch == "" && scratchErr == nil {
+				scratch, scratchErr = agentScratchDir(agentID)
+			}
+			return scratch
+		}
+		return os.Getenv(name)
+	}
+
+	// Sorted so the result doesn't depend on map order.
+	names := make([]string, 0, len(policy.Set))
+	for name := range policy.Set {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		env = setEnv(env, name, os.Expand(policy.Set[name], expand))
+	}
+	if scratchErr != nil {
+		return "", scratchErr
+	}
+	cmd.Env = env
+	return scratch, nil
+}
+
+// agentScratchDir creates the agent's private scratch directory, its
+// $AGENT_TMP.
+func agentScratchDir(agentID string) (string, error) {
+	dir := filepath.Join(os.TempDir(), "blueflame-"+agentID)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("create agent scratch directory: %w", err)
+	}
+	return dir, nil
+}
+
+// EnvStatus describes the environment policy for --dry-run.
+func EnvStatus(policy config.EnvConfig) string {
+	var b strings.Builder
+	if policy.Allow == nil {
+		b.WriteString("inherited")
+	} else {
+		fmt.Fprintf(&b, "%d allowed patterns", len(policy.Allow))
+	}
+	fmt.Fprintf(&b, ", %d denied", len(policy.Deny))
+	if len(policy.Set) > 0 {
+		names := make([]string, 0, len(policy.Set))
+		for name := range policy.Set {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		fmt.Fprintf(&b, ", sets %s", strings.Join(names, ", "))
+	}
+	return b.String()
+}
+
+// redactEnv returns env as a map with secret values replaced. A value is
+// secret when its name looks like one, or, for URLs, its password part.
+func redactEnv(env []string) (map[string]string, int) {
+	out := make(map[string]string, len(env))
+	hidden := 0
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		if value != "" && secretName(name) {
+			value = redacted
+			hidden++
+		} else if u, err := url.Parse(value); err == nil && u.User != nil {
+			if _, ok := u.User.Password(); ok {
+				value = u.Redacted()
+				hidden++
+			}
+		}
+		out[name] = value
+	}
+	return out, hidden
+}
+
+// writeEnvRecord appends the agent's effective environment, redacted, to
+// its audit log.
+func writeEnvRecord(auditPath, agentID, dir string, env []string) error {
+	values, hidden := redactEnv(env)
+	rec := auditRecord{
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		AgentID:   agentID,
+		Tool:      "environment",
+		Target:    dir,
+		Decision:  "allow",
+		Rule:      "sandbox.env",
+		Details:   strconv.Itoa(len(values)) + " variables, " + strconv.Itoa(hidden) + " redacted",
+		Env:       values,
+	}
+	return appendAuditRecord(auditPath, rec)
+}
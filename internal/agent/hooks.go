@@ -5,103 +5,78 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"text/template"
+	"time"
 
 	"github.com/kylegalloway/blueflame/internal/config"
+	"github.com/kylegalloway/blueflame/internal/policy"
 	"github.com/kylegalloway/blueflame/internal/tasks"
 )
 
-// DefaultWatcherTemplate returns the built-in watcher hook template.
-func DefaultWatcherTemplate() *template.Template {
-	return template.Must(template.New("watcher.sh").Parse(watcherTemplateSource))
-}
-
-// WatcherData holds all data needed to render the watcher hook script.
+// WatcherData is an agent's compiled watcher policy. The orchestrator
+// writes it as JSON next to the agent's audit log, and "blueflame hook"
+// evaluates every tool call against its rules.
 type WatcherData struct {
-	AgentID            string
-	Role               string
-	AllowedTools       []string
-	BlockedTools       []string
-	AllowedPaths       []string
-	BlockedPaths       []string
-	AllowedCommands    []string
-	BlockedPatterns    []string
-	FileLocks          []string
-	CommitPattern      string
-	AuditLogPath       string
-	DiagnosticCommands []string
+	AgentID      string        `json:"agent_id"`
+	Role         string        `json:"role"`
+	TaskID       string        `json:"task_id,omitempty"`
+	Worktree     string        `json:"worktree,omitempty"`
+	AuditLogPath string        `json:"audit_log_path"`
+	Rules        []policy.Rule `json:"rules"`
+	// ApprovalSocket is where the orchestrator answers calls an "ask" rule
+	// matches, waiting at most AskTimeout; without it they are blocked.
+	ApprovalSocket string        `json:"approval_socket,omitempty"`
+	AskTimeout     time.Duration `json:"ask_timeout,omitempty"`
 }
 
-// GenerateWatcherHook generates the watcher shell script for an agent.
-func GenerateWatcherHook(tmplPath string, data WatcherData, outputPath string) error {
-	tmpl, err := template.ParseFiles(tmplPath)
-	if err != nil {
-		return fmt.Errorf("parse watcher template: %w", err)
-	}
-
-	if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
-		return fmt.Errorf("create hook dir: %w", err)
-	}
-
-	f, err := os.OpenFile(outputPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o755)
-	if err != nil {
-		return fmt.Errorf("create hook file: %w", err)
-	}
-	defer f.Close()
-
-	if err := tmpl.Execute(f, data); err != nil {
-		return fmt.Errorf("render watcher template: %w", err)
-	}
+// AuditLogPath returns the audit log that agentID's watcher hook and
+// egress proxy append to, under the hooks directory.
+func AuditLogPath(hooksDir, agentID string) string {
+	return filepath.Join(hooksDir, "logs", agentID+".audit.jsonl")
+}
 
-	return nil
+// auditRecord is one line of an agent's audit log, with the same fields as
+// the egress proxy's events.
+type auditRecord struct {
+	Timestamp string            `json:"timestamp"`
+	AgentID   string            `json:"agent_id"`
+	TaskID    string            `json:"task_id,omitempty"`
+	Tool      string            `json:"tool"`
+	Target    string            `json:"target"`
+	Decision  string            `json:"decision"`
+	Rule      string            `json:"rule"`
+	Details   string            `json:"details"`
+	Env       map[string]string `json:"env,omitempty"`
 }
 
-// GenerateWatcherHookFromTemplate generates the watcher from an already-parsed template.
-func GenerateWatcherHookFromTemplate(tmpl *template.Template, data WatcherData, outputPath string) error {
-	if tmpl == nil {
-		return fmt.Errorf("watcher template is nil")
+// appendAuditRecord appends rec to the audit log at auditPath.
+func appendAuditRecord(auditPath string, rec auditRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
 	}
-	if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
-		return fmt.Errorf("create hook dir: %w", err)
+	if err := os.MkdirAll(filepath.Dir(auditPath), 0o755); err != nil {
+		return err
 	}
-
-	f, err := os.OpenFile(outputPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o755)
+	f, err := os.OpenFile(auditPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
 	if err != nil {
-		return fmt.Errorf("create hook file: %w", err)
+		return err
 	}
-	defer f.Close()
-
-	if err := tmpl.Execute(f, data); err != nil {
-		return fmt.Errorf("render watcher template: %w", err)
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
 	}
-
-	return nil
+	return f.Close()
 }
 
 // BuildWatcherData constructs a WatcherData from config and task.
 func BuildWatcherData(agentID, role string, task *tasks.Task, cfg *config.Config, blueflameDir string) WatcherData {
-	data := WatcherData{
-		AgentID:         agentID,
-		Role:            role,
-		AllowedTools:    cfg.Permissions.AllowedTools,
-		BlockedTools:    cfg.Permissions.BlockedTools,
-		AllowedPaths:    cfg.Permissions.AllowedPaths,
-		BlockedPaths:    cfg.Permissions.BlockedPaths,
-		AllowedCommands: cfg.Permissions.BashRules.AllowedCommands,
-		BlockedPatterns: cfg.Permissions.BashRules.BlockedPatterns,
-		CommitPattern:   cfg.Validation.CommitFormat.Pattern,
-		AuditLogPath:    filepath.Join(blueflameDir, "logs", agentID+".audit.jsonl"),
+	return WatcherData{
+		AgentID:      agentID,
+		Role:         role,
+		TaskID:       taskID(task),
+		AuditLogPath: AuditLogPath(blueflameDir, agentID),
+		Rules:        PolicyRules(role, task, cfg),
 	}
-
-	if task != nil {
-		data.FileLocks = task.FileLocks
-	}
-
-	if cfg.Validation.ValidatorDiagnostics.Enabled {
-		data.DiagnosticCommands = cfg.Validation.ValidatorDiagnostics.Commands
-	}
-
-	return data
 }
 
 // AgentSettings represents the .claude/settings.json for a per-agent worktree.
@@ -121,16 +96,18 @@ type HookEntry struct {
 	Timeout int    `json:"timeout"`
 }
 
-// GenerateAgentSettings creates the .claude/settings.json file for an agent's worktree.
-func GenerateAgentSettings(worktreePath, watcherScriptPath string) error {
+// GenerateAgentSettings creates the .claude/settings.json file for an
+// agent's worktree, registering "<executable> hook" with the agent's policy
+// as its PreToolUse hook.
+func GenerateAgentSettings(worktreePath, executable, policyPath string) error {
 	settingsDir := filepath.Join(worktreePath, ".claude")
 	if err := os.MkdirAll(settingsDir, 0o755); err != nil {
 		return fmt.Errorf("create .claude dir: %w", err)
 	}
 
-	absWatcherPath, err := filepath.Abs(watcherScriptPath)
+	absPolicyPath, err := filepath.Abs(policyPath)
 	if err != nil {
-		return fmt.Errorf("resolve watcher path: %w", err)
+		return fmt.Errorf("resolve policy path: %w", err)
 	}
 
 	settings := AgentSettings{
@@ -138,7 +115,7 @@ func GenerateAgentSettings(worktreePath, watcherScriptPath string) error {
 			PreToolUse: []HookEntry{
 				{
 					Type:    "command",
-					Command: absWatcherPath,
+					Command: shellQuote(executable) + " " + HookCommand + " --policy " + shellQuote(absPolicyPath),
 					Timeout: 5000,
 				},
 			},
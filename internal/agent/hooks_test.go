@@ -3,94 +3,41 @@ package agent
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
-	"text/template"
 
 	"github.com/kylegalloway/blueflame/internal/config"
+	"github.com/kylegalloway/blueflame/internal/policy"
 	"github.com/kylegalloway/blueflame/internal/tasks"
 )
 
-func TestGenerateWatcherHook(t *testing.T) {
+func TestWriteHookPolicy(t *testing.T) {
 	dir := t.TempDir()
-	tmplPath := filepath.Join(dir, "watcher.sh.tmpl")
-
-	// Use the actual template
-	srcTmpl, err := os.ReadFile("../../templates/watcher.sh.tmpl")
-	if err != nil {
-		t.Fatalf("read template: %v", err)
-	}
-	os.WriteFile(tmplPath, srcTmpl, 0o644)
-
 	data := WatcherData{
-		AgentID:         "worker-abc12345",
-		Role:            "worker",
-		AllowedTools:    []string{"Read", "Write", "Edit", "Bash"},
-		BlockedTools:    []string{"WebFetch", "Task"},
-		AllowedPaths:    []string{"src/**"},
-		BlockedPaths:    []string{".env*"},
-		AllowedCommands: []string{"go test", "go build"},
-		BlockedPatterns: []string{"rm\\s+-rf", "git\\s+push"},
-		FileLocks:       []string{"pkg/middleware/"},
-		AuditLogPath:    filepath.Join(dir, "audit.jsonl"),
-	}
-
-	outputPath := filepath.Join(dir, "hooks", "watcher.sh")
-	err = GenerateWatcherHook(tmplPath, data, outputPath)
-	if err != nil {
-		t.Fatalf("GenerateWatcherHook: %v", err)
-	}
-
-	// Verify file exists and is executable
-	info, err := os.Stat(outputPath)
-	if err != nil {
-		t.Fatalf("stat hook: %v", err)
-	}
-	if info.Mode()&0o111 == 0 {
-		t.Error("hook should be executable")
+		AgentID:      "worker-abc12345",
+		Role:         "worker",
+		TaskID:       "task-001",
+		AuditLogPath: filepath.Join(dir, "audit.jsonl"),
+		Rules: []policy.Rule{
+			{Name: "tool_blocked", Effect: policy.Deny, Tools: []string{"WebFetch", "Task"}},
+			{Name: "outside_file_scope", Effect: policy.Deny, Tools: policy.WriteTools, Paths: []string{"pkg/middleware/**"}, OutsidePaths: true},
+			{Name: "bash_blocked_pattern", Effect: policy.Deny, Tools: []string{"Bash"}, Commands: []string{`rm\s+-rf`}},
+			{Name: "tool_allowed", Effect: policy.Allow, Tools: []string{"Read", "Write", "Edit", "Bash"}},
+		},
 	}
 
-	// Verify content contains key fields
-	content, _ := os.ReadFile(outputPath)
-	script := string(content)
-
-	checks := []string{
-		"worker-abc12345",  // agent ID
-		"WebFetch",         // blocked tool
-		"go test",          // allowed command
-		"rm\\s+-rf",        // blocked pattern
-		"pkg/middleware/",   // file lock
-	}
-	for _, check := range checks {
-		if !strings.Contains(script, check) {
-			t.Errorf("generated script missing %q", check)
-		}
+	policyPath := HookPolicyPath(filepath.Join(dir, "hooks"), data.AgentID)
+	if err := WriteHookPolicy(data, policyPath); err != nil {
+		t.Fatalf("WriteHookPolicy: %v", err)
 	}
-}
-
-func TestGenerateWatcherHookFromTemplate(t *testing.T) {
-	tmplContent := `#!/bin/bash
-# Agent: {{.AgentID}}
-# Role: {{.Role}}
-echo "ok"
-`
-	tmpl, err := template.New("test").Parse(tmplContent)
+	loaded, err := loadHookPolicy([]string{"--policy", policyPath})
 	if err != nil {
-		t.Fatalf("parse template: %v", err)
+		t.Fatalf("loadHookPolicy: %v", err)
 	}
-
-	dir := t.TempDir()
-	outputPath := filepath.Join(dir, "watcher.sh")
-
-	data := WatcherData{AgentID: "test-agent", Role: "worker"}
-	err = GenerateWatcherHookFromTemplate(tmpl, data, outputPath)
-	if err != nil {
-		t.Fatalf("GenerateWatcherHookFromTemplate: %v", err)
-	}
-
-	content, _ := os.ReadFile(outputPath)
-	if !strings.Contains(string(content), "test-agent") {
-		t.Error("template not rendered correctly")
+	if !reflect.DeepEqual(loaded, data) {
+		t.Errorf("round trip = %+v, want %+v", loaded, data)
 	}
 }
 
@@ -116,7 +63,7 @@ func TestBuildWatcherData(t *testing.T) {
 	}
 
 	task := &tasks.Task{
-		FileLocks: []string{"pkg/auth/"},
+		FileLocks: []string{"src/pkg/auth/"},
 	}
 
 	data := BuildWatcherData("worker-001", "worker", task, cfg, "/project/.blueflame")
@@ -127,15 +74,24 @@ func TestBuildWatcherData(t *testing.T) {
 	if data.Role != "worker" {
 		t.Errorf("Role = %q", data.Role)
 	}
-	if len(data.FileLocks) != 1 || data.FileLocks[0] != "pkg/auth/" {
-		t.Errorf("FileLocks = %v", data.FileLocks)
-	}
-	if len(data.DiagnosticCommands) != 2 {
-		t.Errorf("DiagnosticCommands = %v", data.DiagnosticCommands)
-	}
 	if !strings.Contains(data.AuditLogPath, "worker-001.audit.jsonl") {
 		t.Errorf("AuditLogPath = %q", data.AuditLogPath)
 	}
+	for _, tt := range []struct {
+		in   HookInput
+		rule string
+	}{
+		{hookInput("WebFetch", "", ""), "tool_blocked"},
+		{hookInput("Read", ".env", ""), "blocked_path"},
+		{hookInput("Write", "lib/x.go", ""), "path_not_allowed"},
+		{hookInput("Write", "src/x.go", ""), "outside_file_scope"},
+		{hookInput("Write", "src/pkg/auth/x.go", ""), "tool_allowed"},
+		{hookInput("Read", "anywhere.go", ""), "tool_allowed"},
+	} {
+		if d := data.Evaluate(tt.in); d.Rule != tt.rule {
+			t.Errorf("%s %s: rule = %s, want %s", tt.in.ToolName, tt.in.target(), d.Rule, tt.rule)
+		}
+	}
 }
 
 func TestGenerateAgentSettings(t *testing.T) {
@@ -143,10 +99,9 @@ func TestGenerateAgentSettings(t *testing.T) {
 	wtPath := filepath.Join(dir, "worktree")
 	os.MkdirAll(wtPath, 0o755)
 
-	watcherPath := filepath.Join(dir, "watcher.sh")
-	os.WriteFile(watcherPath, []byte("#!/bin/bash\necho ok"), 0o755)
+	policyPath := filepath.Join(dir, "worker 1.policy.json")
 
-	err := GenerateAgentSettings(wtPath, watcherPath)
+	err := GenerateAgentSettings(wtPath, "/usr/local/bin/blueflame", policyPath)
 	if err != nil {
 		t.Fatalf("GenerateAgentSettings: %v", err)
 	}
@@ -161,7 +116,39 @@ func TestGenerateAgentSettings(t *testing.T) {
 	if !strings.Contains(content, "PreToolUse") {
 		t.Error("settings missing PreToolUse hook")
 	}
-	if !strings.Contains(content, "watcher.sh") {
-		t.Error("settings missing watcher script path")
+	want := "'/usr/local/bin/blueflame' hook --policy '" + policyPath + "'"
+	if !strings.Contains(content, want) {
+		t.Errorf("settings missing hook command %s:\n%s", want, content)
+	}
+}
+
+func TestBuildWatcherDataTaskOverrides(t *testing.T) {
+	cfg := &config.Config{
+		Limits: config.LimitsConfig{
+			TaskOverrides: config.TaskOverrideLimits{
+				AllowedCommands: []string{"make"},
+				AllowedTools:    []string{"WebFetch"},
+			},
+		},
+		Permissions: config.PermissionsConfig{
+			AllowedTools: []string{"Read"},
+			BashRules:    config.BashRules{AllowedCommands: []string{"go test"}},
+		},
+	}
+	task := &tasks.Task{ExtraCommands: []string{"make", "curl"}, ExtraTools: []string{"WebFetch"}}
+
+	data := BuildWatcherData("worker-001", RoleWorker, task, cfg, "/project/.blueflame")
+	for command, denied := range map[string]bool{"go test ./...": false, "make build": false, "curl example.com": true} {
+		if d := data.Evaluate(hookInput("Bash", "", command)); (d.Rule == "bash_not_allowed") != denied {
+			t.Errorf("%s: rule = %s", command, d.Rule)
+		}
+	}
+	if allowed, _ := policyTools(RoleWorker, task, cfg); strings.Join(allowed, ",") != "Read,WebFetch" {
+		t.Errorf("worker tools = %v, want Read,WebFetch", allowed)
+	}
+
+	// Overrides only widen worker permissions.
+	if allowed, _ := policyTools(RoleValidator, task, cfg); slices.Contains(allowed, "WebFetch") {
+		t.Errorf("validator tools = %v", allowed)
 	}
 }
@@ -0,0 +1,247 @@
+//go:build linux
+
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/kylegalloway/blueflame/internal/config"
+)
+
+// Landlock syscalls and constants from <linux/landlock.h>. The syscall
+// numbers are the same on every architecture.
+const (
+	sysLandlockCreateRuleset = 444
+	sysLandlockAddRule       = 445
+	sysLandlockRestrictSelf  = 446
+
+	landlockCreateRulesetVersion = 1 << 0
+	landlockRulePathBeneath      = 1
+
+	prSetNoNewPrivs = 38
+
+	// oPath is O_PATH, which package syscall doesn't define.
+	oPath = 0x200000
+)
+
+// Filesystem access rights that modify something. Read and execute rights
+// are left unhandled, so reads stay unrestricted.
+const (
+	accessFSWriteFile  = 1 << 1
+	accessFSRemoveDir  = 1 << 4
+	accessFSRemoveFile = 1 << 5
+	accessFSMakeChar   = 1 << 6
+	accessFSMakeDir    = 1 << 7
+	accessFSMakeReg    = 1 << 8
+	accessFSMakeSock   = 1 << 9
+	accessFSMakeFifo   = 1 << 10
+	accessFSMakeBlock  = 1 << 11
+	accessFSMakeSym    = 1 << 12
+	accessFSRefer      = 1 << 13 // ABI 2
+	accessFSTruncate   = 1 << 14 // ABI 3
+
+	accessFSWriteV1 = accessFSWriteFile | accessFSRemoveDir | accessFSRemoveFile |
+		accessFSMakeChar | accessFSMakeDir | accessFSMakeReg | accessFSMakeSock |
+		accessFSMakeFifo | accessFSMakeBlock | accessFSMakeSym
+)
+
+type landlockRulesetAttr struct {
+	handledAccessFS uint64
+}
+
+// landlockPathBeneathAttr matches the kernel's packed 12-byte struct; the
+// kernel reads only the first 12 bytes.
+type landlockPathBeneathAttr struct {
+	allowedAccess uint64
+	parentFD      int32
+}
+
+var (
+	landlockOnce    sync.Once
+	landlockVersion int
+)
+
+// landlockABI returns the kernel's Landlock ABI version, or 0 when
+// Landlock is unsupported or disabled.
+func landlockABI() int {
+	landlockOnce.Do(func() {
+		v, _, errno := syscall.Syscall(sysLandlockCreateRuleset, 0, 0, landlockCreateRulesetVersion)
+		if errno == 0 {
+			landlockVersion = int(v)
+		}
+	})
+	return landlockVersion
+}
+
+// handledWriteAccess returns the write rights the given ABI can restrict.
+func handledWriteAccess(abi int) uint64 {
+	access := uint64(accessFSWriteV1)
+	if abi >= 2 {
+		access |= accessFSRefer
+	}
+	if abi >= 3 {
+		access |= accessFSTruncate
+	}
+	return access
+}
+
+// confinesWrites reports whether agents' writes will be confined with
+// Landlock.
+func confinesWrites(cfg config.SandboxConfig) bool {
+	return cfg.Landlock == config.SandboxAuto && landlockABI() != 0
+}
+
+// LandlockStatus describes whether agent writes will be confined, for
+// --dry-run.
+func LandlockStatus(cfg config.SandboxConfig) string {
+	if cfg.Landlock != config.SandboxAuto {
+		return "off (watcher hook and postcheck only)"
+	}
+	abi := landlockABI()
+	if abi == 0 {
+		return "unavailable: kernel lacks Landlock (watcher hook and postcheck only)"
+	}
+	return fmt.Sprintf("ABI v%d: writes confined to the working directory, its commit paths in .git, a private temp dir, audit logs and %v",
+		abi, cfg.WritablePaths)
+}
+
+// landlockWritable lists the paths a confined agent may write: its
+// working directory, what a commit in a linked worktree touches in the
+// shared .git, /dev/null and /dev/tty, extra paths (such as its scratch
+// directory), and the configured writable paths.
+func landlockWritable(dir string, cfg config.SandboxConfig, extra []string) []string {
+	paths := []string{"/dev/null", "/dev/tty"}
+	if dir != "" {
+		paths = append(paths, dir)
+		paths = append(paths, worktreeCommitPaths(dir)...)
+	}
+	paths = append(paths, extra...)
+	home, _ := os.UserHomeDir()
+	for _, p := range cfg.WritablePaths {
+		if rest, ok := strings.CutPrefix(p, "~"); ok && home != "" {
+			p = filepath.Join(home, rest)
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// worktreeCommitPaths returns the paths in the shared .git that a commit in
+// the linked worktree dir writes: the worktree's own admin directory
+// (<repo>/.git/worktrees/<name>, holding HEAD and the index), the object
+// store, and the directories holding the checked-out branch's ref and
+// reflog. The rest of .git, including hooks, config and the base branch,
+// stays read-only, since the orchestrator's unconfined git would act on
+// it. It returns nil when dir isn't a linked worktree; an agent working in
+// the main checkout can write its .git like any other file there.
+func worktreeCommitPaths(dir string) []string {
+	adminDir := worktreeGitDir(dir)
+	if adminDir == "" {
+		return nil
+	}
+	common := filepath.Dir(filepath.Dir(adminDir))
+	paths := []string{adminDir, filepath.Join(common, "objects")}
+
+	head, err := os.ReadFile(filepath.Join(adminDir, "HEAD"))
+	if err != nil {
+		return paths
+	}
+	ref, ok := strings.CutPrefix(strings.TrimSpace(string(head)), "ref: ")
+	if !ok {
+		return paths // detached HEAD lives in the admin directory
+	}
+	// Updating a ref writes a lock file beside it, so its directory must
+	// be writable. Task branches live in their own namespace
+	// (blueflame/<task>); a branch directly under refs/heads would open up
+	// every branch, so it gets nothing.
+	refDir := path.Dir(ref)
+	if refDir == "refs/heads" || !strings.HasPrefix(refDir, "refs/heads/") {
+		return paths
+	}
+	return append(paths,
+		filepath.Join(common, filepath.FromSlash(refDir)),
+		filepath.Join(common, "logs", filepath.FromSlash(refDir)))
+}
+
+// worktreeGitDir returns the admin directory of the linked worktree dir,
+// which dir/.git points to, or "" when dir isn't a linked worktree.
+func worktreeGitDir(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, ".git"))
+	if err != nil {
+		return "" // missing, or a directory in a main checkout
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+	if !ok {
+		return ""
+	}
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(dir, gitDir)
+	}
+	return filepath.Clean(gitDir)
+}
+
+// landlockRestrict confines the calling thread's writes to the given paths.
+// Paths that don't exist are skipped.
+func landlockRestrict(writable []string) error {
+	abi := landlockABI()
+	if abi == 0 {
+		return errors.New("not supported by this kernel")
+	}
+	handled := handledWriteAccess(abi)
+	attr := landlockRulesetAttr{handledAccessFS: handled}
+	fd, _, errno := syscall.Syscall(sysLandlockCreateRuleset, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("create ruleset: %w", errno)
+	}
+	defer syscall.Close(int(fd))
+
+	for _, p := range writable {
+		if err := landlockAllow(int(fd), p, handled); err != nil {
+			return err
+		}
+	}
+
+	if _, _, errno := syscall.Syscall6(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0, 0, 0, 0); errno != 0 {
+		return fmt.Errorf("set no_new_privs: %w", errno)
+	}
+	if _, _, errno := syscall.Syscall(sysLandlockRestrictSelf, fd, 0, 0); errno != 0 {
+		return fmt.Errorf("restrict self: %w", errno)
+	}
+	return nil
+}
+
+// landlockAllow adds a rule granting write access beneath path. Files only
+// accept file rights.
+func landlockAllow(rulesetFD int, path string, handled uint64) error {
+	f, err := os.OpenFile(path, oPath|syscall.O_CLOEXEC, 0)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", path, err)
+	}
+	allowed := handled
+	if !info.IsDir() {
+		allowed &= accessFSWriteFile | accessFSTruncate
+	}
+	rule := landlockPathBeneathAttr{allowedAccess: allowed, parentFD: int32(f.Fd())}
+	_, _, errno := syscall.Syscall6(sysLandlockAddRule, uintptr(rulesetFD), landlockRulePathBeneath,
+		uintptr(unsafe.Pointer(&rule)), 0, 0, 0)
+	if errno != 0 {
+		return fmt.Errorf("allow %s: %w", path, errno)
+	}
+	return nil
+}
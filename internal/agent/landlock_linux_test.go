@@ -0,0 +1,161 @@
+//go:build linux
+
+package agent
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kylegalloway/blueflame/internal/config"
+)
+
+func TestWorktreeCommitPaths(t *testing.T) {
+	repo := t.TempDir()
+	admin := filepath.Join(repo, ".git", "worktrees", "task-001")
+	os.MkdirAll(admin, 0o755)
+	worktree := filepath.Join(repo, ".trees", "task-001")
+	os.MkdirAll(worktree, 0o755)
+	os.WriteFile(filepath.Join(worktree, ".git"), []byte("gitdir: "+admin+"\n"), 0o644)
+
+	os.WriteFile(filepath.Join(admin, "HEAD"), []byte("ref: refs/heads/blueflame/task-001\n"), 0o644)
+	want := []string{
+		admin,
+		filepath.Join(repo, ".git", "objects"),
+		filepath.Join(repo, ".git", "refs", "heads", "blueflame"),
+		filepath.Join(repo, ".git", "logs", "refs", "heads", "blueflame"),
+	}
+	if got := worktreeCommitPaths(worktree); !slices.Equal(got, want) {
+		t.Errorf("task branch paths = %v, want %v", got, want)
+	}
+
+	// A branch directly under refs/heads would share its directory with the
+	// base branch.
+	os.WriteFile(filepath.Join(admin, "HEAD"), []byte("ref: refs/heads/main\n"), 0o644)
+	if got := worktreeCommitPaths(worktree); !slices.Equal(got, want[:2]) {
+		t.Errorf("top-level branch paths = %v, want %v", got, want[:2])
+	}
+
+	if got := worktreeCommitPaths(repo); got != nil {
+		t.Errorf("main checkout paths = %v, want none", got)
+	}
+}
+
+// TestLandlockConfinesWorktreeAgent confines a helper process the way a
+// worker in a linked worktree is confined, then checks that it can commit
+// and use its scratch directory but can't plant hooks, edit the shared
+// config, move the base branch or write elsewhere in /tmp.
+func TestLandlockConfinesWorktreeAgent(t *testing.T) {
+	if dir := os.Getenv("BLUEFLAME_LANDLOCK_WORKTREE"); dir != "" {
+		runtime.LockOSThread()
+		worktree := filepath.Join(dir, "repo", ".trees", "worker-1")
+		scratch := filepath.Join(dir, "scratch")
+		writable := landlockWritable(worktree, config.SandboxConfig{}, []string{scratch})
+		if err := landlockRestrict(writable); err != nil {
+			fmt.Println("restrict:", err)
+			os.Exit(2)
+		}
+		failed := false
+		check := func(what string, err error, wantOK bool) {
+			if (err == nil) != wantOK {
+				fmt.Printf("%s: err = %v, want allowed = %v\n", what, err, wantOK)
+				failed = true
+			}
+		}
+		check("write worktree file", os.WriteFile(filepath.Join(worktree, "a.go"), []byte("package a\n"), 0o644), true)
+		check("write scratch file", os.WriteFile(filepath.Join(scratch, "tmp"), nil, 0o644), true)
+		commit := exec.Command("git", "-C", worktree, "commit", "-q", "-a", "--allow-empty", "-m", "work")
+		if out, err := commit.CombinedOutput(); err != nil {
+			check("git commit: "+string(out), err, true)
+		}
+		check("write .git/hooks", os.WriteFile(filepath.Join(dir, "repo", ".git", "hooks", "post-merge"), []byte("#!/bin/sh\n"), 0o755), false)
+		check("write .git/config", os.WriteFile(filepath.Join(dir, "repo", ".git", "config"), nil, 0o644), false)
+		check("write base branch ref", os.WriteFile(filepath.Join(dir, "repo", ".git", "refs", "heads", "main"), nil, 0o644), false)
+		check("write sibling /tmp dir", os.WriteFile(filepath.Join(dir, "sibling", "socket"), nil, 0o644), false)
+		check("make dir in /tmp", os.Mkdir(filepath.Join(os.TempDir(), "blueflame-landlock-test"), 0o700), false)
+		if failed {
+			os.Exit(3)
+		}
+		os.Exit(0)
+	}
+	if landlockABI() == 0 {
+		t.Skip("kernel lacks Landlock")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir, err := os.MkdirTemp("", "blueflame-landlock-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	repo := filepath.Join(dir, "repo")
+	for _, d := range []string{repo, filepath.Join(dir, "scratch"), filepath.Join(dir, "sibling")} {
+		os.MkdirAll(d, 0o755)
+	}
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.email", "test@test.com"},
+		{"config", "user.name", "Test"},
+		{"commit", "-q", "--allow-empty", "-m", "initial"},
+		{"worktree", "add", "-q", "-b", "blueflame/task-001", filepath.Join(repo, ".trees", "worker-1"), "main"},
+	} {
+		cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s: %v", args, out, err)
+		}
+	}
+	base, _ := exec.Command("git", "-C", repo, "rev-parse", "main").Output()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestLandlockConfinesWorktreeAgent$")
+	cmd.Env = append(os.Environ(), "BLUEFLAME_LANDLOCK_WORKTREE="+dir)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("confined agent: %v\n%s", err, out)
+	}
+
+	log, err := exec.Command("git", "-C", repo, "log", "--format=%s", "blueflame/task-001").Output()
+	if err != nil || !strings.HasPrefix(string(log), "work\n") {
+		t.Errorf("task branch log = %q, %v; want the confined commit", log, err)
+	}
+	if after, _ := exec.Command("git", "-C", repo, "rev-parse", "main").Output(); string(after) != string(base) {
+		t.Errorf("base branch moved from %s to %s", base, after)
+	}
+}
+
+// TestLandlockConfinesWrites runs a helper process that confines itself and
+// then tries to write inside and outside its writable directory.
+func TestLandlockConfinesWrites(t *testing.T) {
+	if dir := os.Getenv("BLUEFLAME_LANDLOCK_HELPER"); dir != "" {
+		runtime.LockOSThread() // Landlock confines only this thread
+		if err := landlockRestrict([]string{filepath.Join(dir, "ok")}); err != nil {
+			os.Exit(2)
+		}
+		if os.WriteFile(filepath.Join(dir, "ok", "file"), nil, 0o644) != nil {
+			os.Exit(3)
+		}
+		if os.WriteFile(filepath.Join(dir, "denied"), nil, 0o644) == nil {
+			os.Exit(4)
+		}
+		os.Exit(0)
+	}
+	if landlockABI() == 0 {
+		t.Skip("kernel lacks Landlock")
+	}
+
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "ok"), 0o755)
+	cmd := exec.Command(os.Args[0], "-test.run=^TestLandlockConfinesWrites$")
+	cmd.Env = append(os.Environ(), "BLUEFLAME_LANDLOCK_HELPER="+dir)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("helper failed: %v\n%s", err, out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ok", "file")); err != nil {
+		t.Errorf("write inside the writable dir failed: %v", err)
+	}
+}
@@ -27,6 +27,12 @@ type AgentEntry struct {
 	CostUSD      float64           `json:"cost_usd"`
 	TokensUsed   int               `json:"tokens_used"`
 	Budget       config.BudgetSpec `json:"budget"`
+	Timeout      time.Duration     `json:"timeout,omitempty"`
+	// Resources is refreshed from the agent's process group sampler on
+	// every heartbeat, and set from its result when it finishes.
+	Resources ResourceUsage `json:"resources"`
+
+	sampler usageSampler
 }
 
 // LifecycleManager tracks running agent processes with heartbeat monitoring.
@@ -95,6 +101,8 @@ func (lm *LifecycleManager) Register(a *Agent) error {
 		StartTime: a.Started,
 		Status:    "running",
 		Budget:    a.Budget,
+		Timeout:   a.Timeout,
+		sampler:   a.sandbox.sampler,
 	}
 	if a.Task != nil {
 		entry.TaskID = a.Task.ID
@@ -121,6 +129,7 @@ func (lm *LifecycleManager) Unregister(agentID string, result AgentResult) {
 		}
 		entry.CostUSD = result.CostUSD
 		entry.TokensUsed = result.TokensUsed
+		entry.Resources = result.Resources
 		delete(lm.agents, agentID)
 	}
 	lm.mu.Unlock()
@@ -175,7 +184,7 @@ func (lm *LifecycleManager) checkAgents() {
 			continue
 		}
 		// Timeout check
-		if time.Since(entry.StartTime) > lm.agentTimeout {
+		if time.Since(entry.StartTime) > lm.timeoutFor(entry) {
 			timedOut = append(timedOut, entry)
 			continue
 		}
@@ -183,8 +192,12 @@ func (lm *LifecycleManager) checkAgents() {
 		if lm.isStalled(entry) {
 			stalled = append(stalled, entry)
 		}
+		if entry.sampler != nil {
+			entry.Resources = entry.sampler.Usage()
+		}
 	}
 	lm.mu.Unlock()
+	lm.persist()
 
 	// Handle dead agents
 	for _, entry := range dead {
@@ -193,7 +206,7 @@ func (lm *LifecycleManager) checkAgents() {
 
 	// Kill timed out agents
 	for _, entry := range timedOut {
-		log.Printf("Agent %s timed out after %v, killing", entry.ID, lm.agentTimeout)
+		log.Printf("Agent %s timed out after %v, killing", entry.ID, lm.timeoutFor(entry))
 		lm.KillAgent(entry.ID, "timeout")
 	}
 
@@ -203,6 +216,15 @@ func (lm *LifecycleManager) checkAgents() {
 	}
 }
 
+// timeoutFor returns the agent's own timeout if it has one, else the
+// manager's default.
+func (lm *LifecycleManager) timeoutFor(entry *AgentEntry) time.Duration {
+	if entry.Timeout > 0 {
+		return entry.Timeout
+	}
+	return lm.agentTimeout
+}
+
 // isStalled checks if an agent's audit log hasn't been modified recently.
 // Must be called with lm.mu held.
 func (lm *LifecycleManager) isStalled(entry *AgentEntry) bool {
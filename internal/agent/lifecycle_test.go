@@ -336,3 +336,14 @@ func TestLifecycleNoPersistPath(t *testing.T) {
 	// persist should be a no-op (no panic)
 	lm.persist()
 }
+
+func TestLifecyclePerAgentTimeout(t *testing.T) {
+	lm := NewLifecycleManager(LifecycleConfig{AgentTimeout: time.Minute})
+
+	if got := lm.timeoutFor(&AgentEntry{}); got != time.Minute {
+		t.Errorf("default timeout = %v, want 1m", got)
+	}
+	if got := lm.timeoutFor(&AgentEntry{Timeout: 10 * time.Minute}); got != 10*time.Minute {
+		t.Errorf("per-agent timeout = %v, want 10m", got)
+	}
+}
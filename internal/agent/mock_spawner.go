@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os/exec"
+	"sync"
 	"time"
 
 	"github.com/kylegalloway/blueflame/internal/config"
@@ -18,12 +19,28 @@ type MockSpawner struct {
 	WorkerResults map[string]MockResult
 	// PlannerResult is the result the planner will produce.
 	PlannerResult *MockResult
+	// PlannerResults, if set, are returned by successive planner calls in
+	// order (the last one repeats) and take precedence over PlannerResult.
+	PlannerResults []MockResult
+	// PlannerCalls records the prompt data of every planner call.
+	PlannerCalls []PlannerPromptData
 	// ValidatorResults maps task IDs to predetermined validation results.
 	ValidatorResults map[string]MockResult
+	// ValidatorCalls records the task ID of every validator call.
+	ValidatorCalls []string
+	// ValidatorModelResults maps validator models to results and takes
+	// precedence over ValidatorResults, for testing validator quorums.
+	ValidatorModelResults map[string]MockResult
 	// MergerResult is the result the merger will produce.
 	MergerResult *MockResult
+	// JudgeResult is the result the ensemble judge will produce.
+	JudgeResult *MockResult
+	// JudgeCalls records the prompt data of every judge call.
+	JudgeCalls []JudgePromptData
 	// Delay is how long mock agents take to "run".
 	Delay time.Duration
+
+	mu sync.Mutex
 }
 
 // MockResult defines what a mock agent will produce.
@@ -31,16 +48,33 @@ type MockResult struct {
 	ExitCode int
 	Output   string
 	Err      error
+	// Resources is reported as sampled usage for worker runs.
+	Resources ResourceUsage
 }
 
-func (m *MockSpawner) SpawnPlanner(ctx context.Context, description string, priorContext string, cfg *config.Config) (*Agent, error) {
-	if m.PlannerResult != nil && m.PlannerResult.Err != nil {
-		return nil, m.PlannerResult.Err
+// staticSampler reports fixed usage in place of sampling.
+type staticSampler ResourceUsage
+
+func (s staticSampler) Usage() ResourceUsage { return ResourceUsage(s) }
+func (s staticSampler) Stop() ResourceUsage  { return ResourceUsage(s) }
+
+func (m *MockSpawner) SpawnPlanner(ctx context.Context, data PlannerPromptData, cfg *config.Config) (*Agent, error) {
+	m.mu.Lock()
+	call := len(m.PlannerCalls)
+	m.PlannerCalls = append(m.PlannerCalls, data)
+	m.mu.Unlock()
+
+	result := m.PlannerResult
+	if len(m.PlannerResults) > 0 {
+		result = &m.PlannerResults[min(call, len(m.PlannerResults)-1)]
+	}
+	if result != nil && result.Err != nil {
+		return nil, result.Err
 	}
 
 	output := `{"result": "planned"}`
-	if m.PlannerResult != nil && m.PlannerResult.Output != "" {
-		output = m.PlannerResult.Output
+	if result != nil && result.Output != "" {
+		output = result.Output
 	}
 
 	return m.createMockAgent("planner-mock0001", RolePlanner, nil, output, cfg)
@@ -65,12 +99,20 @@ func (m *MockSpawner) SpawnWorker(ctx context.Context, task *tasks.Task, cfg *co
 }
 
 func (m *MockSpawner) SpawnValidator(ctx context.Context, task *tasks.Task, diff string, auditSummary string, cfg *config.Config) (*Agent, error) {
-	if result, ok := m.ValidatorResults[task.ID]; ok && result.Err != nil {
+	m.mu.Lock()
+	m.ValidatorCalls = append(m.ValidatorCalls, task.ID)
+	m.mu.Unlock()
+
+	result, ok := m.ValidatorModelResults[cfg.Models.Validator]
+	if !ok {
+		result, ok = m.ValidatorResults[task.ID]
+	}
+	if ok && result.Err != nil {
 		return nil, result.Err
 	}
 
 	output := `{"status": "pass", "notes": "looks good"}`
-	if result, ok := m.ValidatorResults[task.ID]; ok && result.Output != "" {
+	if ok && result.Output != "" {
 		output = result.Output
 	}
 
@@ -93,12 +135,33 @@ func (m *MockSpawner) SpawnMerger(ctx context.Context, branches []BranchInfo, cf
 	return m.createMockAgent("merger-mock0001", RoleMerger, nil, output, cfg)
 }
 
+func (m *MockSpawner) SpawnJudge(ctx context.Context, data JudgePromptData, cfg *config.Config) (*Agent, error) {
+	m.mu.Lock()
+	m.JudgeCalls = append(m.JudgeCalls, data)
+	m.mu.Unlock()
+
+	if m.JudgeResult != nil && m.JudgeResult.Err != nil {
+		return nil, m.JudgeResult.Err
+	}
+
+	output := `{"ranking": [], "notes": "no opinion"}`
+	if m.JudgeResult != nil && m.JudgeResult.Output != "" {
+		output = m.JudgeResult.Output
+	}
+
+	return m.createMockAgent("judge-mock0001", RoleJudge, nil, output, cfg)
+}
+
 func (m *MockSpawner) createMockAgent(id, role string, task *tasks.Task, output string, cfg *config.Config) (*Agent, error) {
 	// Determine exit code from MockResult
 	exitCode := 0
+	var sampled ResourceUsage
 	if task != nil {
 		if result, ok := m.WorkerResults[task.ID]; ok {
 			exitCode = result.ExitCode
+			if role == RoleWorker {
+				sampled = result.Resources
+			}
 		}
 	}
 
@@ -125,15 +188,21 @@ func (m *MockSpawner) createMockAgent(id, role string, task *tasks.Task, output
 	}
 
 	var budget config.BudgetSpec
+	var model string
 	switch role {
-	case RolePlanner:
+	case RolePlanner, RoleJudge:
 		budget = cfg.Limits.TokenBudget.PlannerBudget()
+		model = cfg.Models.Planner
 	case RoleWorker:
-		budget = cfg.Limits.TokenBudget.WorkerBudget()
+		settings, _ := ResolveTaskSettings(task, cfg)
+		budget = settings.Budget
+		model = settings.Model
 	case RoleValidator:
 		budget = cfg.Limits.TokenBudget.ValidatorBudget()
+		model = cfg.Models.Validator
 	case RoleMerger:
 		budget = cfg.Limits.TokenBudget.MergerBudget()
+		model = cfg.Models.Merger
 	}
 
 	return &Agent{
@@ -144,7 +213,9 @@ func (m *MockSpawner) createMockAgent(id, role string, task *tasks.Task, output
 		Stderr:  &stderr,
 		Started: time.Now(),
 		Role:    role,
+		Model:   model,
 		Budget:  budget,
+		sandbox: agentSandbox{sampler: staticSampler(sampled)},
 	}, nil
 }
 
@@ -161,17 +232,23 @@ func MockCollectResult(agent *Agent) AgentResult {
 
 	result := AgentResult{
 		AgentID:    agent.ID,
+		Role:       agent.Role,
+		Model:      agent.Model,
 		ExitCode:   exitCode,
 		Output:     output,
 		RawStdout:  agent.Stdout.Bytes(),
 		RawStderr:  agent.Stderr.Bytes(),
 		CostUSD:    output.TotalCostUSD,
-		TokensUsed: output.Usage.InputTokens + output.Usage.OutputTokens,
+		TokensUsed: output.TotalUsage().Total(),
+		Usage:      output.TotalUsage(),
+		ModelUsage: output.ModelUsage,
 		Duration:   time.Since(agent.Started),
 		Err:        err,
 	}
 	if agent.Task != nil {
 		result.TaskID = agent.Task.ID
 	}
+	result.Resources = agent.sandbox.usage(agent.Cmd.ProcessState)
+	classifyFailure(&result)
 	return result
 }
@@ -0,0 +1,152 @@
+package agent
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/kylegalloway/blueflame/internal/config"
+	"github.com/kylegalloway/blueflame/internal/tasks"
+)
+
+// TaskSettings are a worker's effective model, budget, timeout and
+// permissions after applying its task's overrides.
+type TaskSettings struct {
+	Model           string
+	Budget          config.BudgetSpec
+	Timeout         time.Duration
+	AllowedTools    []string
+	AllowedCommands []string
+}
+
+// ResolveTaskSettings applies a task's routing band and then its overrides
+// to the worker-wide settings. Overrides beyond limits.task_overrides are
+// clamped or dropped; each adjustment is described in the returned notes.
+// A nil task gets the worker-wide settings.
+func ResolveTaskSettings(task *tasks.Task, cfg *config.Config) (TaskSettings, []string) {
+	s := TaskSettings{
+		Model:           cfg.Models.Worker,
+		Budget:          cfg.Limits.TokenBudget.WorkerBudget(),
+		Timeout:         cfg.Limits.AgentTimeout,
+		AllowedTools:    append([]string{}, cfg.Permissions.AllowedTools...),
+		AllowedCommands: append([]string{}, cfg.Permissions.BashRules.AllowedCommands...),
+	}
+	if task == nil {
+		return s, nil
+	}
+	lim := cfg.Limits.TaskOverrides
+	var notes []string
+	notef := func(format string, args ...interface{}) {
+		notes = append(notes, fmt.Sprintf(format, args...))
+	}
+
+	if task.Route != "" {
+		if band := cfg.Models.Routing.Band(task.Route); band == nil {
+			notef("route %q is not in models.routing.bands; using the default worker", task.Route)
+		} else {
+			if band.WorkerModel != "" {
+				s.Model = band.WorkerModel
+			}
+			if b := band.WorkerBudget(); b.Value > 0 {
+				s.Budget = b
+			}
+		}
+	}
+
+	if task.Model != "" && task.Model != s.Model {
+		if slices.Contains(lim.Models, task.Model) {
+			s.Model = task.Model
+		} else {
+			notef("model %q is not in limits.task_overrides.models; using %q", task.Model, s.Model)
+		}
+	}
+
+	if task.BudgetUSD > 0 || task.BudgetTokens > 0 {
+		if task.BudgetUSD > 0 && task.BudgetTokens > 0 {
+			notef("both budget_usd and budget_tokens set; using budget_tokens")
+		}
+		want := config.BudgetSpec{Unit: config.USD, Value: task.BudgetUSD}
+		ceiling := lim.MaxBudgetUSD
+		if task.BudgetTokens > 0 {
+			want = config.BudgetSpec{Unit: config.Tokens, Value: float64(task.BudgetTokens)}
+			ceiling = float64(lim.MaxBudgetTokens)
+		}
+		if ceiling == 0 && s.Budget.Value > 0 {
+			if s.Budget.Unit != want.Unit {
+				ceiling = -1 // switching units would escape the worker budget
+			} else {
+				ceiling = s.Budget.Value
+			}
+		}
+		switch {
+		case ceiling < 0:
+			notef("budget override has no ceiling in its unit under limits.task_overrides; using the worker budget")
+		case ceiling > 0 && want.Value > ceiling:
+			notef("budget %s exceeds the ceiling; clamped to %s", formatBudget(want), formatBudget(config.BudgetSpec{Unit: want.Unit, Value: ceiling}))
+			want.Value = ceiling
+			s.Budget = want
+		default:
+			s.Budget = want
+		}
+	}
+
+	if task.Timeout > 0 {
+		ceiling := lim.MaxTimeout
+		if ceiling == 0 {
+			ceiling = cfg.Limits.AgentTimeout
+		}
+		s.Timeout = task.Timeout
+		if ceiling > 0 && task.Timeout > ceiling {
+			notef("timeout %v exceeds the ceiling; clamped to %v", task.Timeout, ceiling)
+			s.Timeout = ceiling
+		}
+	}
+
+	for _, tool := range task.ExtraTools {
+		switch {
+		case slices.Contains(s.AllowedTools, tool):
+		case !slices.Contains(lim.AllowedTools, tool) || slices.Contains(cfg.Permissions.BlockedTools, tool):
+			notef("tool %q is not in limits.task_overrides.allowed_tools; dropped", tool)
+		default:
+			s.AllowedTools = append(s.AllowedTools, tool)
+		}
+	}
+	for _, c := range task.ExtraCommands {
+		switch {
+		case slices.Contains(s.AllowedCommands, c):
+		case !slices.Contains(lim.AllowedCommands, c):
+			notef("command %q is not in limits.task_overrides.allowed_commands; dropped", c)
+		default:
+			s.AllowedCommands = append(s.AllowedCommands, c)
+		}
+	}
+	return s, notes
+}
+
+// RoutedValidatorConfig returns cfg, or a copy with the validator model and
+// budget of the task's routing band.
+func RoutedValidatorConfig(task *tasks.Task, cfg *config.Config) *config.Config {
+	if task == nil || task.Route == "" {
+		return cfg
+	}
+	band := cfg.Models.Routing.Band(task.Route)
+	if band == nil || (band.ValidatorModel == "" && band.ValidatorBudget().Value == 0) {
+		return cfg
+	}
+	c := *cfg
+	if band.ValidatorModel != "" {
+		c.Models.Validator = band.ValidatorModel
+	}
+	if band.ValidatorBudget().Value > 0 {
+		c.Limits.TokenBudget.ValidatorUSD = band.ValidatorUSD
+		c.Limits.TokenBudget.ValidatorTokens = band.ValidatorTokens
+	}
+	return &c
+}
+
+func formatBudget(b config.BudgetSpec) string {
+	if b.Unit == config.Tokens {
+		return fmt.Sprintf("%.0f tokens", b.Value)
+	}
+	return fmt.Sprintf("$%.2f", b.Value)
+}
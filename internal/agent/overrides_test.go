@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kylegalloway/blueflame/internal/config"
+	"github.com/kylegalloway/blueflame/internal/tasks"
+)
+
+func overridesConfig() *config.Config {
+	return &config.Config{
+		Models: config.ModelsConfig{Worker: "sonnet"},
+		Limits: config.LimitsConfig{
+			AgentTimeout: 5 * time.Minute,
+			TokenBudget:  config.TokenBudget{WorkerUSD: 1.00},
+			TaskOverrides: config.TaskOverrideLimits{
+				Models:       []string{"opus"},
+				MaxBudgetUSD: 3.00,
+				MaxTimeout:   20 * time.Minute,
+			},
+		},
+		Permissions: config.PermissionsConfig{
+			AllowedTools: []string{"Read", "Edit"},
+			BlockedTools: []string{"WebFetch"},
+		},
+	}
+}
+
+func TestResolveTaskSettingsDefaults(t *testing.T) {
+	s, notes := ResolveTaskSettings(&tasks.Task{ID: "task-001"}, overridesConfig())
+	if s.Model != "sonnet" || s.Budget.Value != 1.00 || s.Timeout != 5*time.Minute || len(notes) != 0 {
+		t.Errorf("settings = %+v, notes = %v", s, notes)
+	}
+}
+
+func TestResolveTaskSettingsWithinCeilings(t *testing.T) {
+	task := &tasks.Task{Model: "opus", BudgetUSD: 2.50, Timeout: 15 * time.Minute}
+	s, notes := ResolveTaskSettings(task, overridesConfig())
+	if s.Model != "opus" || s.Budget.Unit != config.USD || s.Budget.Value != 2.50 || s.Timeout != 15*time.Minute {
+		t.Errorf("settings = %+v", s)
+	}
+	if len(notes) != 0 {
+		t.Errorf("unexpected notes: %v", notes)
+	}
+}
+
+func TestResolveTaskSettingsClampsAndDrops(t *testing.T) {
+	cfg := overridesConfig()
+	cfg.Limits.TaskOverrides.AllowedTools = []string{"WebFetch", "Bash"}
+	task := &tasks.Task{
+		Model:         "gpt",
+		BudgetUSD:     10,
+		Timeout:       time.Hour,
+		ExtraTools:    []string{"Bash", "WebFetch", "Task"},
+		ExtraCommands: []string{"make"},
+	}
+	s, notes := ResolveTaskSettings(task, cfg)
+
+	if s.Model != "sonnet" {
+		t.Errorf("model = %q, want unlisted model ignored", s.Model)
+	}
+	if s.Budget.Value != 3.00 || s.Timeout != 20*time.Minute {
+		t.Errorf("budget = %v, timeout = %v, want clamped to ceilings", s.Budget.Value, s.Timeout)
+	}
+	if got := strings.Join(s.AllowedTools, ","); got != "Read,Edit,Bash" {
+		t.Errorf("tools = %s, want blocked and unlisted tools dropped", got)
+	}
+	if len(s.AllowedCommands) != 0 {
+		t.Errorf("commands = %v, want unlisted command dropped", s.AllowedCommands)
+	}
+	if len(notes) != 6 {
+		t.Errorf("notes = %d, want 6: %v", len(notes), notes)
+	}
+}
+
+func TestResolveTaskSettingsDefaultCeilings(t *testing.T) {
+	cfg := overridesConfig()
+	cfg.Limits.TaskOverrides = config.TaskOverrideLimits{}
+
+	// Without explicit ceilings a task can only tighten its limits.
+	s, _ := ResolveTaskSettings(&tasks.Task{BudgetUSD: 0.50, Timeout: time.Hour}, cfg)
+	if s.Budget.Value != 0.50 || s.Timeout != 5*time.Minute {
+		t.Errorf("budget = %v, timeout = %v", s.Budget.Value, s.Timeout)
+	}
+
+	// Switching to a token budget would escape the USD budget.
+	s, notes := ResolveTaskSettings(&tasks.Task{BudgetTokens: 1_000_000}, cfg)
+	if s.Budget.Unit != config.USD || s.Budget.Value != 1.00 || len(notes) != 1 {
+		t.Errorf("budget = %+v, notes = %v", s.Budget, notes)
+	}
+}
+
+func TestResolveTaskSettingsRouting(t *testing.T) {
+	cfg := overridesConfig()
+	cfg.Models.Validator = "haiku"
+	cfg.Limits.TaskOverrides.Models = []string{"opus", "sonnet"}
+	cfg.Models.Routing.Bands = []config.RoutingBand{
+		{Name: "hard", WorkerModel: "opus", WorkerUSD: 2.00, ValidatorModel: "sonnet", ValidatorTokens: 50000},
+	}
+
+	s, notes := ResolveTaskSettings(&tasks.Task{Route: "hard"}, cfg)
+	if s.Model != "opus" || s.Budget.Value != 2.00 || len(notes) != 0 {
+		t.Errorf("routed settings = %+v, notes = %v", s, notes)
+	}
+
+	// An explicit override still wins, and is capped by the routed budget.
+	s, _ = ResolveTaskSettings(&tasks.Task{Route: "hard", Model: "sonnet", BudgetUSD: 1.50}, cfg)
+	if s.Model != "sonnet" || s.Budget.Value != 1.50 {
+		t.Errorf("override on routed task = %+v", s)
+	}
+
+	vcfg := RoutedValidatorConfig(&tasks.Task{Route: "hard"}, cfg)
+	if vcfg.Models.Validator != "sonnet" || vcfg.Limits.TokenBudget.ValidatorBudget().Value != 50000 {
+		t.Errorf("routed validator = %s %+v", vcfg.Models.Validator, vcfg.Limits.TokenBudget.ValidatorBudget())
+	}
+	if cfg.Models.Validator != "haiku" {
+		t.Error("RoutedValidatorConfig must not modify the shared config")
+	}
+
+	_, notes = ResolveTaskSettings(&tasks.Task{Route: "gone"}, cfg)
+	if len(notes) != 1 || !strings.Contains(notes[0], `route "gone"`) {
+		t.Errorf("unknown route notes = %v", notes)
+	}
+}
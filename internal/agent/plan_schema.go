@@ -0,0 +1,151 @@
+package agent
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kylegalloway/blueflame/internal/config"
+	"github.com/kylegalloway/blueflame/internal/tasks"
+)
+
+// PlanValidationError lists every schema problem found in a planner output,
+// so the planner can be asked to fix them all in one repair attempt.
+type PlanValidationError struct {
+	Problems []string
+}
+
+func (e *PlanValidationError) Error() string {
+	return "invalid plan: " + strings.Join(e.Problems, "; ")
+}
+
+// reservedLockPrefixes are paths no task may lock.
+var reservedLockPrefixes = []string{".git/", ".blueflame/"}
+
+// ValidatePlannerOutput checks a parsed plan against the planner schema:
+// required fields, unique IDs, positive priorities, file locks that are
+// relative paths inside the repo, well-formed overrides and complexity
+// estimates, known dependency IDs, and no cycles.
+// It returns a *PlanValidationError listing all problems, or nil.
+func ValidatePlannerOutput(out *PlannerOutput) error {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if len(out.Tasks) == 0 {
+		addf("plan has no tasks")
+	}
+
+	ids := make(map[string]bool, len(out.Tasks))
+	for i, t := range out.Tasks {
+		label := t.ID
+		if label == "" {
+			label = fmt.Sprintf("tasks[%d]", i)
+			addf("%s: missing id", label)
+		} else if ids[t.ID] {
+			addf("%s: duplicate id", label)
+		}
+		ids[t.ID] = true
+
+		if strings.TrimSpace(t.Title) == "" {
+			addf("%s: missing title", label)
+		}
+		if strings.TrimSpace(t.Description) == "" {
+			addf("%s: missing description", label)
+		}
+		if t.Priority < 1 {
+			addf("%s: priority must be >= 1, got %d", label, t.Priority)
+		}
+		if len(t.FileLocks) == 0 {
+			addf("%s: file_locks must list at least one path", label)
+		}
+		for _, lock := range t.FileLocks {
+			if msg := checkLockPath(lock); msg != "" {
+				addf("%s: file_locks entry %q %s", label, lock, msg)
+			}
+		}
+		if t.BudgetUSD < 0 || t.BudgetTokens < 0 {
+			addf("%s: budget must be >= 0", label)
+		}
+		if t.BudgetUSD > 0 && t.BudgetTokens > 0 {
+			addf("%s: set at most one of budget_usd and budget_tokens", label)
+		}
+		if t.Timeout != "" {
+			if d, err := time.ParseDuration(t.Timeout); err != nil || d <= 0 {
+				addf("%s: timeout %q must be a positive duration like \"15m\"", label, t.Timeout)
+			}
+		}
+		if c := t.Complexity; c != nil {
+			if c.Files < 0 {
+				addf("%s: complexity.files must be >= 0, got %d", label, c.Files)
+			}
+			if c.Risk != "" && !slices.Contains(config.RiskLevels, c.Risk) {
+				addf("%s: complexity.risk must be one of %s, got %q", label, strings.Join(config.RiskLevels, ", "), c.Risk)
+			}
+			if c.Size != "" && !slices.Contains(config.SizeLevels, c.Size) {
+				addf("%s: complexity.size must be one of %s, got %q", label, strings.Join(config.SizeLevels, ", "), c.Size)
+			}
+		}
+	}
+
+	depsKnown := true
+	for _, t := range out.Tasks {
+		for _, dep := range t.Dependencies {
+			switch {
+			case dep == t.ID:
+				addf("%s: depends on itself", t.ID)
+				depsKnown = false
+			case !ids[dep]:
+				addf("%s: depends on unknown task %q", t.ID, dep)
+				depsKnown = false
+			}
+		}
+	}
+
+	// Cycle detection only makes sense once IDs are unique and deps resolve.
+	if depsKnown && len(ids) == len(out.Tasks) {
+		storeTasks := make([]tasks.Task, len(out.Tasks))
+		for i, t := range out.Tasks {
+			storeTasks[i] = tasks.Task{ID: t.ID, Dependencies: t.Dependencies}
+		}
+		if err := tasks.ValidateDependencies(storeTasks); err != nil {
+			addf("%v", err)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &PlanValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// checkLockPath returns why a file lock path is unusable, or "".
+func checkLockPath(lock string) string {
+	p := filepath.ToSlash(strings.TrimSpace(lock))
+	switch {
+	case p == "":
+		return "is empty"
+	case path.IsAbs(p) || filepath.IsAbs(lock):
+		return "must be relative to the repo root"
+	case p == "." || p == "./" || p == "*" || p == "**":
+		return "locks the whole repository"
+	}
+	for _, seg := range strings.Split(p, "/") {
+		if seg == ".." {
+			return "must not leave the repository"
+		}
+	}
+	for _, prefix := range reservedLockPrefixes {
+		if p == strings.TrimSuffix(prefix, "/") || strings.HasPrefix(p, prefix) {
+			return "is a reserved path"
+		}
+	}
+	if _, err := path.Match(p, ""); err != nil {
+		return "is not a valid glob"
+	}
+	return ""
+}
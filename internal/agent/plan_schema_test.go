@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kylegalloway/blueflame/internal/tasks"
+)
+
+func TestValidatePlannerOutput(t *testing.T) {
+	valid := func() PlannerTask {
+		return PlannerTask{ID: "task-001", Title: "T", Description: "d", Priority: 1, FileLocks: []string{"pkg/a/"}}
+	}
+
+	tests := []struct {
+		name  string
+		tasks func() []PlannerTask
+		want  []string // substrings of expected problems; empty means valid
+	}{
+		{"valid", func() []PlannerTask { return []PlannerTask{valid()} }, nil},
+		{"no tasks", func() []PlannerTask { return nil }, []string{"no tasks"}},
+		{"missing fields", func() []PlannerTask {
+			t := valid()
+			t.ID, t.Title, t.Description = "", "", ""
+			return []PlannerTask{t}
+		}, []string{"tasks[0]: missing id", "missing title", "missing description"}},
+		{"duplicate id", func() []PlannerTask { return []PlannerTask{valid(), valid()} }, []string{"task-001: duplicate id"}},
+		{"bad priority", func() []PlannerTask {
+			t := valid()
+			t.Priority = 0
+			return []PlannerTask{t}
+		}, []string{"priority must be >= 1"}},
+		{"bad locks", func() []PlannerTask {
+			t := valid()
+			t.FileLocks = []string{"/etc/passwd", "../outside", ".", ".git/config", "pkg/[a"}
+			return []PlannerTask{t}
+		}, []string{"must be relative", "must not leave", "whole repository", "reserved path", "not a valid glob"}},
+		{"no locks", func() []PlannerTask {
+			t := valid()
+			t.FileLocks = nil
+			return []PlannerTask{t}
+		}, []string{"at least one path"}},
+		{"unknown and self deps", func() []PlannerTask {
+			t := valid()
+			t.Dependencies = []string{"task-001", "task-404"}
+			return []PlannerTask{t}
+		}, []string{"depends on itself", `unknown task "task-404"`}},
+		{"bad overrides", func() []PlannerTask {
+			t := valid()
+			t.BudgetUSD, t.BudgetTokens, t.Timeout = 1, 1000, "soon"
+			return []PlannerTask{t}
+		}, []string{"at most one of budget_usd and budget_tokens", `timeout "soon"`}},
+		{"bad complexity", func() []PlannerTask {
+			t := valid()
+			t.Complexity = &tasks.Complexity{Files: -1, Risk: "extreme", Size: "huge"}
+			return []PlannerTask{t}
+		}, []string{"complexity.files", `complexity.risk must be one of low, medium, high, got "extreme"`, "complexity.size"}},
+		{"cycle", func() []PlannerTask {
+			a, b := valid(), valid()
+			b.ID = "task-002"
+			a.Dependencies = []string{"task-002"}
+			b.Dependencies = []string{"task-001"}
+			return []PlannerTask{a, b}
+		}, []string{"circular dependency"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePlannerOutput(&PlannerOutput{Tasks: tt.tasks()})
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var verr *PlanValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("expected PlanValidationError, got %v", err)
+			}
+			joined := strings.Join(verr.Problems, "\n")
+			for _, w := range tt.want {
+				if !strings.Contains(joined, w) {
+					t.Errorf("problems missing %q:\n%s", w, joined)
+				}
+			}
+		})
+	}
+}
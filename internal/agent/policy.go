@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/kylegalloway/blueflame/internal/config"
+	"github.com/kylegalloway/blueflame/internal/policy"
+	"github.com/kylegalloway/blueflame/internal/tasks"
+)
+
+// readOnlyRoleTools are what validators and mergers may use, and
+// readOnlyRoleDenied what they never get.
+var (
+	readOnlyRoleTools  = map[string][]string{RoleValidator: {"Read", "Glob", "Grep", "Bash"}, RoleMerger: {"Bash", "Read", "Glob", "Grep"}}
+	readOnlyRoleDenied = []string{"Write", "Edit", "WebFetch", "WebSearch", "NotebookEdit", "Task"}
+)
+
+// fileScopeRule is the built-in rule keeping a worker's writes inside its
+// task's file locks.
+const fileScopeRule = "outside_file_scope"
+
+// PolicyRules returns the rules that govern role working on task, which
+// may be nil: the configured policy.rules, then rules compiled
+// from the permissions lists, the task's file locks and overrides, and the
+// role's built-in tool set, ending with a rule denying everything else.
+func PolicyRules(role string, task *tasks.Task, cfg *config.Config) []policy.Rule {
+	rules := policy.FromConfig(cfg.Policy.Rules)
+	add := func(r policy.Rule) { rules = append(rules, r) }
+
+	perms := cfg.Permissions
+	if len(perms.BlockedTools) > 0 {
+		add(policy.Rule{Name: "tool_blocked", Effect: policy.Deny, Tools: perms.BlockedTools,
+			Reason: "tool is in permissions.blocked_tools"})
+	}
+	if len(perms.BlockedPaths) > 0 {
+		add(policy.Rule{Name: "blocked_path", Effect: policy.Deny, Paths: perms.BlockedPaths,
+			Reason: "path matches permissions.blocked_paths"})
+	}
+
+	switch role {
+	case RoleValidator, RoleMerger:
+		if role == RoleValidator {
+			var diagnostics []string
+			if cfg.Validation.ValidatorDiagnostics.Enabled {
+				for _, c := range cfg.Validation.ValidatorDiagnostics.Commands {
+					diagnostics = append(diagnostics, policy.CommandPrefix(c))
+				}
+			}
+			add(policy.Rule{Name: "validator_bash_restricted", Effect: policy.Deny, Roles: []string{role}, Tools: []string{"Bash"},
+				Commands: diagnostics, OutsideCommands: true, Reason: "validator Bash is restricted to diagnostic commands"})
+		}
+		add(policy.Rule{Name: "role_tools", Effect: policy.Allow, Roles: []string{role}, Tools: readOnlyRoleTools[role]})
+		add(policy.Rule{Name: "read_only_role", Effect: policy.Deny, Roles: []string{role}, Tools: readOnlyRoleDenied,
+			Reason: role + "s do not modify files or use the network"})
+	case RoleWorker:
+		settings, _ := ResolveTaskSettings(task, cfg)
+		if len(perms.AllowedPaths) > 0 {
+			add(policy.Rule{Name: "path_not_allowed", Effect: policy.Deny, Tools: policy.WriteTools,
+				Paths: perms.AllowedPaths, OutsidePaths: true, Reason: "path is outside permissions.allowed_paths"})
+		}
+		if task != nil && len(task.FileLocks) > 0 {
+			add(policy.Rule{Name: fileScopeRule, Effect: policy.Deny, Tools: policy.WriteTools,
+				Paths: policy.LockPatterns(task.FileLocks), OutsidePaths: true, Reason: "path is outside the task's file_locks"})
+		}
+		if len(perms.BashRules.BlockedPatterns) > 0 {
+			add(policy.Rule{Name: "bash_blocked_pattern", Effect: policy.Deny, Tools: []string{"Bash"},
+				Commands: perms.BashRules.BlockedPatterns, Reason: "command matches permissions.bash_rules.blocked_patterns"})
+		}
+		allowed := make([]string, 0, len(settings.AllowedCommands))
+		for _, c := range settings.AllowedCommands {
+			allowed = append(allowed, policy.CommandPrefix(c))
+		}
+		add(policy.Rule{Name: "bash_not_allowed", Effect: policy.Deny, Tools: []string{"Bash"},
+			Commands: allowed, OutsideCommands: true, Reason: "command is not in permissions.bash_rules.allowed_commands"})
+		if len(settings.AllowedTools) > 0 {
+			add(policy.Rule{Name: "tool_allowed", Effect: policy.Allow, Tools: settings.AllowedTools})
+		}
+	}
+
+	add(policy.Rule{Name: "tool_not_allowed", Effect: policy.Deny, Reason: "no permission allows this tool"})
+	return rules
+}
+
+// CompilePolicy compiles the rules for role working on task.
+func CompilePolicy(role string, task *tasks.Task, cfg *config.Config) (*policy.Policy, error) {
+	p, err := policy.New(PolicyRules(role, task, cfg))
+	if err != nil {
+		return nil, fmt.Errorf("compile %s policy: %w", role, err)
+	}
+	return p, nil
+}
+
+// postCheckPolicy compiles the rules PostCheck judges a worker's changes
+// by. The watcher always keeps writes inside the task's file locks, but
+// PostCheck checks them after the fact only with
+// validation.file_scope.enforce.
+func postCheckPolicy(task *tasks.Task, cfg *config.Config) (*policy.Policy, error) {
+	rules := PolicyRules(RoleWorker, task, cfg)
+	if !cfg.Validation.FileScope.Enforce {
+		// Skip the configured rules, which may reuse the name.
+		for i := len(cfg.Policy.Rules); i < len(rules); i++ {
+			if rules[i].Name == fileScopeRule {
+				rules = slices.Delete(rules, i, i+1)
+				break
+			}
+		}
+	}
+	p, err := policy.New(rules)
+	if err != nil {
+		return nil, fmt.Errorf("compile %s policy: %w", RoleWorker, err)
+	}
+	return p, nil
+}
+
+// policyTools returns role's --allowed-tools and --disallowed-tools as the
+// compiled policy derives them. A policy that doesn't compile (config
+// validation rules this out) allows nothing.
+func policyTools(role string, task *tasks.Task, cfg *config.Config) (allowed, disallowed []string) {
+	p, err := CompilePolicy(role, task, cfg)
+	if err != nil {
+		return nil, nil
+	}
+	return p.Tools(role, taskID(task))
+}
+
+func taskID(task *tasks.Task) string {
+	if task == nil {
+		return ""
+	}
+	return task.ID
+}
@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kylegalloway/blueflame/internal/config"
+	"github.com/kylegalloway/blueflame/internal/policy"
+	"github.com/kylegalloway/blueflame/internal/tasks"
+)
+
+// PolicyCase is a simulated tool call for "blueflame policy test": the role
+// making it, its task, and the tool input Claude would send the watcher
+// hook. In a fixtures file it also names the decision it should get.
+type PolicyCase struct {
+	Name  string         `yaml:"name"`
+	Role  string         `yaml:"role"`
+	Task  *tasks.Task    `yaml:"task,omitempty"`
+	Tool  string         `yaml:"tool"`
+	Input map[string]any `yaml:"input,omitempty"`
+	Want  PolicyWant     `yaml:"want"`
+}
+
+// PolicyWant is a fixture's expected decision. An empty Rule accepts any
+// rule with the right effect.
+type PolicyWant struct {
+	Effect string `yaml:"effect"`
+	Rule   string `yaml:"rule,omitempty"`
+}
+
+// PolicyFixtures is a table of policy test cases, kept alongside
+// blueflame.yaml so policy changes can be checked in CI.
+type PolicyFixtures struct {
+	Cases []PolicyCase `yaml:"cases"`
+}
+
+// PolicyExplanation is how the policy decided a simulated tool call.
+type PolicyExplanation struct {
+	Decision policy.Decision
+	Request  policy.Request
+	// Index is the deciding rule's position in Rules, or -1 when no rule
+	// matched.
+	Index int
+	Rules []policy.Rule
+}
+
+// ExplainPolicy evaluates c against cfg's policy exactly as the watcher
+// hook would, and reports the rule that decided it.
+func ExplainPolicy(c PolicyCase, cfg *config.Config) (*PolicyExplanation, error) {
+	if !slices.Contains(config.PolicyRoles, c.Role) {
+		return nil, fmt.Errorf("role %q must be one of %v", c.Role, config.PolicyRoles)
+	}
+	if c.Tool == "" {
+		return nil, errors.New("no tool given")
+	}
+	in := HookInput{ToolName: c.Tool}
+	if len(c.Input) > 0 {
+		raw, err := json.Marshal(c.Input)
+		if err != nil {
+			return nil, fmt.Errorf("tool input: %w", err)
+		}
+		if err := json.Unmarshal(raw, &in.ToolInput); err != nil {
+			return nil, fmt.Errorf("tool input: %w", err)
+		}
+	}
+
+	compiled, err := CompilePolicy(c.Role, c.Task, cfg)
+	if err != nil {
+		return nil, err
+	}
+	watcher := BuildWatcherData("policy-test", c.Role, c.Task, cfg, "")
+	req := watcher.request(in)
+	return &PolicyExplanation{
+		Decision: watcher.Evaluate(in),
+		Request:  req,
+		Index:    compiled.Match(req),
+		Rules:    compiled.Rules,
+	}, nil
+}
+
+// FormatPolicyExplanation renders an explanation for the terminal.
+func FormatPolicyExplanation(e *PolicyExplanation) string {
+	var b strings.Builder
+	req := e.Request
+	fmt.Fprintf(&b, "Call:     %s %s", req.Role, req.Tool)
+	if req.Path != "" {
+		fmt.Fprintf(&b, " %s", req.Path)
+	}
+	if req.Command != "" {
+		fmt.Fprintf(&b, " %q", req.Command)
+	}
+	if req.TaskID != "" {
+		fmt.Fprintf(&b, " (task %s)", req.TaskID)
+	}
+	b.WriteString("\n")
+	fmt.Fprintf(&b, "Decision: %s\n", e.Decision.Effect)
+	if e.Index < 0 {
+		fmt.Fprintf(&b, "Rule:     none of the %d rules matched, so the call is denied\n", len(e.Rules))
+		return b.String()
+	}
+	r := e.Rules[e.Index]
+	fmt.Fprintf(&b, "Rule:     %s (rule %d of %d)\n", r.Name, e.Index+1, len(e.Rules))
+	fmt.Fprintf(&b, "Matches:  %s\n", r)
+	fmt.Fprintf(&b, "Reason:   %s\n", e.Decision.Reason)
+	if e.Decision.Effect == policy.Ask {
+		b.WriteString("          (the watcher blocks this until someone approves it)\n")
+	}
+	return b.String()
+}
+
+// LoadPolicyFixtures reads and checks a fixtures file.
+func LoadPolicyFixtures(path string) (*PolicyFixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read fixtures: %w", err)
+	}
+	var f PolicyFixtures
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse fixtures %s: %w", path, err)
+	}
+	for i := range f.Cases {
+		c := &f.Cases[i]
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("cases[%d]", i)
+		}
+		if !slices.Contains(config.PolicyEffects, c.Want.Effect) {
+			return nil, fmt.Errorf("%s: want.effect %q must be one of %v", c.Name, c.Want.Effect, config.PolicyEffects)
+		}
+	}
+	return &f, nil
+}
+
+// RunPolicyFixtures evaluates every case, writing one line per case to w,
+// and returns how many got a different decision than they want.
+func RunPolicyFixtures(f *PolicyFixtures, cfg *config.Config, w io.Writer) (int, error) {
+	failed := 0
+	for _, c := range f.Cases {
+		e, err := ExplainPolicy(c, cfg)
+		if err != nil {
+			return failed, fmt.Errorf("%s: %w", c.Name, err)
+		}
+		got := e.Decision
+		if string(got.Effect) == c.Want.Effect && (c.Want.Rule == "" || got.Rule == c.Want.Rule) {
+			fmt.Fprintf(w, "ok    %s\n", c.Name)
+			continue
+		}
+		failed++
+		want := c.Want.Effect
+		if c.Want.Rule != "" {
+			want += " by " + c.Want.Rule
+		}
+		fmt.Fprintf(w, "FAIL  %s: got %s by %s, want %s\n", c.Name, got.Effect, got.Rule, want)
+	}
+	fmt.Fprintf(w, "%d passed, %d failed\n", len(f.Cases)-failed, failed)
+	return failed, nil
+}
@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kylegalloway/blueflame/internal/config"
+	"github.com/kylegalloway/blueflame/internal/tasks"
+)
+
+func TestExplainPolicy(t *testing.T) {
+	cfg := &config.Config{
+		Policy: config.PolicyConfig{Rules: []config.PolicyRule{
+			{Name: "no_migrations", Effect: "deny", Paths: []string{"db/migrations/**"}, Reason: "migrations are written by hand"},
+		}},
+		Permissions: config.PermissionsConfig{AllowedTools: []string{"Read", "Write"}},
+	}
+	e, err := ExplainPolicy(PolicyCase{
+		Role:  RoleWorker,
+		Task:  &tasks.Task{ID: "task-001", FileLocks: []string{"db/"}},
+		Tool:  "Write",
+		Input: map[string]any{"file_path": "db/migrations/001.sql"},
+	}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Index != 0 || e.Decision.Rule != "no_migrations" {
+		t.Errorf("explanation = %+v", e)
+	}
+	out := FormatPolicyExplanation(e)
+	for _, want := range []string{"worker Write db/migrations/001.sql (task task-001)", "no_migrations (rule 1 of", "paths db/migrations/**", "written by hand"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	if _, err := ExplainPolicy(PolicyCase{Role: "reviewer", Tool: "Read"}, cfg); err == nil {
+		t.Error("expected error for an unknown role")
+	}
+}
+
+func TestRunPolicyFixtures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy-tests.yaml")
+	fixtures := `cases:
+  - name: reads are fine
+    role: worker
+    tool: Read
+    input: {file_path: main.go}
+    want: {effect: allow}
+  - role: worker
+    task: {id: task-001, file_locks: [pkg/]}
+    tool: Edit
+    input: {file_path: main.go}
+    want: {effect: allow, rule: tool_allowed}
+`
+	if err := os.WriteFile(path, []byte(fixtures), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := LoadPolicyFixtures(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{Permissions: config.PermissionsConfig{AllowedTools: []string{"Read", "Edit"}}}
+	var out strings.Builder
+	failed, err := RunPolicyFixtures(f, cfg, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed != 1 || !strings.Contains(out.String(), "FAIL  cases[1]: got deny by outside_file_scope, want allow by tool_allowed") {
+		t.Errorf("failed = %d, output:\n%s", failed, out.String())
+	}
+}
+
+func TestLoadPolicyFixturesRejectsBadEffect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy-tests.yaml")
+	if err := os.WriteFile(path, []byte("cases:\n  - {role: worker, tool: Read, want: {effect: maybe}}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPolicyFixtures(path); err == nil {
+		t.Error("expected error for an unknown effect")
+	}
+}
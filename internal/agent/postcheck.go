@@ -3,11 +3,11 @@ package agent
 import (
 	"fmt"
 	"os/exec"
-	"path/filepath"
 	"regexp"
 	"strings"
 
 	"github.com/kylegalloway/blueflame/internal/config"
+	"github.com/kylegalloway/blueflame/internal/policy"
 	"github.com/kylegalloway/blueflame/internal/tasks"
 )
 
@@ -28,7 +28,7 @@ type PostCheckResult struct {
 
 // Violation represents a single post-check violation.
 type Violation struct {
-	Type string // "path_not_allowed", "blocked_path_modified", "outside_file_scope", etc.
+	Type string // the denying policy rule, like "blocked_path" or "outside_file_scope"; or "no_commits", "sensitive_content"
 	Path string
 }
 
@@ -58,18 +58,15 @@ func PostCheck(task *tasks.Task, cfg *config.Config) (*PostCheckResult, error) {
 		return nil, fmt.Errorf("git diff: %w", err)
 	}
 
+	// Judge each change by the path rules the watcher applies to writes.
+	rules, err := postCheckPolicy(task, cfg)
+	if err != nil {
+		return nil, err
+	}
 	for _, change := range changes {
-		// Check: file is within allowed_paths
-		if len(cfg.Permissions.AllowedPaths) > 0 && !matchesAnyGlob(change, cfg.Permissions.AllowedPaths) {
-			result.AddViolation("path_not_allowed", change)
-		}
-		// Check: file is NOT in blocked_paths
-		if matchesAnyGlob(change, cfg.Permissions.BlockedPaths) {
-			result.AddViolation("blocked_path_modified", change)
-		}
-		// Check: file is within task's file_locks scope
-		if cfg.Validation.FileScope.Enforce && !withinFileLocks(change, task.FileLocks) {
-			result.AddViolation("outside_file_scope", change)
+		d := rules.EvaluateChange(policy.Request{Role: RoleWorker, TaskID: task.ID, Path: change})
+		if d.Effect == policy.Deny {
+			result.AddViolation(d.Rule, change)
 		}
 	}
 
@@ -142,37 +139,3 @@ func gitDiffNameStatus(repoDir, baseBranch, branch string) ([]string, error) {
 	}
 	return files, nil
 }
-
-// matchesAnyGlob checks if a path matches any of the given glob patterns.
-func matchesAnyGlob(path string, patterns []string) bool {
-	for _, pattern := range patterns {
-		matched, err := filepath.Match(pattern, path)
-		if err == nil && matched {
-			return true
-		}
-		// Also try matching against just the filename for simple patterns
-		matched, err = filepath.Match(pattern, filepath.Base(path))
-		if err == nil && matched {
-			return true
-		}
-	}
-	return false
-}
-
-// withinFileLocks checks if a path falls within any of the task's declared file locks.
-func withinFileLocks(path string, fileLocks []string) bool {
-	for _, lock := range fileLocks {
-		// Directory lock: path starts with the lock path
-		if strings.HasSuffix(lock, "/") {
-			if strings.HasPrefix(path, lock) || strings.HasPrefix(path+"/", lock) {
-				return true
-			}
-		} else {
-			// File lock: exact match or prefix match
-			if path == lock || strings.HasPrefix(path, lock) {
-				return true
-			}
-		}
-	}
-	return false
-}
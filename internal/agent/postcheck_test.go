@@ -10,52 +10,6 @@ import (
 	"github.com/kylegalloway/blueflame/internal/tasks"
 )
 
-func TestMatchesAnyGlob(t *testing.T) {
-	tests := []struct {
-		path     string
-		patterns []string
-		want     bool
-	}{
-		{"src/main.go", []string{"src/**"}, true},   // ** matches via filepath.Match on base
-		{"src/main.go", []string{"src/*.go"}, true},
-		{".env", []string{".env*"}, true},
-		{".env.local", []string{".env*"}, true},
-		{"pkg/auth/handler.go", []string{"*.secret"}, false},
-		{"credentials.secret", []string{"*.secret"}, true},
-		{"foo/bar.go", []string{}, false},
-	}
-
-	for _, tt := range tests {
-		got := matchesAnyGlob(tt.path, tt.patterns)
-		if got != tt.want {
-			t.Errorf("matchesAnyGlob(%q, %v) = %v, want %v", tt.path, tt.patterns, got, tt.want)
-		}
-	}
-}
-
-func TestWithinFileLocks(t *testing.T) {
-	tests := []struct {
-		path      string
-		fileLocks []string
-		want      bool
-	}{
-		{"pkg/middleware/auth.go", []string{"pkg/middleware/"}, true},
-		{"pkg/middleware/nested/deep.go", []string{"pkg/middleware/"}, true},
-		{"pkg/other/file.go", []string{"pkg/middleware/"}, false},
-		{"internal/auth/handler.go", []string{"internal/auth/"}, true},
-		{"internal/auth/handler.go", []string{"internal/other/"}, false},
-		{"exact_file.go", []string{"exact_file.go"}, true},
-		{"other_file.go", []string{"exact_file.go"}, false},
-	}
-
-	for _, tt := range tests {
-		got := withinFileLocks(tt.path, tt.fileLocks)
-		if got != tt.want {
-			t.Errorf("withinFileLocks(%q, %v) = %v, want %v", tt.path, tt.fileLocks, got, tt.want)
-		}
-	}
-}
-
 func TestPostCheckResultViolations(t *testing.T) {
 	result := &PostCheckResult{Pass: true}
 	if !result.Pass {
@@ -245,3 +199,41 @@ func TestSensitiveContentClean(t *testing.T) {
 		t.Errorf("expected no violations for clean file, got %v", violations)
 	}
 }
+
+func TestPostCheckPolicy(t *testing.T) {
+	dir := setupGitRepo(t, "main", true)
+	task := &tasks.Task{
+		ID:        "task-001",
+		Worktree:  dir,
+		Branch:    "blueflame/task-001",
+		FileLocks: []string{"pkg/"},
+	}
+	cfg := &config.Config{Project: config.ProjectConfig{BaseBranch: "main"}}
+
+	// File locks are only checked with file_scope.enforce.
+	result, err := PostCheck(task, cfg)
+	if err != nil {
+		t.Fatalf("PostCheck: %v", err)
+	}
+	if !result.Pass {
+		t.Errorf("violations = %+v, want none without file_scope.enforce", result.Violations)
+	}
+	cfg.Validation.FileScope.Enforce = true
+	result, err = PostCheck(task, cfg)
+	if err != nil {
+		t.Fatalf("PostCheck: %v", err)
+	}
+	if result.Pass || len(result.Violations) != 1 || result.Violations[0].Type != "outside_file_scope" {
+		t.Errorf("violations = %+v, want hello.go outside_file_scope", result.Violations)
+	}
+
+	// A policy rule comes before the file locks.
+	cfg.Policy.Rules = []config.PolicyRule{{Name: "hello", Effect: "allow", Paths: []string{"hello.go"}}}
+	result, err = PostCheck(task, cfg)
+	if err != nil {
+		t.Fatalf("PostCheck: %v", err)
+	}
+	if !result.Pass {
+		t.Errorf("violations = %+v, want none", result.Violations)
+	}
+}
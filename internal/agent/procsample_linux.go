@@ -0,0 +1,160 @@
+//go:build linux
+
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clockTicks is USER_HZ, the unit of CPU times in /proc/<pid>/stat. It is
+// 100 on every architecture Go supports.
+const clockTicks = 100
+
+// procTotals is what one process had used when last sampled.
+type procTotals struct {
+	cpu        time.Duration
+	readBytes  int64
+	writeBytes int64
+}
+
+// groupSampler samples /proc for every process in an agent's process group.
+// It sees the group's combined memory peak, which rusage can't, and the CPU
+// and I/O of descendants that exit without being waited for.
+type groupSampler struct {
+	pgid     int
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+
+	mu      sync.Mutex
+	peakRSS int64
+	maxRSS  int64
+	procs   map[int]procTotals
+}
+
+// startGroupSampler samples pgid's processes every sampleInterval until
+// stopped.
+func startGroupSampler(pgid int) usageSampler {
+	s := &groupSampler{
+		pgid:  pgid,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+		procs: make(map[int]procTotals),
+	}
+	go s.run(sampleInterval)
+	return s
+}
+
+func (s *groupSampler) run(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	s.sample("/proc")
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.sample("/proc")
+		}
+	}
+}
+
+// Stop ends sampling and returns the final figures.
+func (s *groupSampler) Stop() ResourceUsage {
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+	return s.Usage()
+}
+
+// Usage returns what has been sampled so far.
+func (s *groupSampler) Usage() ResourceUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := ResourceUsage{PeakMemoryBytes: s.peakRSS, MaxRSSBytes: s.maxRSS}
+	for _, p := range s.procs {
+		u.CPUTime += p.cpu
+		u.BlockReadBytes += p.readBytes
+		u.BlockWriteBytes += p.writeBytes
+	}
+	return u
+}
+
+// sample reads every process under procDir that belongs to the group.
+func (s *groupSampler) sample(procDir string) {
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return
+	}
+	pageSize := int64(os.Getpagesize())
+	var groupRSS int64
+	seen := make(map[int]procTotals)
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(procDir, e.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		stat, ok := parseProcStat(string(data))
+		if !ok || stat.pgrp != s.pgid {
+			continue
+		}
+		rss := stat.rssPages * pageSize
+		groupRSS += rss
+		s.mu.Lock()
+		s.maxRSS = max(s.maxRSS, rss)
+		s.mu.Unlock()
+		totals := procTotals{cpu: time.Duration(stat.cpuTicks) * time.Second / clockTicks}
+		if io, err := readKeyedFile(filepath.Join(procDir, e.Name(), "io")); err == nil {
+			totals.readBytes = io["read_bytes:"]
+			totals.writeBytes = io["write_bytes:"]
+		}
+		seen[pid] = totals
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.peakRSS = max(s.peakRSS, groupRSS)
+	// Exited processes keep their last sampled totals.
+	for pid, t := range seen {
+		s.procs[pid] = t
+	}
+}
+
+// procStat is the part of /proc/<pid>/stat the sampler uses.
+type procStat struct {
+	pgrp     int
+	cpuTicks int64
+	rssPages int64
+}
+
+// parseProcStat parses /proc/<pid>/stat. The command name is in parentheses
+// and may itself contain spaces and parentheses, so fields are counted from
+// the last ")".
+func parseProcStat(data string) (procStat, bool) {
+	end := strings.LastIndexByte(data, ')')
+	if end < 0 {
+		return procStat{}, false
+	}
+	// Fields after the name start with state (field 3 in proc(5)).
+	fields := strings.Fields(data[end+1:])
+	if len(fields) < 22 {
+		return procStat{}, false
+	}
+	pgrp, err1 := strconv.Atoi(fields[2])
+	utime, err2 := strconv.ParseInt(fields[11], 10, 64)
+	stime, err3 := strconv.ParseInt(fields[12], 10, 64)
+	rss, err4 := strconv.ParseInt(fields[21], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+		return procStat{}, false
+	}
+	return procStat{pgrp: pgrp, cpuTicks: utime + stime, rssPages: rss}, true
+}
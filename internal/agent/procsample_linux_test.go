@@ -0,0 +1,55 @@
+//go:build linux
+
+package agent
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParseProcStat(t *testing.T) {
+	// The command name may contain spaces and parentheses.
+	data := "4242 (my (odd) cmd) S 1 4240 4240 0 -1 4194560 100 0 0 0 250 50 0 0 20 0 3 0 12345 104857600 2560 18446744073709551615"
+	stat, ok := parseProcStat(data)
+	if !ok {
+		t.Fatal("parse failed")
+	}
+	if stat.pgrp != 4240 || stat.cpuTicks != 300 || stat.rssPages != 2560 {
+		t.Errorf("stat = %+v", stat)
+	}
+	if _, ok := parseProcStat("4242 (truncated) S 1"); ok {
+		t.Error("expected failure for a truncated stat line")
+	}
+}
+
+func TestGroupSamplerAndRusage(t *testing.T) {
+	// A shell that forks a child and burns some CPU, in its own group.
+	cmd := exec.Command("bash", "-c", "sleep 1.5 & i=0; while [ $i -lt 20000 ]; do i=$((i+1)); done; wait")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	sb := agentSandbox{}
+	if err := sb.start(cmd); err != nil {
+		t.Fatal(err)
+	}
+	// The first sample may catch the process mid-exec with nothing resident;
+	// the next comes a sampleInterval later.
+	deadline := time.Now().Add(sampleInterval + 300*time.Millisecond)
+	for sb.sampler.Usage().PeakMemoryBytes == 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if sb.sampler.Usage().PeakMemoryBytes == 0 {
+		t.Error("sampler saw no memory while the group ran")
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	u := sb.usage(cmd.ProcessState)
+	sb.close()
+	if u.PeakMemoryBytes == 0 || u.MaxRSSBytes == 0 {
+		t.Errorf("memory not measured: %+v", u)
+	}
+	if u.CPUTime == 0 || u.CPUTime < u.UserCPU {
+		t.Errorf("CPU not measured: %+v", u)
+	}
+}
@@ -11,8 +11,28 @@ import (
 type PlannerPromptData struct {
 	Description  string
 	PriorContext string
-	ProjectName  string
-	BaseBranch   string
+	// RepoContext is a bounded summary of the repository (tree, README,
+	// recent commits, symbol index) to ground the plan in the real code.
+	RepoContext string
+	ProjectName string
+	BaseBranch  string
+	// RepairErrors and PreviousOutput are set when re-invoking the planner
+	// after its output failed schema validation.
+	RepairErrors   []string
+	PreviousOutput string
+	// Variation steers one planner of an ensemble toward a different
+	// decomposition strategy.
+	Variation string
+	// Candidates holds competing plans (JSON) for the planner to synthesize
+	// into one.
+	Candidates []string
+}
+
+// JudgePromptData holds data for rendering the ensemble judge prompt.
+type JudgePromptData struct {
+	Description string
+	// Plans are the candidate plans as JSON, numbered from 1 in the prompt.
+	Plans []string
 }
 
 // WorkerPromptData holds data for rendering worker prompts.
@@ -20,6 +40,12 @@ type WorkerPromptData struct {
 	Task       *tasks.Task
 	FileLocks  []string
 	RetryNotes string
+	// Issues are the structured problems the last review found; rendered
+	// as a checklist on retry.
+	Issues []tasks.Issue
+	// CodeContext holds pre-read excerpts of the task's files and their
+	// neighbors; empty unless prompts.worker_context is enabled.
+	CodeContext string
 }
 
 // ValidatorPromptData holds data for rendering validator prompts.
@@ -28,6 +54,9 @@ type ValidatorPromptData struct {
 	Diff               string
 	AuditSummary       string
 	DiagnosticCommands []string
+	// PriorIssues are the issues raised when the task was last reviewed;
+	// the validator checks that each was addressed.
+	PriorIssues []tasks.Issue
 }
 
 // MergerPromptData holds data for rendering merger prompts.
@@ -65,6 +94,12 @@ func (r *DefaultPromptRenderer) RenderPrompt(role string, data interface{}) (str
 			return "", fmt.Errorf("invalid data type for merger prompt")
 		}
 		return renderMergerPrompt(d), nil
+	case RoleJudge:
+		d, ok := data.(JudgePromptData)
+		if !ok {
+			return "", fmt.Errorf("invalid data type for judge prompt")
+		}
+		return renderJudgePrompt(d), nil
 	default:
 		return "", fmt.Errorf("unknown role: %s", role)
 	}
@@ -80,6 +115,8 @@ func (r *DefaultPromptRenderer) RenderSystemPrompt(role string, data interface{}
 		return validatorSystemPrompt, nil
 	case RoleMerger:
 		return mergerSystemPrompt, nil
+	case RoleJudge:
+		return judgeSystemPrompt, nil
 	default:
 		return "", fmt.Errorf("unknown role: %s", role)
 	}
@@ -95,6 +132,15 @@ Output a JSON object with a "tasks" array. Each task has:
 - cohesion_group: group name for tasks that must be merged together (optional)
 - dependencies: array of task IDs this task depends on
 - file_locks: array of file/directory paths this task will modify
+- complexity: your estimate of the task's effort, used to pick its models: {"files": number of files it will touch, "risk": "low" | "medium" | "high", "size": "small" | "medium" | "large"}
+
+Optionally, a task that needs more or less than the default worker can set:
+- model: worker model (e.g. "opus" for hard tasks, "haiku" for trivial ones)
+- budget_usd or budget_tokens: worker budget
+- timeout: worker time limit as a duration (e.g. "15m")
+- extra_allowed_commands: shell commands beyond the defaults
+- extra_allowed_tools: tools beyond the defaults
+These are capped by the project's limits; omit them unless the task needs them.
 
 Minimize dependencies between tasks to maximize parallelism. Each task should be independently implementable and testable.`
 
@@ -115,7 +161,12 @@ const validatorSystemPrompt = `You are a validation agent. Review the changes ma
 Output a JSON object with:
 - status: "pass" or "fail"
 - notes: explanation of your assessment
-- issues: array of specific issues found (if any)
+- issues: array of specific issues found (if any), each an object with:
+  - file: path relative to the repo root (if the issue is in a file)
+  - line: line number (if known)
+  - severity: "high", "medium", or "low"
+  - category: e.g. "correctness", "tests", "style", "safety"
+  - message: what is wrong and how to fix it
 
 Check for:
 - Correctness: Does the code do what the task requires?
@@ -133,12 +184,52 @@ Workflow:
 
 Do NOT create new branches. Merge directly into the base branch specified in the prompt.`
 
+const judgeSystemPrompt = `You are a plan review agent. Several planning agents decomposed the same task into sub-tasks. Rank their plans from best to worst.
+
+Output a JSON object with:
+- ranking: array of plan numbers, best first, including every plan
+- notes: short explanation of the ranking
+
+Prefer plans that:
+- Cover the whole task without gaps or duplicated work
+- Run many tasks in parallel with few dependencies
+- Give each task a narrow, non-overlapping set of file_locks
+- Have clear, independently testable task descriptions`
+
 func renderPlannerPrompt(d PlannerPromptData) string {
 	var b strings.Builder
 	fmt.Fprintf(&b, "Decompose the following task into parallelizable sub-tasks:\n\n%s", d.Description)
 	if d.PriorContext != "" {
 		fmt.Fprintf(&b, "\n\nContext from prior sessions:\n%s", d.PriorContext)
 	}
+	if d.RepoContext != "" {
+		fmt.Fprintf(&b, "\n\nRepository context (use real paths from here for file_locks):\n%s", d.RepoContext)
+	}
+	if d.Variation != "" {
+		fmt.Fprintf(&b, "\n\nPlanning approach: %s", d.Variation)
+	}
+	if len(d.Candidates) > 0 {
+		fmt.Fprintf(&b, "\n\nSeveral planners proposed the plans below. Combine their best parts into one plan: keep the clearest task boundaries, the most parallelism and the narrowest file_locks.")
+		for i, c := range d.Candidates {
+			fmt.Fprintf(&b, "\n\nPlan %d:\n%s", i+1, c)
+		}
+	}
+	if len(d.RepairErrors) > 0 {
+		fmt.Fprintf(&b, "\n\nYour previous plan was rejected. Fix every problem below and output the complete corrected plan as JSON.\n\nProblems:\n")
+		for _, e := range d.RepairErrors {
+			fmt.Fprintf(&b, "- %s\n", e)
+		}
+		fmt.Fprintf(&b, "\nPrevious output:\n%s", d.PreviousOutput)
+	}
+	return b.String()
+}
+
+func renderJudgePrompt(d JudgePromptData) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Rank the following %d plans for this task:\n\n%s", len(d.Plans), d.Description)
+	for i, p := range d.Plans {
+		fmt.Fprintf(&b, "\n\nPlan %d:\n%s", i+1, p)
+	}
 	return b.String()
 }
 
@@ -151,6 +242,15 @@ func renderWorkerPrompt(d WorkerPromptData) string {
 	if d.RetryNotes != "" {
 		fmt.Fprintf(&b, "\n\nPrevious attempt notes:\n%s", d.RetryNotes)
 	}
+	if len(d.Issues) > 0 {
+		fmt.Fprintf(&b, "\n\nThe last review found these issues. Address every item; the validator will check each one:\n")
+		for _, issue := range d.Issues {
+			fmt.Fprintf(&b, "- [ ] %s\n", issue)
+		}
+	}
+	if d.CodeContext != "" {
+		fmt.Fprintf(&b, "\n\nRelevant code (excerpts taken before you started; re-read files before editing):\n%s", d.CodeContext)
+	}
 	return b.String()
 }
 
@@ -166,6 +266,12 @@ func renderValidatorPrompt(d ValidatorPromptData) string {
 			fmt.Fprintf(&b, "- %s\n", cmd)
 		}
 	}
+	if len(d.PriorIssues) > 0 {
+		fmt.Fprintf(&b, "\n\nThe previous review of this task raised the issues below. Verify each one was addressed; report any that remain in issues and fail the task if a high-severity issue remains:\n")
+		for _, issue := range d.PriorIssues {
+			fmt.Fprintf(&b, "- %s\n", issue)
+		}
+	}
 	return b.String()
 }
 
@@ -57,12 +57,55 @@ func TestDefaultPromptRendererWorkerWithRetryNotes(t *testing.T) {
 	}
 }
 
+func TestDefaultPromptRendererIssueChecklist(t *testing.T) {
+	r := &DefaultPromptRenderer{}
+	task := &tasks.Task{ID: "task-001", Title: "Add auth"}
+	issues := []tasks.Issue{{File: "auth.go", Line: 12, Severity: "high", Category: "correctness", Message: "nil deref"}}
+
+	prompt, err := r.RenderPrompt(RoleWorker, WorkerPromptData{Task: task, Issues: issues})
+	if err != nil {
+		t.Fatalf("RenderPrompt: %v", err)
+	}
+	if !strings.Contains(prompt, "- [ ] auth.go:12 [high/correctness] nil deref") {
+		t.Errorf("worker prompt should render issues as a checklist:\n%s", prompt)
+	}
+
+	prompt, err = r.RenderPrompt(RoleValidator, ValidatorPromptData{Task: task, PriorIssues: issues})
+	if err != nil {
+		t.Fatalf("RenderPrompt: %v", err)
+	}
+	if !strings.Contains(prompt, "Verify each one was addressed") || !strings.Contains(prompt, "auth.go:12") {
+		t.Errorf("validator prompt should list prior issues:\n%s", prompt)
+	}
+}
+
+func TestDefaultPromptRendererWorkerWithCodeContext(t *testing.T) {
+	r := &DefaultPromptRenderer{}
+
+	prompt, err := r.RenderPrompt(RoleWorker, WorkerPromptData{
+		Task:        &tasks.Task{ID: "task-001", Title: "Add auth"},
+		CodeContext: "## pkg/auth/auth.go (owned)\npackage auth",
+	})
+	if err != nil {
+		t.Fatalf("RenderPrompt: %v", err)
+	}
+	if !strings.Contains(prompt, "## pkg/auth/auth.go (owned)\npackage auth") {
+		t.Error("prompt should contain code context")
+	}
+
+	prompt, _ = r.RenderPrompt(RoleWorker, WorkerPromptData{Task: &tasks.Task{ID: "task-001"}})
+	if strings.Contains(prompt, "Relevant code") {
+		t.Error("prompt should omit the code section without context")
+	}
+}
+
 func TestDefaultPromptRendererPlanner(t *testing.T) {
 	r := &DefaultPromptRenderer{}
 
 	prompt, err := r.RenderPrompt(RolePlanner, PlannerPromptData{
 		Description:  "Add user authentication",
 		PriorContext: "task-005 failed due to missing deps",
+		RepoContext:  "## Directory tree\npkg/auth/",
 		ProjectName:  "myproject",
 	})
 	if err != nil {
@@ -75,6 +118,9 @@ func TestDefaultPromptRendererPlanner(t *testing.T) {
 	if !strings.Contains(prompt, "task-005 failed") {
 		t.Error("prompt should contain prior context")
 	}
+	if !strings.Contains(prompt, "## Directory tree\npkg/auth/") {
+		t.Error("prompt should contain repo context")
+	}
 }
 
 func TestDefaultPromptRendererValidator(t *testing.T) {
@@ -0,0 +1,193 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/kylegalloway/blueflame/internal/tasks"
+	"github.com/kylegalloway/blueflame/internal/tokens"
+)
+
+// Prompt kinds, used in template file names: <role>.<kind>.tmpl.
+const (
+	PromptKindSystem = "system"
+	PromptKindTask   = "task"
+)
+
+// SystemPromptData holds data for rendering system prompt templates.
+type SystemPromptData struct {
+	Role        string
+	ProjectName string
+	BaseBranch  string
+	// Default is the built-in system prompt, so templates can extend it.
+	Default string
+}
+
+// PromptFuncs returns the helper functions available to prompt templates.
+func PromptFuncs() template.FuncMap {
+	return template.FuncMap{
+		"join": func(sep string, elems []string) string {
+			return strings.Join(elems, sep)
+		},
+		"indent": func(n int, s string) string {
+			pad := strings.Repeat(" ", n)
+			return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+		},
+		"truncateTokens": tokens.Truncate,
+	}
+}
+
+// FilePromptRenderer renders prompts from text/template files in a directory,
+// falling back to another renderer for roles without a template file.
+type FilePromptRenderer struct {
+	dir       string
+	fallback  PromptRenderer
+	templates map[string]*template.Template // keyed by "<role>.<kind>"
+}
+
+// NewFilePromptRenderer loads and validates the templates in dir. Every
+// template is executed against sample data so that references to unknown
+// fields fail here rather than mid-session.
+func NewFilePromptRenderer(dir string, fallback PromptRenderer) (*FilePromptRenderer, error) {
+	if fallback == nil {
+		fallback = &DefaultPromptRenderer{}
+	}
+	r := &FilePromptRenderer{
+		dir:       dir,
+		fallback:  fallback,
+		templates: make(map[string]*template.Template),
+	}
+
+	var errs []error
+	known := make(map[string]bool)
+	for _, role := range Roles {
+		for _, kind := range []string{PromptKindSystem, PromptKindTask} {
+			name := role + "." + kind
+			known[name+".tmpl"] = true
+			path := filepath.Join(dir, name+".tmpl")
+			src, err := os.ReadFile(path)
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+				errs = append(errs, fmt.Errorf("read prompt template: %w", err))
+				continue
+			}
+			tmpl, err := template.New(name).Funcs(PromptFuncs()).Option("missingkey=error").Parse(string(src))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("parse prompt template %s: %w", path, err))
+				continue
+			}
+			if err := tmpl.Execute(io.Discard, samplePromptData(role, kind)); err != nil {
+				errs = append(errs, fmt.Errorf("check prompt template %s: %w", path, err))
+				continue
+			}
+			r.templates[name] = tmpl
+		}
+	}
+	// A misspelled file would otherwise be ignored without a word.
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		errs = append(errs, fmt.Errorf("read prompt templates dir: %w", err))
+	}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".tmpl" || known[e.Name()] {
+			continue
+		}
+		errs = append(errs, fmt.Errorf("unknown prompt template %s: want <role>.<kind>.tmpl with role one of %v and kind %s or %s",
+			filepath.Join(dir, e.Name()), Roles, PromptKindSystem, PromptKindTask))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Overrides returns the "<role>.<kind>" names that have template files.
+func (r *FilePromptRenderer) Overrides() []string {
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *FilePromptRenderer) RenderPrompt(role string, data interface{}) (string, error) {
+	tmpl, ok := r.templates[role+"."+PromptKindTask]
+	if !ok {
+		return r.fallback.RenderPrompt(role, data)
+	}
+	return executeTemplate(tmpl, data)
+}
+
+func (r *FilePromptRenderer) RenderSystemPrompt(role string, data interface{}) (string, error) {
+	d, ok := data.(SystemPromptData)
+	if !ok {
+		d = SystemPromptData{Role: role}
+	}
+	def, err := r.fallback.RenderSystemPrompt(role, d)
+	if err != nil {
+		return "", err
+	}
+	d.Default = def
+
+	tmpl, ok := r.templates[role+"."+PromptKindSystem]
+	if !ok {
+		return def, nil
+	}
+	return executeTemplate(tmpl, d)
+}
+
+func executeTemplate(tmpl *template.Template, data interface{}) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("render %s: %w", tmpl.Name(), err)
+	}
+	return b.String(), nil
+}
+
+// samplePromptData returns representative data for validating a template.
+func samplePromptData(role, kind string) interface{} {
+	if kind == PromptKindSystem {
+		return SystemPromptData{Role: role, ProjectName: "sample", BaseBranch: "main", Default: "default"}
+	}
+	task := &tasks.Task{
+		ID:          "task-001",
+		Title:       "Sample task",
+		Description: "Sample description",
+		FileLocks:   []string{"pkg/sample/"},
+	}
+	issues := []tasks.Issue{{File: "pkg/sample/sample.go", Line: 1, Severity: "high", Category: "tests", Message: "no tests"}}
+	switch role {
+	case RolePlanner:
+		return PlannerPromptData{
+			Description:    "sample",
+			RepoContext:    "## README\nsample",
+			ProjectName:    "sample",
+			BaseBranch:     "main",
+			RepairErrors:   []string{"task-001: missing title"},
+			PreviousOutput: `{"tasks":[]}`,
+			Variation:      "Favor small tasks",
+			Candidates:     []string{`{"tasks":[]}`},
+		}
+	case RoleWorker:
+		return WorkerPromptData{Task: task, FileLocks: task.FileLocks, Issues: issues, CodeContext: "## pkg/sample/sample.go (owned)\npackage sample"}
+	case RoleValidator:
+		return ValidatorPromptData{Task: task, Diff: "diff", PriorIssues: issues}
+	case RoleMerger:
+		return MergerPromptData{
+			Branches:   []BranchInfo{{Name: "blueflame/task-001", TaskID: task.ID, TaskTitle: task.Title}},
+			BaseBranch: "main",
+		}
+	case RoleJudge:
+		return JudgePromptData{Description: "sample", Plans: []string{`{"tasks":[]}`, `{"tasks":[]}`}}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kylegalloway/blueflame/internal/tasks"
+)
+
+func writeTemplate(t *testing.T, dir, name, src string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFilePromptRendererOverridesTaskPrompt(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "worker.task.tmpl",
+		`Task {{.Task.ID}}: {{.Task.Title}}
+Scope: {{.FileLocks | join ", "}}
+{{.Task.Description | indent 2}}`)
+
+	r, err := NewFilePromptRenderer(dir, nil)
+	if err != nil {
+		t.Fatalf("NewFilePromptRenderer: %v", err)
+	}
+
+	prompt, err := r.RenderPrompt(RoleWorker, WorkerPromptData{
+		Task:      &tasks.Task{ID: "task-001", Title: "Add auth", Description: "line one\nline two"},
+		FileLocks: []string{"pkg/auth/", "cmd/"},
+	})
+	if err != nil {
+		t.Fatalf("RenderPrompt: %v", err)
+	}
+
+	want := "Task task-001: Add auth\nScope: pkg/auth/, cmd/\n  line one\n  line two"
+	if prompt != want {
+		t.Errorf("prompt = %q, want %q", prompt, want)
+	}
+}
+
+func TestFilePromptRendererOverridesJudgePrompt(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "judge.task.tmpl", `Pick one of {{len .Plans}} plans for: {{.Description}}`)
+
+	r, err := NewFilePromptRenderer(dir, nil)
+	if err != nil {
+		t.Fatalf("NewFilePromptRenderer: %v", err)
+	}
+	prompt, err := r.RenderPrompt(RoleJudge, JudgePromptData{Description: "add auth", Plans: []string{"{}", "{}", "{}"}})
+	if err != nil {
+		t.Fatalf("RenderPrompt: %v", err)
+	}
+	if want := "Pick one of 3 plans for: add auth"; prompt != want {
+		t.Errorf("prompt = %q, want %q", prompt, want)
+	}
+}
+
+func TestFilePromptRendererFallsBackToBuiltin(t *testing.T) {
+	r, err := NewFilePromptRenderer(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("NewFilePromptRenderer: %v", err)
+	}
+	if len(r.Overrides()) != 0 {
+		t.Errorf("Overrides = %v, want none", r.Overrides())
+	}
+
+	prompt, err := r.RenderPrompt(RolePlanner, PlannerPromptData{Description: "Build a thing"})
+	if err != nil {
+		t.Fatalf("RenderPrompt: %v", err)
+	}
+	if !strings.Contains(prompt, "Decompose the following task") {
+		t.Errorf("expected built-in planner prompt, got %q", prompt)
+	}
+
+	sys, err := r.RenderSystemPrompt(RoleValidator, nil)
+	if err != nil {
+		t.Fatalf("RenderSystemPrompt: %v", err)
+	}
+	if sys != validatorSystemPrompt {
+		t.Errorf("expected built-in validator system prompt")
+	}
+}
+
+func TestFilePromptRendererSystemPromptExtendsDefault(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "worker.system.tmpl",
+		"{{.Default}}\n\nProject {{.ProjectName}} uses table-driven tests.")
+
+	r, err := NewFilePromptRenderer(dir, nil)
+	if err != nil {
+		t.Fatalf("NewFilePromptRenderer: %v", err)
+	}
+	if got := r.Overrides(); len(got) != 1 || got[0] != "worker.system" {
+		t.Errorf("Overrides = %v, want [worker.system]", got)
+	}
+
+	sys, err := r.RenderSystemPrompt(RoleWorker, SystemPromptData{Role: RoleWorker, ProjectName: "acme"})
+	if err != nil {
+		t.Fatalf("RenderSystemPrompt: %v", err)
+	}
+	if !strings.HasPrefix(sys, workerSystemPrompt) {
+		t.Error("system prompt should start with the built-in default")
+	}
+	if !strings.HasSuffix(sys, "Project acme uses table-driven tests.") {
+		t.Errorf("system prompt missing override text: %q", sys)
+	}
+}
+
+func TestFilePromptRendererRejectsBrokenTemplates(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		src  string
+	}{
+		{"syntax error", "worker.task.tmpl", "{{.Task.ID"},
+		{"unknown function", "planner.task.tmpl", "{{shout .Description}}"},
+		{"unknown field", "validator.task.tmpl", "{{.Task.Nonexistent}}"},
+		{"unknown judge field", "judge.task.tmpl", "{{.Candidates}}"},
+		{"unknown role", "reviewer.task.tmpl", "{{.Task.ID}}"},
+		{"unknown kind", "worker.prompt.tmpl", "{{.Task.ID}}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTemplate(t, dir, tt.file, tt.src)
+			_, err := NewFilePromptRenderer(dir, nil)
+			if err == nil {
+				t.Fatal("expected error for broken template")
+			}
+			if !strings.Contains(err.Error(), tt.file) {
+				t.Errorf("error should name the template file: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FailureRateLimited classifies a run that hit a rate or usage limit rather
+// than failing at its task.
+const FailureRateLimited = "rate_limited"
+
+// RateLimit describes a rate- or usage-limit failure.
+type RateLimit struct {
+	Message string
+	// ResetAt is when the limit resets; zero when the CLI didn't say.
+	ResetAt time.Time
+}
+
+// rateLimitError is the API error category the CLI reports for a rate
+// limit.
+const rateLimitError = "rate_limit"
+
+var (
+	// The CLI's whole result for a usage limit:
+	// "Claude AI usage limit reached|1760000000".
+	usageLimitPattern = regexp.MustCompile(`^Claude AI usage limit reached(?:\|(\d{9,}))?$`)
+	// "retry after 120 seconds", "retry-after: 120"
+	retryAfterPattern = regexp.MustCompile(`(?i)retry[ -]after:?\s*(\d+)\s*(s|sec|secs|seconds?)?\b`)
+	// "resets at 5pm", "reset at 3:30 PM", "resets 17:00"
+	resetClockPattern = regexp.MustCompile(`(?i)resets?(?: at)?\s+(\d{1,2})(?::(\d{2}))?\s*(am|pm)?\b`)
+)
+
+// DetectRateLimit reports whether a failed run hit a rate or usage limit.
+// Only the CLI's structured error result counts: an API error status of
+// 429, the rate_limit error category, or the CLI's usage-limit result.
+// Free text such as stderr or the agent's transcript is never searched, so
+// a run that merely talks about limits fails normally. now anchors
+// relative and clock-time reset hints in the result.
+func DetectRateLimit(result AgentResult, now time.Time) (RateLimit, bool) {
+	out := result.Output
+	if !out.IsError {
+		return RateLimit{}, false
+	}
+	text := strings.TrimSpace(out.Result)
+	usage := usageLimitPattern.FindStringSubmatch(text)
+	if usage == nil && out.APIErrorStatus != 429 && out.Error != rateLimitError {
+		return RateLimit{}, false
+	}
+
+	rl := RateLimit{Message: firstLine(text)}
+	if rl.Message == "" {
+		rl.Message = fmt.Sprintf("API error %d", out.APIErrorStatus)
+		if out.APIErrorStatus == 0 {
+			rl.Message = "API error " + out.Error
+		}
+	}
+	if usage != nil && usage[1] != "" {
+		if secs, err := strconv.ParseInt(usage[1], 10, 64); err == nil {
+			rl.ResetAt = time.Unix(secs, 0)
+			return rl, true
+		}
+	}
+	rl.ResetAt = parseResetTime(text, now)
+	return rl, true
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+// parseResetTime extracts when a limit resets from the CLI's error result,
+// or returns the zero time.
+func parseResetTime(text string, now time.Time) time.Time {
+	if m := retryAfterPattern.FindStringSubmatch(text); m != nil {
+		if secs, err := strconv.Atoi(m[1]); err == nil {
+			return now.Add(time.Duration(secs) * time.Second)
+		}
+	}
+	if m := resetClockPattern.FindStringSubmatch(text); m != nil {
+		hour, _ := strconv.Atoi(m[1])
+		minute, _ := strconv.Atoi(m[2])
+		switch strings.ToLower(m[3]) {
+		case "pm":
+			if hour < 12 {
+				hour += 12
+			}
+		case "am":
+			if hour == 12 {
+				hour = 0
+			}
+		}
+		if hour > 23 || minute > 59 {
+			return time.Time{}
+		}
+		reset := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+		if !reset.After(now) {
+			reset = reset.AddDate(0, 0, 1)
+		}
+		return reset
+	}
+	return time.Time{}
+}
@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDetectRateLimit(t *testing.T) {
+	now := time.Date(2026, 3, 1, 14, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		result  AgentResult
+		limited bool
+		reset   time.Time
+	}{
+		{
+			name: "usage limit with epoch",
+			result: AgentResult{ExitCode: 1, Output: ClaudeOutput{IsError: true,
+				Result: "Claude AI usage limit reached|1772388000"}},
+			limited: true,
+			reset:   time.Unix(1772388000, 0),
+		},
+		{
+			name: "429 with retry after",
+			result: AgentResult{ExitCode: 1, Output: ClaudeOutput{IsError: true, APIErrorStatus: 429,
+				Result: "API Error: 429 Too Many Requests, retry after 90 seconds"}},
+			limited: true,
+			reset:   now.Add(90 * time.Second),
+		},
+		{
+			name: "clock reset rolls to the next day",
+			result: AgentResult{ExitCode: 1, Output: ClaudeOutput{IsError: true, Error: "rate_limit",
+				Result: "5-hour limit reached ∙ resets 1pm"}},
+			limited: true,
+			reset:   time.Date(2026, 3, 2, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "rate limit without reset hint",
+			result:  AgentResult{ExitCode: 1, Output: ClaudeOutput{IsError: true, Error: "rate_limit"}},
+			limited: true,
+		},
+		{
+			name:   "ordinary failure",
+			result: AgentResult{ExitCode: 1, Output: ClaudeOutput{IsError: true, Result: "tests failed"}},
+		},
+		{
+			name: "failure that talks about limits",
+			result: AgentResult{ExitCode: 1, Output: ClaudeOutput{IsError: true,
+				Result: "context limit reached; the 429 handler's rate limit tests fail"}},
+		},
+		{
+			name: "limit text only in stderr and stdout",
+			result: AgentResult{ExitCode: 1,
+				RawStdout: []byte(`{"type":"assistant","content":"// usage limit reached: return 429 Too Many Requests"}`),
+				RawStderr: []byte("rate_limit_error: overloaded")},
+		},
+		{
+			name:   "successful run mentioning limits",
+			result: AgentResult{Output: ClaudeOutput{Result: "added a rate limit to the API"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, ok := DetectRateLimit(tt.result, now)
+			if ok != tt.limited {
+				t.Fatalf("limited = %v, want %v", ok, tt.limited)
+			}
+			if ok && !rl.ResetAt.Equal(tt.reset) {
+				t.Errorf("ResetAt = %v, want %v", rl.ResetAt, tt.reset)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// sampleInterval is how often a running agent's process group is sampled.
+const sampleInterval = time.Second
+
+// blockSize is the unit of rusage block I/O counts.
+const blockSize = 512
+
+// ResourceUsage is what an agent's processes consumed.
+type ResourceUsage struct {
+	// PeakMemoryBytes is the most memory the whole process tree held at
+	// once: the cgroup's memory.peak, or the largest sampled sum of the
+	// process group's resident sets.
+	PeakMemoryBytes int64 `json:"peak_memory_bytes,omitempty"`
+	// CPUTime is user plus system time for the whole tree; UserCPU and
+	// SystemCPU split the agent's own and its waited-for descendants'.
+	CPUTime   time.Duration `json:"cpu_time,omitempty"`
+	UserCPU   time.Duration `json:"user_cpu,omitempty"`
+	SystemCPU time.Duration `json:"system_cpu,omitempty"`
+	// MaxRSSBytes is the largest resident set of any single process.
+	MaxRSSBytes int64 `json:"max_rss_bytes,omitempty"`
+	// BlockReadBytes and BlockWriteBytes count I/O that reached the block
+	// layer.
+	BlockReadBytes  int64 `json:"block_read_bytes,omitempty"`
+	BlockWriteBytes int64 `json:"block_write_bytes,omitempty"`
+	OOMKilled       bool  `json:"oom_killed,omitempty"`
+}
+
+// merge combines two measurements of the same run, keeping the larger
+// figure for each, since each source misses some processes.
+func (u ResourceUsage) merge(o ResourceUsage) ResourceUsage {
+	u.PeakMemoryBytes = max(u.PeakMemoryBytes, o.PeakMemoryBytes)
+	u.CPUTime = max(u.CPUTime, o.CPUTime)
+	u.UserCPU = max(u.UserCPU, o.UserCPU)
+	u.SystemCPU = max(u.SystemCPU, o.SystemCPU)
+	u.MaxRSSBytes = max(u.MaxRSSBytes, o.MaxRSSBytes)
+	u.BlockReadBytes = max(u.BlockReadBytes, o.BlockReadBytes)
+	u.BlockWriteBytes = max(u.BlockWriteBytes, o.BlockWriteBytes)
+	u.OOMKilled = u.OOMKilled || o.OOMKilled
+	return u
+}
+
+// usageSampler watches a running agent's process group.
+type usageSampler interface {
+	// Usage returns what has been sampled so far.
+	Usage() ResourceUsage
+	// Stop ends sampling and returns the final figures. It may be called
+	// more than once.
+	Stop() ResourceUsage
+}
+
+// rusageUsage reads the rusage of an exited process, which covers it and
+// the descendants it waited for.
+func rusageUsage(state *os.ProcessState) ResourceUsage {
+	if state == nil {
+		return ResourceUsage{}
+	}
+	ru, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || ru == nil {
+		return ResourceUsage{}
+	}
+	u := ResourceUsage{
+		UserCPU:         time.Duration(ru.Utime.Nano()),
+		SystemCPU:       time.Duration(ru.Stime.Nano()),
+		MaxRSSBytes:     int64(ru.Maxrss) * rusageMaxRSSUnit,
+		BlockReadBytes:  int64(ru.Inblock) * blockSize,
+		BlockWriteBytes: int64(ru.Oublock) * blockSize,
+	}
+	u.CPUTime = u.UserCPU + u.SystemCPU
+	u.PeakMemoryBytes = u.MaxRSSBytes
+	return u
+}
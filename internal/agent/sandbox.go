@@ -2,10 +2,132 @@ package agent
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
 	"strings"
+
+	"github.com/kylegalloway/blueflame/internal/config"
 )
 
+// SandboxExecCommand is the hidden blueflame subcommand that confines
+// itself (with Landlock on Linux) and then runs the agent command, so the
+// confinement covers the agent's whole process tree. Inside a network
+// namespace it also bridges the egress proxy onto loopback.
+const SandboxExecCommand = "sandbox-exec"
+
+// egressProxyAddr is where the egress proxy is reachable inside an agent's
+// network namespace.
+const egressProxyAddr = "127.0.0.1:3128"
+
+// FailureOOMKilled classifies a run the kernel killed for exceeding its
+// memory limit.
+const FailureOOMKilled = "oom_killed"
+
+// resourceGroup confines an agent's process tree, such as a cgroup on
+// Linux.
+type resourceGroup interface {
+	// Usage reads what the group's processes consumed.
+	Usage() (ResourceUsage, error)
+	// Close kills any stragglers and removes the group.
+	Close() error
+}
+
+// sandboxOptions are the per-agent parts of an agent's sandbox.
+type sandboxOptions struct {
+	agentID string
+	// writable lists paths outside the working directory that a
+	// Landlock-confined agent may write.
+	writable []string
+	// proxySocket is the agent's egress proxy socket, bridged to
+	// egressProxyAddr inside its network namespace; empty for none.
+	proxySocket string
+}
+
+// agentSandbox holds the per-agent resources an agent's sandbox uses until
+// the agent is collected.
+type agentSandbox struct {
+	group   resourceGroup // nil without a cgroup
+	egress  io.Closer     // the agent's egress listener, nil without one
+	sampler usageSampler  // nil until started, or where unsupported
+	scratch string        // the agent's $AGENT_TMP, removed on close
+}
+
+// start starts cmd and begins sampling its process group. On failure it
+// releases the sandbox.
+func (sb *agentSandbox) start(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		sb.close()
+		return err
+	}
+	sb.sampler = startGroupSampler(cmd.Process.Pid)
+	return nil
+}
+
+// usage combines what the finished agent consumed: rusage from its process
+// state, the process group sampler's peaks, and the cgroup's figures, which
+// cover the whole tree exactly.
+func (sb agentSandbox) usage(state *os.ProcessState) ResourceUsage {
+	u := rusageUsage(state)
+	if sb.sampler != nil {
+		u = u.merge(sb.sampler.Stop())
+	}
+	if sb.group != nil {
+		if g, err := sb.group.Usage(); err == nil {
+			if g.PeakMemoryBytes > 0 {
+				u.PeakMemoryBytes = g.PeakMemoryBytes
+			}
+			if g.CPUTime > 0 {
+				u.CPUTime = g.CPUTime
+			}
+			u.OOMKilled = g.OOMKilled
+		}
+	}
+	return u
+}
+
+// close releases the sandbox's resources, killing any stragglers left in
+// the resource group.
+func (sb agentSandbox) close() {
+	if sb.sampler != nil {
+		sb.sampler.Stop()
+	}
+	if sb.group != nil {
+		sb.group.Close()
+	}
+	if sb.egress != nil {
+		sb.egress.Close()
+	}
+	if sb.scratch != "" {
+		os.RemoveAll(sb.scratch)
+	}
+}
+
+// proxyEnv points HTTP clients at the egress proxy. Both spellings are set
+// because tools disagree on which they read.
+func proxyEnv() []string {
+	proxy := "http://" + egressProxyAddr
+	return []string{
+		"HTTPS_PROXY=" + proxy, "https_proxy=" + proxy,
+		"HTTP_PROXY=" + proxy, "http_proxy=" + proxy,
+		"NO_PROXY=localhost,127.0.0.1", "no_proxy=localhost,127.0.0.1",
+	}
+}
+
+// NetworkStatus describes agents' network access, for --dry-run.
+func NetworkStatus(cfg config.SandboxConfig) string {
+	switch {
+	case cfg.AllowNetwork:
+		return "open (sandbox.allow_network)"
+	case !networkNamespaces:
+		return "not isolated on this platform"
+	case cfg.Egress.Enabled:
+		return fmt.Sprintf("isolated; egress proxy allows %v", cfg.Egress.AllowedHosts)
+	default:
+		return "isolated (no network access)"
+	}
+}
+
 // shellQuote wraps a string in single quotes, escaping internal single quotes.
 func shellQuote(s string) string {
 	return "'" + strings.ReplaceAll(s, "'", "'\"'\"'") + "'"
@@ -31,6 +153,12 @@ func wrapWithLimits(cmd *exec.Cmd, limits []string) {
 	ulimitPrefix := strings.Join(limits, " && ")
 	script := fmt.Sprintf("%s && exec %s", ulimitPrefix, original.String())
 
+	rewrapCommand(cmd, "bash", "-c", script)
+}
+
+// rewrapCommand replaces cmd with name and args while preserving the
+// working directory, environment, output buffers, and SysProcAttr.
+func rewrapCommand(cmd *exec.Cmd, name string, args ...string) {
 	// Preserve state from the original cmd
 	dir := cmd.Dir
 	env := cmd.Env
@@ -39,7 +167,7 @@ func wrapWithLimits(cmd *exec.Cmd, limits []string) {
 	sysProcAttr := cmd.SysProcAttr
 
 	// Replace the command
-	*cmd = *exec.Command("bash", "-c", script)
+	*cmd = *exec.Command(name, args...)
 	cmd.Dir = dir
 	cmd.Env = env
 	cmd.Stdout = stdout
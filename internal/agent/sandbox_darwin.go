@@ -3,6 +3,7 @@
 package agent
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os/exec"
@@ -15,7 +16,9 @@ import (
 // macOS has limited sandboxing compared to Linux:
 // - No reliable RSS limiting (ulimit -v crashes Node.js)
 // - CPU time, file size, and open files work via ulimit wrapper
-func applySandboxLimits(cmd *exec.Cmd, cfg config.SandboxConfig) {
+// - No cgroups, so no resource group is returned
+// - No Landlock or network namespaces, so opts only names the agent
+func applySandboxLimits(cmd *exec.Cmd, cfg config.SandboxConfig, opts sandboxOptions) resourceGroup {
 	if cmd.SysProcAttr == nil {
 		cmd.SysProcAttr = &syscall.SysProcAttr{}
 	}
@@ -41,4 +44,31 @@ func applySandboxLimits(cmd *exec.Cmd, cfg config.SandboxConfig) {
 	}
 
 	wrapWithLimits(cmd, limits)
+	return nil
+}
+
+// CgroupStatus describes agent cgroup support, for --dry-run.
+func CgroupStatus(cfg config.SandboxConfig) string {
+	return "unavailable on macOS (rlimits only)"
+}
+
+// LandlockStatus describes agent write confinement, for --dry-run.
+func LandlockStatus(cfg config.SandboxConfig) string {
+	return "unavailable on macOS (watcher hook and postcheck only)"
+}
+
+// confinesWrites reports whether agents' writes are confined; macOS has
+// no Landlock.
+func confinesWrites(cfg config.SandboxConfig) bool {
+	return false
+}
+
+// networkNamespaces reports whether agents can be isolated in their own
+// network namespace; macOS has none, so the egress proxy isn't used.
+const networkNamespaces = false
+
+// SandboxExec implements the sandbox-exec subcommand, which needs Landlock
+// or network namespaces.
+func SandboxExec(args []string) error {
+	return errors.New("sandbox-exec is only supported on Linux")
 }
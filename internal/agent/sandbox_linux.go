@@ -4,6 +4,8 @@ package agent
 
 import (
 	"fmt"
+	"log"
+	"os"
 	"os/exec"
 	"syscall"
 
@@ -11,8 +13,15 @@ import (
 )
 
 // applySandboxLimits applies platform-specific resource limits on Linux.
-// Linux has full sandboxing support via rlimits, CLONE_NEWNET, and ulimit wrapper.
-func applySandboxLimits(cmd *exec.Cmd, cfg config.SandboxConfig) {
+// Linux has full sandboxing support via cgroup v2, rlimits, CLONE_NEWNET,
+// and ulimit wrapper. When cgroups are delegated, the agent starts inside
+// its own cgroup, which limits memory, CPU and pids for every process it
+// forks, and is returned for usage accounting; otherwise the memory limit
+// falls back to ulimit -v and nil is returned. Where the kernel supports
+// Landlock, writes are confined to the working directory and
+// opts.writable. With an egress proxy socket, the agent reaches the proxy
+// on loopback inside its network namespace.
+func applySandboxLimits(cmd *exec.Cmd, cfg config.SandboxConfig, opts sandboxOptions) resourceGroup {
 	if cmd.SysProcAttr == nil {
 		cmd.SysProcAttr = &syscall.SysProcAttr{}
 	}
@@ -23,6 +32,18 @@ func applySandboxLimits(cmd *exec.Cmd, cfg config.SandboxConfig) {
 		cmd.SysProcAttr.Cloneflags = syscall.CLONE_NEWNET
 	}
 
+	var group *agentCgroup
+	if cfg.Cgroup == config.SandboxAuto && cfg.CgroupParent != "" {
+		g, err := newAgentCgroup(opts.agentID, cfg)
+		if err != nil {
+			log.Printf("cgroup for %s unavailable, using rlimits: %v", opts.agentID, err)
+		} else {
+			group = g
+			cmd.SysProcAttr.UseCgroupFD = true
+			cmd.SysProcAttr.CgroupFD = int(g.dir.Fd())
+		}
+	}
+
 	var limits []string
 
 	if cfg.MaxCPUSeconds > 0 {
@@ -35,11 +56,28 @@ func applySandboxLimits(cmd *exec.Cmd, cfg config.SandboxConfig) {
 	if cfg.MaxOpenFiles > 0 {
 		limits = append(limits, fmt.Sprintf("ulimit -n %d", cfg.MaxOpenFiles))
 	}
-	if cfg.MaxMemoryMB > 0 {
+	if cfg.MaxMemoryMB > 0 && group == nil {
 		// RLIMIT_AS via ulimit -v (kilobytes)
 		kb := cfg.MaxMemoryMB * 1024
 		limits = append(limits, fmt.Sprintf("ulimit -v %d", kb))
 	}
 
 	wrapWithLimits(cmd, limits)
+	if opts.proxySocket != "" {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, proxyEnv()...)
+	}
+	if err := applySandboxExec(cmd, cfg, opts); err != nil {
+		log.Printf("sandbox-exec for %s unavailable: %v", opts.agentID, err)
+	}
+	if group == nil {
+		return nil
+	}
+	return group
 }
+
+// networkNamespaces reports whether agents can be isolated in their own
+// network namespace, which the egress proxy relies on.
+const networkNamespaces = true
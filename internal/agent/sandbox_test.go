@@ -68,7 +68,7 @@ func TestWrapWithLimitsEmpty(t *testing.T) {
 
 func TestApplySandboxLimitsNoLimits(t *testing.T) {
 	cmd := exec.Command("claude", "--print")
-	applySandboxLimits(cmd, config.SandboxConfig{})
+	applySandboxLimits(cmd, config.SandboxConfig{}, sandboxOptions{agentID: "test"})
 
 	// With no limits configured, command should still be "claude" (no bash wrapper)
 	if cmd.Args[0] != "claude" {
@@ -86,7 +86,7 @@ func TestApplySandboxLimitsWithRlimits(t *testing.T) {
 		MaxCPUSeconds: 300,
 		MaxOpenFiles:  1024,
 		MaxFileSizeMB: 100,
-	})
+	}, sandboxOptions{agentID: "test"})
 
 	// Should be wrapped in bash
 	if cmd.Args[0] != "bash" {
@@ -0,0 +1,210 @@
+//go:build linux
+
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"github.com/kylegalloway/blueflame/internal/config"
+)
+
+// applySandboxExec re-execs cmd through "blueflame sandbox-exec" when the
+// agent is confined with Landlock or reaches the network through the
+// egress proxy. It does nothing when neither applies.
+func applySandboxExec(cmd *exec.Cmd, cfg config.SandboxConfig, opts sandboxOptions) error {
+	landlock := confinesWrites(cfg)
+	if !landlock && opts.proxySocket == "" {
+		return nil
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("find blueflame executable: %w", err)
+	}
+	args := []string{SandboxExecCommand}
+	if landlock {
+		for _, p := range landlockWritable(cmd.Dir, cfg, opts.writable) {
+			args = append(args, "--allow-write", p)
+		}
+	}
+	if opts.proxySocket != "" {
+		args = append(args, "--proxy-socket", opts.proxySocket)
+	}
+	args = append(args, "--")
+	args = append(args, cmd.Args...)
+	rewrapCommand(cmd, exe, args...)
+	return nil
+}
+
+// sandboxExecArgs are the parsed arguments of the sandbox-exec subcommand.
+type sandboxExecArgs struct {
+	writable    []string
+	proxySocket string
+	command     []string
+}
+
+// parseSandboxExecArgs splits
+// "[--allow-write PATH]... [--proxy-socket PATH] -- COMMAND ARGS...".
+func parseSandboxExecArgs(args []string) (sandboxExecArgs, error) {
+	var parsed sandboxExecArgs
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--allow-write", "--proxy-socket":
+			if i+1 >= len(args) {
+				return sandboxExecArgs{}, fmt.Errorf("%s needs a path", args[i])
+			}
+			if args[i] == "--allow-write" {
+				parsed.writable = append(parsed.writable, args[i+1])
+			} else {
+				parsed.proxySocket = args[i+1]
+			}
+			i++
+		case "--":
+			if i+1 >= len(args) {
+				return sandboxExecArgs{}, errors.New("no command to run")
+			}
+			parsed.command = args[i+1:]
+			return parsed, nil
+		default:
+			return sandboxExecArgs{}, fmt.Errorf("unexpected argument %q", args[i])
+		}
+	}
+	return sandboxExecArgs{}, errors.New("missing -- before the command")
+}
+
+// SandboxExec implements the sandbox-exec subcommand: it restricts writes
+// to the given paths and runs the command. Without a proxy socket it execs
+// the command and only returns on failure. With one, it bridges
+// egressProxyAddr to the socket and stays as the command's parent to keep
+// the bridge running, exiting with the command's status. The command never
+// runs unconfined.
+func SandboxExec(args []string) error {
+	parsed, err := parseSandboxExecArgs(args)
+	if err != nil {
+		return err
+	}
+	path, err := exec.LookPath(parsed.command[0])
+	if err != nil {
+		return err
+	}
+
+	if parsed.proxySocket != "" {
+		if err := loopbackUp(); err != nil {
+			return fmt.Errorf("bring up loopback: %w", err)
+		}
+		l, err := net.Listen("tcp", egressProxyAddr)
+		if err != nil {
+			return fmt.Errorf("listen for egress: %w", err)
+		}
+		go bridgeEgress(l, parsed.proxySocket)
+	}
+
+	// Landlock restricts the calling thread. Exec turns it into the whole
+	// new process, and a child forked from it inherits the restriction.
+	runtime.LockOSThread()
+	if len(parsed.writable) > 0 {
+		if err := landlockRestrict(parsed.writable); err != nil {
+			return fmt.Errorf("landlock: %w", err)
+		}
+	}
+	if parsed.proxySocket == "" {
+		return syscall.Exec(path, parsed.command, os.Environ())
+	}
+	code, err := superviseCommand(path, parsed.command)
+	if err != nil {
+		return err
+	}
+	os.Exit(code)
+	return nil
+}
+
+// superviseCommand runs the command as a child, forwarding termination
+// signals, and returns its exit status in shell form (128+signal when
+// killed).
+func superviseCommand(path string, argv []string) (int, error) {
+	cmd := &exec.Cmd{Path: path, Args: argv, Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	go func() {
+		for sig := range signals {
+			cmd.Process.Signal(sig)
+		}
+	}()
+	err := cmd.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal()), nil
+		}
+		return exitErr.ExitCode(), nil
+	}
+	return 0, err
+}
+
+// bridgeEgress forwards every connection accepted on l to the egress
+// proxy's unix socket, which lives outside the network namespace.
+func bridgeEgress(l net.Listener, socket string) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			upstream, err := net.Dial("unix", socket)
+			if err != nil {
+				return
+			}
+			defer upstream.Close()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				io.Copy(upstream, conn)
+				upstream.(*net.UnixConn).CloseWrite()
+			}()
+			io.Copy(conn, upstream)
+			conn.(*net.TCPConn).CloseWrite()
+			<-done
+		}()
+	}
+}
+
+// ifreqFlags is struct ifreq with the flags member of its union.
+type ifreqFlags struct {
+	name  [syscall.IFNAMSIZ]byte
+	flags uint16
+	_     [22]byte
+}
+
+// loopbackUp brings up "lo", which starts down in a new network namespace.
+func loopbackUp() error {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+	var req ifreqFlags
+	copy(req.name[:], "lo")
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.SIOCGIFFLAGS, uintptr(unsafe.Pointer(&req))); errno != 0 {
+		return fmt.Errorf("get flags: %w", errno)
+	}
+	if req.flags&syscall.IFF_UP != 0 {
+		return nil
+	}
+	req.flags |= syscall.IFF_UP
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.SIOCSIFFLAGS, uintptr(unsafe.Pointer(&req))); errno != 0 {
+		return fmt.Errorf("set flags: %w", errno)
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+//go:build linux
+
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/kylegalloway/blueflame/internal/config"
+	"github.com/kylegalloway/blueflame/internal/egress"
+)
+
+func TestParseSandboxExecArgs(t *testing.T) {
+	parsed, err := parseSandboxExecArgs([]string{"--allow-write", "/a", "--proxy-socket", "/s.sock", "--allow-write", "/b", "--", "bash", "-c", "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(parsed.writable, []string{"/a", "/b"}) || !slices.Equal(parsed.command, []string{"bash", "-c", "true"}) {
+		t.Errorf("writable=%v command=%v", parsed.writable, parsed.command)
+	}
+	if parsed.proxySocket != "/s.sock" {
+		t.Errorf("proxySocket = %q", parsed.proxySocket)
+	}
+	for _, bad := range [][]string{{"--allow-write"}, {"--proxy-socket"}, {"--"}, {"bash"}, {"--allow-write", "/a"}} {
+		if _, err := parseSandboxExecArgs(bad); err == nil {
+			t.Errorf("expected error for %v", bad)
+		}
+	}
+}
+
+func TestApplySandboxExecWrapsCommand(t *testing.T) {
+	if landlockABI() == 0 {
+		t.Skip("kernel lacks Landlock")
+	}
+	cmd := exec.Command("claude", "--print")
+	cmd.Dir = "/work/tree"
+	cfg := config.SandboxConfig{Landlock: config.SandboxAuto, WritablePaths: []string{"/cache"}}
+	if err := applySandboxExec(cmd, cfg, sandboxOptions{writable: []string{"/audit"}}); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := parseSandboxExecArgs(cmd.Args[2:])
+	if err != nil || cmd.Args[1] != SandboxExecCommand {
+		t.Fatalf("args = %v (%v)", cmd.Args, err)
+	}
+	for _, p := range []string{"/work/tree", "/audit", "/cache", "/dev/null", "/dev/tty"} {
+		if !slices.Contains(parsed.writable, p) {
+			t.Errorf("%s not writable: %v", p, parsed.writable)
+		}
+	}
+	for _, p := range []string{"/dev", os.TempDir()} {
+		if slices.Contains(parsed.writable, p) {
+			t.Errorf("%s should not be writable: %v", p, parsed.writable)
+		}
+	}
+	if !slices.Equal(parsed.command, []string{"claude", "--print"}) || cmd.Dir != "/work/tree" {
+		t.Errorf("command = %v in %s", parsed.command, cmd.Dir)
+	}
+}
+
+func TestApplySandboxExecProxyOnly(t *testing.T) {
+	cmd := exec.Command("claude", "--print")
+	if err := applySandboxExec(cmd, config.SandboxConfig{Landlock: config.SandboxOff}, sandboxOptions{proxySocket: "/s.sock"}); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := parseSandboxExecArgs(cmd.Args[2:])
+	if err != nil {
+		t.Fatalf("args = %v (%v)", cmd.Args, err)
+	}
+	if parsed.proxySocket != "/s.sock" || len(parsed.writable) != 0 {
+		t.Errorf("parsed = %+v", parsed)
+	}
+
+	plain := exec.Command("claude", "--print")
+	applySandboxExec(plain, config.SandboxConfig{Landlock: config.SandboxOff}, sandboxOptions{})
+	if plain.Args[0] != "claude" {
+		t.Errorf("without Landlock or a proxy the command should be unchanged, got %v", plain.Args)
+	}
+}
+
+// standInProxy serves a proxy for agentID that allows only standin.test,
+// which it resolves to a local HTTP server.
+func standInProxy(t *testing.T, agentID, auditPath string) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello from "+r.Host)
+	}))
+	t.Cleanup(server.Close)
+
+	proxy, err := egress.New([]string{"standin.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { proxy.Close() })
+	proxy.Dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if addr != "standin.test:80" {
+			return nil, errors.New("no such stand-in")
+		}
+		return net.Dial(network, server.Listener.Addr().String())
+	}
+	socket, listener, err := proxy.Listen(agentID, auditPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	return socket
+}
+
+func TestBridgeEgressReachesProxy(t *testing.T) {
+	auditPath := filepath.Join(t.TempDir(), "w-1.audit.jsonl")
+	socket := standInProxy(t, "w-1", auditPath)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go bridgeEgress(l, socket)
+
+	proxyURL, _ := url.Parse("http://" + l.Addr().String())
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+	body, err := get(client, "http://standin.test/")
+	if err != nil || body != "hello from standin.test" {
+		t.Fatalf("allowed host: body=%q err=%v", body, err)
+	}
+	if _, err := get(client, "http://elsewhere.test/"); err == nil {
+		t.Error("request to a host off the allowlist should fail")
+	}
+
+	audit, _ := os.ReadFile(auditPath)
+	for _, want := range []string{`"target":"standin.test:80","decision":"allow"`, `"target":"elsewhere.test:80","decision":"block"`} {
+		if !strings.Contains(string(audit), want) {
+			t.Errorf("audit log missing %s:\n%s", want, audit)
+		}
+	}
+}
+
+func get(client *http.Client, target string) (string, error) {
+	resp, err := client.Get(target)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return string(body), fmt.Errorf("status %s", resp.Status)
+	}
+	return string(body), err
+}
+
+// TestEgressFromNetworkNamespace runs sandbox-exec in a new network
+// namespace, as an agent would, with a client that must reach the stand-in
+// host through the proxy and can't reach it directly.
+func TestEgressFromNetworkNamespace(t *testing.T) {
+	switch os.Getenv("BLUEFLAME_EGRESS_HELPER") {
+	case "sandbox-exec":
+		// The command sandbox-exec runs is this test again, as the client.
+		os.Setenv("BLUEFLAME_EGRESS_HELPER", "client")
+		err := SandboxExec(strings.Split(os.Getenv("BLUEFLAME_EGRESS_ARGS"), "\n"))
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(126)
+	case "client":
+		body, err := get(http.DefaultClient, "http://standin.test/")
+		if err != nil || body != "hello from standin.test" {
+			fmt.Fprintf(os.Stderr, "proxied request: body=%q err=%v\n", body, err)
+			os.Exit(3)
+		}
+		if conn, err := net.Dial("tcp", os.Getenv("BLUEFLAME_EGRESS_DIRECT")); err == nil {
+			conn.Close()
+			fmt.Fprintln(os.Stderr, "direct connection succeeded")
+			os.Exit(4)
+		}
+		os.Exit(0)
+	}
+
+	auditPath := filepath.Join(t.TempDir(), "w-1.audit.jsonl")
+	socket := standInProxy(t, "w-1", auditPath)
+	direct, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer direct.Close()
+
+	self := []string{os.Args[0], "-test.run=^TestEgressFromNetworkNamespace$"}
+	args := append([]string{"--proxy-socket", socket, "--"}, self...)
+	cmd := exec.Command(self[0], self[1:]...)
+	cmd.Env = append(os.Environ(),
+		"BLUEFLAME_EGRESS_HELPER=sandbox-exec",
+		"BLUEFLAME_EGRESS_ARGS="+strings.Join(args, "\n"),
+		"BLUEFLAME_EGRESS_DIRECT="+direct.Addr().String(),
+	)
+	cmd.Env = append(cmd.Env, proxyEnv()...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Cloneflags: syscall.CLONE_NEWNET}
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Start(); err != nil {
+		t.Skipf("cannot create a network namespace: %v", err)
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("agent in network namespace failed: %v\n%s", err, out.String())
+	}
+	audit, _ := os.ReadFile(auditPath)
+	if !strings.Contains(string(audit), `"target":"standin.test:80","decision":"allow"`) {
+		t.Errorf("audit log missing the proxied connection:\n%s", audit)
+	}
+}
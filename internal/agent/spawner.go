@@ -6,11 +6,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/kylegalloway/blueflame/internal/config"
+	"github.com/kylegalloway/blueflame/internal/egress"
+	"github.com/kylegalloway/blueflame/internal/repocontext"
 	"github.com/kylegalloway/blueflame/internal/tasks"
 )
 
@@ -20,8 +24,14 @@ const (
 	RoleWorker    = "worker"
 	RoleValidator = "validator"
 	RoleMerger    = "merger"
+	// RoleJudge ranks competing plans from a planner ensemble. It runs with
+	// the planner's model and budget.
+	RoleJudge = "judge"
 )
 
+// Roles lists every agent role in the order a session runs them.
+var Roles = []string{RolePlanner, RoleJudge, RoleWorker, RoleValidator, RoleMerger}
+
 // Agent represents a running or completed claude CLI process.
 type Agent struct {
 	ID       string
@@ -31,7 +41,13 @@ type Agent struct {
 	Stderr   *bytes.Buffer
 	Started  time.Time
 	Role     string
+	Model    string
 	Budget   config.BudgetSpec
+	// Timeout overrides the lifecycle manager's agent timeout when set.
+	Timeout time.Duration
+
+	// sandbox holds the agent's resource group and egress listener.
+	sandbox agentSandbox
 }
 
 // BranchInfo describes a validated branch for the merger.
@@ -53,34 +69,59 @@ type ClaudeOutput struct {
 	NumTurns     int          `json:"num_turns"`
 	Usage        ClaudeUsage  `json:"usage"`
 	SessionID    string       `json:"session_id"`
+	// Error is the API error category of a failed run, such as
+	// "rate_limit", and APIErrorStatus its HTTP status, when the CLI
+	// reports them.
+	Error          string `json:"error,omitempty"`
+	APIErrorStatus int    `json:"api_error_status,omitempty"`
+	// ModelUsage breaks usage down by model when the run used several.
+	ModelUsage map[string]ClaudeModelUsage `json:"modelUsage"`
 }
 
 // ClaudeUsage represents token usage from claude CLI output.
 type ClaudeUsage struct {
-	InputTokens  int `json:"input_tokens"`
-	OutputTokens int `json:"output_tokens"`
+	InputTokens              int                 `json:"input_tokens"`
+	OutputTokens             int                 `json:"output_tokens"`
+	CacheCreationInputTokens int                 `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int                 `json:"cache_read_input_tokens"`
+	CacheCreation            ClaudeCacheCreation `json:"cache_creation"`
+	ServerToolUse            ClaudeServerToolUse `json:"server_tool_use"`
+	ServiceTier              string              `json:"service_tier,omitempty"`
 }
 
 // AgentResult holds the outcome of an agent execution.
 type AgentResult struct {
 	AgentID    string
 	TaskID     string
+	Role       string
+	Model      string
 	ExitCode   int
 	Output     ClaudeOutput
 	RawStdout  []byte
 	RawStderr  []byte
 	CostUSD    float64
+	// TokensUsed counts every token class; Usage and ModelUsage break it
+	// down.
 	TokensUsed int
+	Usage      ClaudeUsage
+	ModelUsage map[string]ClaudeModelUsage
+	// Failure classifies a failed run, e.g. FailureRateLimited, and
+	// RateLimit describes a rate-limit failure.
+	Failure    string
+	RateLimit  *RateLimit
+	// Resources is what the agent's processes consumed.
+	Resources  ResourceUsage
 	Duration   time.Duration
 	Err        error
 }
 
 // AgentSpawner is the interface for spawning claude CLI agents.
 type AgentSpawner interface {
-	SpawnPlanner(ctx context.Context, description string, priorContext string, cfg *config.Config) (*Agent, error)
+	SpawnPlanner(ctx context.Context, data PlannerPromptData, cfg *config.Config) (*Agent, error)
 	SpawnWorker(ctx context.Context, task *tasks.Task, cfg *config.Config) (*Agent, error)
 	SpawnValidator(ctx context.Context, task *tasks.Task, diff string, auditSummary string, cfg *config.Config) (*Agent, error)
 	SpawnMerger(ctx context.Context, branches []BranchInfo, cfg *config.Config) (*Agent, error)
+	SpawnJudge(ctx context.Context, data JudgePromptData, cfg *config.Config) (*Agent, error)
 }
 
 // ProductionSpawner implements AgentSpawner using real claude CLI invocations.
@@ -89,6 +130,69 @@ type ProductionSpawner struct {
 	PromptRenderer PromptRenderer
 	// HooksDir is the base directory for generated hook scripts.
 	HooksDir string
+	// Egress serves agents that run without network access when
+	// sandbox.egress is enabled.
+	Egress *egress.Proxy
+}
+
+// sandbox applies the sandbox to cmd, first starting the agent's egress
+// proxy listener when it gets one.
+func (s *ProductionSpawner) sandbox(cmd *exec.Cmd, cfg config.SandboxConfig, agentID string) (agentSandbox, error) {
+	opts := sandboxOptions{agentID: agentID, writable: s.writablePaths()}
+	var sb agentSandbox
+	scratch, err := applyEnvPolicy(cmd, cfg.Env, agentID)
+	if err != nil {
+		return sb, err
+	}
+	if confinesWrites(cfg) {
+		// A confined agent can't write the shared temp directory, so its
+		// scratch directory stands in for it.
+		if scratch == "" {
+			if scratch, err = agentScratchDir(agentID); err != nil {
+				return sb, err
+			}
+		}
+		if _, ok := cfg.Env.Set["TMPDIR"]; !ok {
+			cmd.Env = setEnv(cmd.Env, "TMPDIR", scratch)
+		}
+	}
+	if scratch != "" {
+		opts.writable = append(opts.writable, scratch)
+	}
+	sb.scratch = scratch
+	if s.Egress != nil && cfg.Egress.Enabled && !cfg.AllowNetwork && networkNamespaces {
+		auditPath := ""
+		if s.HooksDir != "" {
+			auditPath = AuditLogPath(s.HooksDir, agentID)
+		}
+		socket, listener, err := s.Egress.Listen(agentID, auditPath)
+		if err != nil {
+			sb.close()
+			return agentSandbox{}, fmt.Errorf("start egress proxy: %w", err)
+		}
+		opts.proxySocket = socket
+		sb.egress = listener
+	}
+	sb.group = applySandboxLimits(cmd, cfg, opts)
+	if s.HooksDir != "" {
+		if err := writeEnvRecord(AuditLogPath(s.HooksDir, agentID), agentID, cmd.Dir, cmd.Env); err != nil {
+			sb.close()
+			return agentSandbox{}, fmt.Errorf("record agent environment: %w", err)
+		}
+	}
+	return sb, nil
+}
+
+// writablePaths returns the paths outside its working directory that a
+// confined agent must be able to write: the watcher hook's audit logs,
+// created here because a confined hook couldn't create them.
+func (s *ProductionSpawner) writablePaths() []string {
+	if s.HooksDir == "" {
+		return nil
+	}
+	logs := filepath.Join(s.HooksDir, "logs")
+	os.MkdirAll(logs, 0o755)
+	return []string{logs}
 }
 
 // PromptRenderer renders prompt templates for different agent roles.
@@ -97,13 +201,33 @@ type PromptRenderer interface {
 	RenderSystemPrompt(role string, data interface{}) (string, error)
 }
 
+// workerCodeContext pre-reads the task's files and their neighbors when
+// prompts.worker_context is enabled. Errors drop the context rather than
+// failing the spawn.
+func workerCodeContext(task *tasks.Task, cfg *config.Config) string {
+	wc := cfg.Prompts.WorkerContext
+	if !wc.Enabled {
+		return ""
+	}
+	dir := task.Worktree
+	if dir == "" {
+		dir = cfg.Project.Repo
+	}
+	pack, err := repocontext.BuildTask(dir, task.FileLocks, repocontext.TaskOptions{MaxTokens: wc.MaxTokens})
+	if err != nil {
+		return ""
+	}
+	return pack.String()
+}
+
 func (s *ProductionSpawner) SpawnWorker(ctx context.Context, task *tasks.Task, cfg *config.Config) (*Agent, error) {
 	agentID := task.AgentID
 	if agentID == "" {
 		return nil, fmt.Errorf("task %s has no agent_id", task.ID)
 	}
 
-	allowedTools := append([]string{}, cfg.Permissions.AllowedTools...)
+	settings, _ := ResolveTaskSettings(task, cfg)
+	allowedTools, disallowedTools := policyTools(RoleWorker, task, cfg)
 	// Wire superpowers skills as additional allowed tools
 	if cfg.Superpowers.Enabled && len(cfg.Superpowers.Skills) > 0 {
 		allowedTools = append(allowedTools, cfg.Superpowers.Skills...)
@@ -111,13 +235,13 @@ func (s *ProductionSpawner) SpawnWorker(ctx context.Context, task *tasks.Task, c
 
 	args := []string{
 		"--print",
-		"--model", cfg.Models.Worker,
+		"--model", settings.Model,
 		"--allowed-tools", strings.Join(allowedTools, ","),
-		"--disallowed-tools", strings.Join(cfg.Permissions.BlockedTools, ","),
+		"--disallowed-tools", strings.Join(disallowedTools, ","),
 		"--output-format", "json",
 	}
 
-	budget := cfg.Limits.TokenBudget.WorkerBudget()
+	budget := settings.Budget
 	if budget.Unit == config.USD && budget.Value > 0 {
 		args = append(args, "--max-budget-usd", fmt.Sprintf("%.2f", budget.Value))
 	} else if budget.Unit == config.Tokens && budget.Value > 0 {
@@ -126,7 +250,7 @@ func (s *ProductionSpawner) SpawnWorker(ctx context.Context, task *tasks.Task, c
 
 	// Render system prompt
 	if s.PromptRenderer != nil {
-		sysPrompt, err := s.PromptRenderer.RenderSystemPrompt(RoleWorker, nil)
+		sysPrompt, err := s.PromptRenderer.RenderSystemPrompt(RoleWorker, systemPromptData(RoleWorker, cfg))
 		if err == nil && sysPrompt != "" {
 			args = append(args, "--system-prompt", sysPrompt)
 		}
@@ -136,14 +260,18 @@ func (s *ProductionSpawner) SpawnWorker(ctx context.Context, task *tasks.Task, c
 	prompt := fmt.Sprintf("Implement task %s: %s", task.ID, task.Title)
 	if s.PromptRenderer != nil {
 		var retryNotes string
+		var issues []tasks.Issue
 		if len(task.History) > 0 {
 			last := task.History[len(task.History)-1]
 			retryNotes = last.Notes
+			issues = last.Issues
 		}
 		rendered, err := s.PromptRenderer.RenderPrompt(RoleWorker, WorkerPromptData{
-			Task:       task,
-			FileLocks:  task.FileLocks,
-			RetryNotes: retryNotes,
+			Task:        task,
+			FileLocks:   task.FileLocks,
+			RetryNotes:  retryNotes,
+			Issues:      issues,
+			CodeContext: workerCodeContext(task, cfg),
 		})
 		if err == nil {
 			prompt = rendered
@@ -153,14 +281,18 @@ func (s *ProductionSpawner) SpawnWorker(ctx context.Context, task *tasks.Task, c
 
 	cmd := exec.CommandContext(ctx, "claude", args...)
 	cmd.Dir = task.Worktree
+	cmd.Env = agentEnv(RoleWorker, agentID, task)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	applySandboxLimits(cmd, cfg.Sandbox)
+	sb, err := s.sandbox(cmd, cfg.Sandbox, agentID)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := cmd.Start(); err != nil {
+	if err := sb.start(cmd); err != nil {
 		return nil, fmt.Errorf("start worker: %w", err)
 	}
 
@@ -171,12 +303,15 @@ func (s *ProductionSpawner) SpawnWorker(ctx context.Context, task *tasks.Task, c
 		Stdout:  &stdout,
 		Stderr:  &stderr,
 		Started: time.Now(),
+		sandbox: sb,
 		Role:    RoleWorker,
+		Model:   settings.Model,
 		Budget:  budget,
+		Timeout: settings.Timeout,
 	}, nil
 }
 
-func (s *ProductionSpawner) SpawnPlanner(ctx context.Context, description string, priorContext string, cfg *config.Config) (*Agent, error) {
+func (s *ProductionSpawner) SpawnPlanner(ctx context.Context, data PlannerPromptData, cfg *config.Config) (*Agent, error) {
 	args := []string{
 		"--print",
 		"--model", cfg.Models.Planner,
@@ -197,57 +332,123 @@ func (s *ProductionSpawner) SpawnPlanner(ctx context.Context, description string
 
 	// Render system prompt
 	if s.PromptRenderer != nil {
-		sysPrompt, err := s.PromptRenderer.RenderSystemPrompt(RolePlanner, nil)
+		sysPrompt, err := s.PromptRenderer.RenderSystemPrompt(RolePlanner, systemPromptData(RolePlanner, cfg))
 		if err == nil && sysPrompt != "" {
 			args = append(args, "--system-prompt", sysPrompt)
 		}
 	}
 
 	// Render task prompt
-	prompt := description
+	prompt := data.Description
 	if s.PromptRenderer != nil {
-		rendered, err := s.PromptRenderer.RenderPrompt(RolePlanner, PlannerPromptData{
-			Description:  description,
-			PriorContext: priorContext,
-			ProjectName:  cfg.Project.Name,
-			BaseBranch:   cfg.Project.BaseBranch,
-		})
+		data.ProjectName = cfg.Project.Name
+		data.BaseBranch = cfg.Project.BaseBranch
+		rendered, err := s.PromptRenderer.RenderPrompt(RolePlanner, data)
 		if err == nil {
 			prompt = rendered
 		}
 	}
 	args = append(args, prompt)
 
+	agentID := fmt.Sprintf("planner-%08x", time.Now().UnixNano()&0xFFFFFFFF)
 	cmd := exec.CommandContext(ctx, "claude", args...)
 	cmd.Dir = cfg.Project.Repo
+	cmd.Env = agentEnv(RolePlanner, agentID, nil)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	applySandboxLimits(cmd, cfg.Sandbox)
+	sb, err := s.sandbox(cmd, cfg.Sandbox, agentID)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := cmd.Start(); err != nil {
+	if err := sb.start(cmd); err != nil {
 		return nil, fmt.Errorf("start planner: %w", err)
 	}
 
 	return &Agent{
-		ID:      fmt.Sprintf("planner-%08x", time.Now().UnixNano()&0xFFFFFFFF),
+		ID:      agentID,
 		Cmd:     cmd,
 		Stdout:  &stdout,
 		Stderr:  &stderr,
 		Started: time.Now(),
+		sandbox: sb,
 		Role:    RolePlanner,
+		Model:   cfg.Models.Planner,
+		Budget:  budget,
+	}, nil
+}
+
+func (s *ProductionSpawner) SpawnJudge(ctx context.Context, data JudgePromptData, cfg *config.Config) (*Agent, error) {
+	args := []string{
+		"--print",
+		"--model", cfg.Models.Planner,
+		"--output-format", "json",
+	}
+
+	budget := cfg.Limits.TokenBudget.PlannerBudget()
+	if budget.Unit == config.USD && budget.Value > 0 {
+		args = append(args, "--max-budget-usd", fmt.Sprintf("%.2f", budget.Value))
+	} else if budget.Unit == config.Tokens && budget.Value > 0 {
+		args = append(args, "--max-tokens", fmt.Sprintf("%.0f", budget.Value))
+	}
+
+	if s.PromptRenderer != nil {
+		sysPrompt, err := s.PromptRenderer.RenderSystemPrompt(RoleJudge, systemPromptData(RoleJudge, cfg))
+		if err == nil && sysPrompt != "" {
+			args = append(args, "--system-prompt", sysPrompt)
+		}
+	}
+
+	prompt := renderJudgePrompt(data)
+	if s.PromptRenderer != nil {
+		rendered, err := s.PromptRenderer.RenderPrompt(RoleJudge, data)
+		if err == nil {
+			prompt = rendered
+		}
+	}
+	args = append(args, prompt)
+
+	agentID := fmt.Sprintf("judge-%08x", time.Now().UnixNano()&0xFFFFFFFF)
+	cmd := exec.CommandContext(ctx, "claude", args...)
+	cmd.Dir = cfg.Project.Repo
+	cmd.Env = agentEnv(RoleJudge, agentID, nil)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	sb, err := s.sandbox(cmd, cfg.Sandbox, agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sb.start(cmd); err != nil {
+		return nil, fmt.Errorf("start judge: %w", err)
+	}
+
+	return &Agent{
+		ID:      agentID,
+		Cmd:     cmd,
+		Stdout:  &stdout,
+		Stderr:  &stderr,
+		Started: time.Now(),
+		sandbox: sb,
+		Role:    RoleJudge,
+		Model:   cfg.Models.Planner,
 		Budget:  budget,
 	}, nil
 }
 
 func (s *ProductionSpawner) SpawnValidator(ctx context.Context, task *tasks.Task, diff string, auditSummary string, cfg *config.Config) (*Agent, error) {
+	allowedTools, disallowedTools := policyTools(RoleValidator, task, cfg)
 	args := []string{
 		"--print",
 		"--model", cfg.Models.Validator,
-		"--allowed-tools", "Read,Glob,Grep,Bash",
-		"--disallowed-tools", "Write,Edit,WebFetch,WebSearch,NotebookEdit,Task",
+		"--allowed-tools", strings.Join(allowedTools, ","),
+		"--disallowed-tools", strings.Join(disallowedTools, ","),
 		"--output-format", "json",
 	}
 
@@ -260,7 +461,7 @@ func (s *ProductionSpawner) SpawnValidator(ctx context.Context, task *tasks.Task
 
 	// Render system prompt
 	if s.PromptRenderer != nil {
-		sysPrompt, err := s.PromptRenderer.RenderSystemPrompt(RoleValidator, nil)
+		sysPrompt, err := s.PromptRenderer.RenderSystemPrompt(RoleValidator, systemPromptData(RoleValidator, cfg))
 		if err == nil && sysPrompt != "" {
 			args = append(args, "--system-prompt", sysPrompt)
 		}
@@ -273,11 +474,16 @@ func (s *ProductionSpawner) SpawnValidator(ctx context.Context, task *tasks.Task
 		if cfg.Validation.ValidatorDiagnostics.Enabled {
 			diagCmds = cfg.Validation.ValidatorDiagnostics.Commands
 		}
+		var priorIssues []tasks.Issue
+		if len(task.History) > 0 {
+			priorIssues = task.History[len(task.History)-1].Issues
+		}
 		rendered, err := s.PromptRenderer.RenderPrompt(RoleValidator, ValidatorPromptData{
 			Task:               task,
 			Diff:               diff,
 			AuditSummary:       auditSummary,
 			DiagnosticCommands: diagCmds,
+			PriorIssues:        priorIssues,
 		})
 		if err == nil {
 			prompt = rendered
@@ -285,37 +491,45 @@ func (s *ProductionSpawner) SpawnValidator(ctx context.Context, task *tasks.Task
 	}
 	args = append(args, prompt)
 
+	agentID := fmt.Sprintf("validator-%08x", time.Now().UnixNano()&0xFFFFFFFF)
 	cmd := exec.CommandContext(ctx, "claude", args...)
 	cmd.Dir = task.Worktree
+	cmd.Env = agentEnv(RoleValidator, agentID, task)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	applySandboxLimits(cmd, cfg.Sandbox)
+	sb, err := s.sandbox(cmd, cfg.Sandbox, agentID)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := cmd.Start(); err != nil {
+	if err := sb.start(cmd); err != nil {
 		return nil, fmt.Errorf("start validator: %w", err)
 	}
 
 	return &Agent{
-		ID:      fmt.Sprintf("validator-%08x", time.Now().UnixNano()&0xFFFFFFFF),
+		ID:      agentID,
 		Cmd:     cmd,
 		Task:    task,
 		Stdout:  &stdout,
 		Stderr:  &stderr,
 		Started: time.Now(),
+		sandbox: sb,
 		Role:    RoleValidator,
+		Model:   cfg.Models.Validator,
 		Budget:  budget,
 	}, nil
 }
 
 func (s *ProductionSpawner) SpawnMerger(ctx context.Context, branches []BranchInfo, cfg *config.Config) (*Agent, error) {
+	allowedTools, disallowedTools := policyTools(RoleMerger, nil, cfg)
 	args := []string{
 		"--print",
 		"--model", cfg.Models.Merger,
-		"--allowed-tools", "Bash,Read,Glob,Grep",
-		"--disallowed-tools", "Write,Edit,WebFetch,WebSearch,NotebookEdit,Task",
+		"--allowed-tools", strings.Join(allowedTools, ","),
+		"--disallowed-tools", strings.Join(disallowedTools, ","),
 		"--output-format", "json",
 	}
 
@@ -328,7 +542,7 @@ func (s *ProductionSpawner) SpawnMerger(ctx context.Context, branches []BranchIn
 
 	// Render system prompt
 	if s.PromptRenderer != nil {
-		sysPrompt, err := s.PromptRenderer.RenderSystemPrompt(RoleMerger, nil)
+		sysPrompt, err := s.PromptRenderer.RenderSystemPrompt(RoleMerger, systemPromptData(RoleMerger, cfg))
 		if err == nil && sysPrompt != "" {
 			args = append(args, "--system-prompt", sysPrompt)
 		}
@@ -356,30 +570,67 @@ func (s *ProductionSpawner) SpawnMerger(ctx context.Context, branches []BranchIn
 	}
 	args = append(args, prompt)
 
+	agentID := fmt.Sprintf("merger-%08x", time.Now().UnixNano()&0xFFFFFFFF)
 	cmd := exec.CommandContext(ctx, "claude", args...)
 	cmd.Dir = cfg.Project.Repo
+	cmd.Env = agentEnv(RoleMerger, agentID, nil)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	applySandboxLimits(cmd, cfg.Sandbox)
+	sb, err := s.sandbox(cmd, cfg.Sandbox, agentID)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := cmd.Start(); err != nil {
+	if err := sb.start(cmd); err != nil {
 		return nil, fmt.Errorf("start merger: %w", err)
 	}
 
 	return &Agent{
-		ID:      fmt.Sprintf("merger-%08x", time.Now().UnixNano()&0xFFFFFFFF),
+		ID:      agentID,
 		Cmd:     cmd,
 		Stdout:  &stdout,
 		Stderr:  &stderr,
 		Started: time.Now(),
+		sandbox: sb,
 		Role:    RoleMerger,
+		Model:   cfg.Models.Merger,
 		Budget:  budget,
 	}, nil
 }
 
+// systemPromptData builds the data passed to system prompt templates.
+func systemPromptData(role string, cfg *config.Config) SystemPromptData {
+	return SystemPromptData{
+		Role:        role,
+		ProjectName: cfg.Project.Name,
+		BaseBranch:  cfg.Project.BaseBranch,
+	}
+}
+
+// Environment variables identifying a spawned agent. Hooks and test doubles
+// read these instead of parsing the prompt.
+const (
+	EnvAgentID = "BLUEFLAME_AGENT_ID"
+	EnvRole    = "BLUEFLAME_ROLE"
+	EnvTaskID  = "BLUEFLAME_TASK_ID"
+)
+
+// agentEnv returns the environment for a spawned agent: the orchestrator's
+// environment plus the agent's identity.
+func agentEnv(role, agentID string, task *tasks.Task) []string {
+	env := append(os.Environ(),
+		EnvAgentID+"="+agentID,
+		EnvRole+"="+role,
+	)
+	if task != nil {
+		env = append(env, EnvTaskID+"="+task.ID)
+	}
+	return env
+}
+
 // CollectResult waits for an agent to complete and returns its result.
 func CollectResult(agent *Agent) AgentResult {
 	err := agent.Cmd.Wait()
@@ -396,17 +647,39 @@ func CollectResult(agent *Agent) AgentResult {
 
 	result := AgentResult{
 		AgentID:   agent.ID,
+		Role:      agent.Role,
+		Model:     agent.Model,
 		ExitCode:  exitCode,
 		Output:    output,
 		RawStdout: agent.Stdout.Bytes(),
 		RawStderr: agent.Stderr.Bytes(),
 		CostUSD:   output.TotalCostUSD,
-		TokensUsed: output.Usage.InputTokens + output.Usage.OutputTokens,
+		TokensUsed: output.TotalUsage().Total(),
+		Usage:     output.TotalUsage(),
+		ModelUsage: output.ModelUsage,
 		Duration:  time.Since(agent.Started),
 		Err:       err,
 	}
 	if agent.Task != nil {
 		result.TaskID = agent.Task.ID
 	}
+	result.Resources = agent.sandbox.usage(agent.Cmd.ProcessState)
+	if result.Resources.OOMKilled {
+		result.Failure = FailureOOMKilled
+	}
+	agent.sandbox.close()
+	classifyFailure(&result)
 	return result
 }
+
+// classifyFailure sets the result's failure class, unless the resource
+// group already did.
+func classifyFailure(result *AgentResult) {
+	if result.Failure != "" {
+		return
+	}
+	if rl, ok := DetectRateLimit(*result, time.Now()); ok {
+		result.Failure = FailureRateLimited
+		result.RateLimit = &rl
+	}
+}
@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -81,7 +82,7 @@ func TestMockSpawnerPlanner(t *testing.T) {
 		},
 	}
 
-	agent, err := spawner.SpawnPlanner(context.Background(), "test desc", "", testConfig())
+	agent, err := spawner.SpawnPlanner(context.Background(), PlannerPromptData{Description: "test desc"}, testConfig())
 	if err != nil {
 		t.Fatalf("SpawnPlanner: %v", err)
 	}
@@ -184,3 +185,32 @@ func TestAgentSpawnerInterface(t *testing.T) {
 	var _ AgentSpawner = &ProductionSpawner{}
 	var _ AgentSpawner = &MockSpawner{}
 }
+
+func TestAgentEnvIdentifiesAgent(t *testing.T) {
+	task := &tasks.Task{ID: "task-001"}
+	env := agentEnv(RoleWorker, "worker-abc", task)
+
+	want := []string{
+		EnvAgentID + "=worker-abc",
+		EnvRole + "=" + RoleWorker,
+		EnvTaskID + "=task-001",
+	}
+	for _, w := range want {
+		found := false
+		for _, e := range env {
+			if e == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("env missing %q", w)
+		}
+	}
+
+	for _, e := range agentEnv(RoleMerger, "merger-abc", nil) {
+		if strings.HasPrefix(e, EnvTaskID+"=") {
+			t.Errorf("merger env should not set %s, got %q", EnvTaskID, e)
+		}
+	}
+}
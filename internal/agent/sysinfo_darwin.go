@@ -7,6 +7,16 @@ import (
 	"unsafe"
 )
 
+// rusageMaxRSSUnit converts rusage's Maxrss, already in bytes on macOS,
+// to bytes.
+const rusageMaxRSSUnit = 1
+
+// startGroupSampler returns nil: macOS has no /proc to sample, so usage
+// comes from rusage alone.
+func startGroupSampler(pgid int) usageSampler {
+	return nil
+}
+
 // getAvailableRAMMB returns available RAM in MB on macOS.
 // Uses sysctlbyname to query hw.memsize (total) and vm.page_free_count * vm.pagesize (free).
 func getAvailableRAMMB() int {
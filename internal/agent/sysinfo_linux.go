@@ -9,6 +9,10 @@ import (
 	"strings"
 )
 
+// rusageMaxRSSUnit converts rusage's Maxrss, in kilobytes on Linux, to
+// bytes.
+const rusageMaxRSSUnit = 1024
+
 // getAvailableRAMMB returns available RAM in MB on Linux.
 // Parses /proc/meminfo for MemAvailable.
 func getAvailableRAMMB() int {
@@ -4,13 +4,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+
+	"github.com/kylegalloway/blueflame/internal/tasks"
 )
 
 // ValidatorOutput represents the structured output from a validator agent.
 type ValidatorOutput struct {
-	Status string   `json:"status"` // "pass" or "fail"
-	Notes  string   `json:"notes"`
-	Issues []string `json:"issues,omitempty"`
+	Status string        `json:"status"` // "pass" or "fail"
+	Notes  string        `json:"notes"`
+	Issues []tasks.Issue `json:"issues,omitempty"`
+}
+
+// JudgeOutput represents the structured output from an ensemble judge agent.
+type JudgeOutput struct {
+	Ranking []int  `json:"ranking"` // plan numbers, 1-based, best first
+	Notes   string `json:"notes"`
 }
 
 // PlannerOutput represents the structured output from a planner agent.
@@ -27,6 +35,17 @@ type PlannerTask struct {
 	CohesionGroup string   `json:"cohesion_group,omitempty"`
 	Dependencies  []string `json:"dependencies"`
 	FileLocks     []string `json:"file_locks"`
+
+	// Optional overrides of the worker settings for this task.
+	Model         string   `json:"model,omitempty"`
+	BudgetUSD     float64  `json:"budget_usd,omitempty"`
+	BudgetTokens  int      `json:"budget_tokens,omitempty"`
+	Timeout       string   `json:"timeout,omitempty"` // Go duration, e.g. "15m"
+	ExtraCommands []string `json:"extra_allowed_commands,omitempty"`
+	ExtraTools    []string `json:"extra_allowed_tools,omitempty"`
+
+	// Optional complexity estimate, used for model routing.
+	Complexity *tasks.Complexity `json:"complexity,omitempty"`
 }
 
 // ParseValidatorOutput parses structured validator output from JSON.
@@ -55,6 +74,35 @@ func ParsePlannerOutput(data []byte) (*PlannerOutput, error) {
 	return &out, nil
 }
 
+// ParseJudgeOutput parses a judge ranking of planCount plans.
+func ParseJudgeOutput(data []byte, planCount int) (*JudgeOutput, error) {
+	extracted := extractResultJSON(data)
+	var out JudgeOutput
+	if err := json.Unmarshal(extracted, &out); err != nil {
+		return nil, fmt.Errorf("parse judge output: %w", err)
+	}
+	seen := make(map[int]bool, len(out.Ranking))
+	for _, n := range out.Ranking {
+		if n < 1 || n > planCount || seen[n] {
+			return nil, fmt.Errorf("invalid judge ranking %v for %d plans", out.Ranking, planCount)
+		}
+		seen[n] = true
+	}
+	return &out, nil
+}
+
+// ResultText returns the agent's text response from a Claude output envelope,
+// or data unchanged if it is not an envelope.
+func ResultText(data []byte) string {
+	var envelope struct {
+		Result string `json:"result"`
+	}
+	if json.Unmarshal(data, &envelope) == nil && envelope.Result != "" {
+		return envelope.Result
+	}
+	return string(data)
+}
+
 // extractResultJSON extracts the inner result from Claude's --output-format json envelope.
 // When claude is invoked with --output-format json, stdout is a JSON object like:
 //
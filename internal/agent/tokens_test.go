@@ -30,6 +30,17 @@ func TestParseValidatorOutputFail(t *testing.T) {
 	}
 }
 
+func TestParseValidatorOutputStructuredIssues(t *testing.T) {
+	data := []byte(`{"status":"fail","notes":"see issues","issues":[{"file":"auth.go","line":12,"severity":"high","category":"tests","message":"no tests"}]}`)
+	out, err := ParseValidatorOutput(data)
+	if err != nil {
+		t.Fatalf("ParseValidatorOutput: %v", err)
+	}
+	if len(out.Issues) != 1 || out.Issues[0].File != "auth.go" || out.Issues[0].Line != 12 || out.Issues[0].Severity != "high" {
+		t.Errorf("issues = %+v", out.Issues)
+	}
+}
+
 func TestParseValidatorOutputInvalidStatus(t *testing.T) {
 	data := []byte(`{"status":"maybe","notes":"not sure"}`)
 	_, err := ParseValidatorOutput(data)
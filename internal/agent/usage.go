@@ -0,0 +1,92 @@
+package agent
+
+import "github.com/kylegalloway/blueflame/internal/config"
+
+// ClaudeCacheCreation splits cache-creation tokens by cache lifetime.
+type ClaudeCacheCreation struct {
+	Ephemeral5mInputTokens int `json:"ephemeral_5m_input_tokens"`
+	Ephemeral1hInputTokens int `json:"ephemeral_1h_input_tokens"`
+}
+
+// ClaudeServerToolUse counts server-side tool requests.
+type ClaudeServerToolUse struct {
+	WebSearchRequests int `json:"web_search_requests"`
+}
+
+// ClaudeModelUsage is one model's entry in the claude CLI's modelUsage map.
+type ClaudeModelUsage struct {
+	InputTokens              int     `json:"inputTokens"`
+	OutputTokens             int     `json:"outputTokens"`
+	CacheReadInputTokens     int     `json:"cacheReadInputTokens"`
+	CacheCreationInputTokens int     `json:"cacheCreationInputTokens"`
+	WebSearchRequests        int     `json:"webSearchRequests"`
+	CostUSD                  float64 `json:"costUSD"`
+}
+
+// Usage converts a per-model entry to a ClaudeUsage.
+func (m ClaudeModelUsage) Usage() ClaudeUsage {
+	return ClaudeUsage{
+		InputTokens:              m.InputTokens,
+		OutputTokens:             m.OutputTokens,
+		CacheCreationInputTokens: m.CacheCreationInputTokens,
+		CacheReadInputTokens:     m.CacheReadInputTokens,
+		ServerToolUse:            ClaudeServerToolUse{WebSearchRequests: m.WebSearchRequests},
+	}
+}
+
+// Class returns the tokens in one of the config.TokenClasses.
+func (u ClaudeUsage) Class(class string) int {
+	switch class {
+	case config.TokenInput:
+		return u.InputTokens
+	case config.TokenOutput:
+		return u.OutputTokens
+	case config.TokenCacheCreation:
+		return u.CacheCreationInputTokens
+	case config.TokenCacheRead:
+		return u.CacheReadInputTokens
+	}
+	return 0
+}
+
+// Total returns the tokens in every class.
+func (u ClaudeUsage) Total() int {
+	return u.InputTokens + u.OutputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+}
+
+// Counted returns the tokens in the classes that count toward tb.
+func (u ClaudeUsage) Counted(tb *config.TokenBudget) int {
+	n := 0
+	for _, class := range config.TokenClasses {
+		if tb.Counts(class) {
+			n += u.Class(class)
+		}
+	}
+	return n
+}
+
+// add sums two usages.
+func (u ClaudeUsage) add(v ClaudeUsage) ClaudeUsage {
+	u.InputTokens += v.InputTokens
+	u.OutputTokens += v.OutputTokens
+	u.CacheCreationInputTokens += v.CacheCreationInputTokens
+	u.CacheReadInputTokens += v.CacheReadInputTokens
+	u.ServerToolUse.WebSearchRequests += v.ServerToolUse.WebSearchRequests
+	return u
+}
+
+// TotalUsage returns the run's usage across all models. The top-level usage
+// object only covers the main model, so when modelUsage is present its
+// entries are summed instead.
+func (o ClaudeOutput) TotalUsage() ClaudeUsage {
+	if len(o.ModelUsage) == 0 {
+		return o.Usage
+	}
+	var total ClaudeUsage
+	for _, m := range o.ModelUsage {
+		total = total.add(m.Usage())
+	}
+	total.CacheCreation = o.Usage.CacheCreation
+	total.ServiceTier = o.Usage.ServiceTier
+	return total
+}
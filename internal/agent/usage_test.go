@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kylegalloway/blueflame/internal/config"
+)
+
+func TestClaudeOutputTotalUsage(t *testing.T) {
+	data := []byte(`{"type":"result","total_cost_usd":0.30,
+		"usage":{"input_tokens":10,"output_tokens":200,"cache_creation_input_tokens":5000,"cache_read_input_tokens":40000,
+			"cache_creation":{"ephemeral_5m_input_tokens":5000},"server_tool_use":{"web_search_requests":1},"service_tier":"standard"},
+		"modelUsage":{
+			"claude-sonnet":{"inputTokens":10,"outputTokens":200,"cacheCreationInputTokens":5000,"cacheReadInputTokens":40000,"costUSD":0.28},
+			"claude-haiku":{"inputTokens":300,"outputTokens":20,"costUSD":0.02}
+		}}`)
+	var out ClaudeOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Usage.CacheCreation.Ephemeral5mInputTokens != 5000 || out.Usage.ServerToolUse.WebSearchRequests != 1 {
+		t.Errorf("usage details not parsed: %+v", out.Usage)
+	}
+
+	total := out.TotalUsage()
+	if total.InputTokens != 310 || total.OutputTokens != 220 || total.CacheReadInputTokens != 40000 {
+		t.Errorf("total usage should sum every model, got %+v", total)
+	}
+	if got := total.Total(); got != 45530 {
+		t.Errorf("Total() = %d, want 45530", got)
+	}
+	if total.ServiceTier != "standard" {
+		t.Errorf("service tier = %q", total.ServiceTier)
+	}
+	if out.ModelUsage["claude-haiku"].CostUSD != 0.02 {
+		t.Errorf("model usage = %+v", out.ModelUsage)
+	}
+}
+
+func TestClaudeUsageCounted(t *testing.T) {
+	u := ClaudeUsage{InputTokens: 10, OutputTokens: 20, CacheCreationInputTokens: 300, CacheReadInputTokens: 4000}
+	if got := u.Counted(&config.TokenBudget{}); got != 4330 {
+		t.Errorf("default counts all classes: got %d, want 4330", got)
+	}
+	tb := &config.TokenBudget{CountTokens: []string{config.TokenInput, config.TokenOutput, config.TokenCacheCreation}}
+	if got := u.Counted(tb); got != 330 {
+		t.Errorf("without cache reads: got %d, want 330", got)
+	}
+}
@@ -0,0 +1,228 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kylegalloway/blueflame/internal/approval"
+	"github.com/kylegalloway/blueflame/internal/policy"
+)
+
+// HookCommand is the blueflame subcommand that agents' .claude/settings.json
+// runs as the PreToolUse watcher hook.
+const HookCommand = "hook"
+
+// Hook exit codes, as Claude reads them.
+const (
+	hookExitAllow = 0
+	hookExitBlock = 2
+)
+
+// HookInput is the tool invocation Claude sends a PreToolUse hook on stdin.
+type HookInput struct {
+	ToolName  string `json:"tool_name"`
+	Cwd       string `json:"cwd"`
+	ToolInput struct {
+		FilePath string `json:"file_path"`
+		// NotebookPath is where NotebookEdit names its file.
+		NotebookPath string `json:"notebook_path"`
+		Path         string `json:"path"`
+		Command      string `json:"command"`
+	} `json:"tool_input"`
+}
+
+// target is what the invocation acts on: its path, or its Bash command.
+func (in HookInput) target() string {
+	if p := in.filePath(); p != "" {
+		return p
+	}
+	return in.ToolInput.Command
+}
+
+func (in HookInput) filePath() string {
+	switch {
+	case in.ToolInput.FilePath != "":
+		return in.ToolInput.FilePath
+	case in.ToolInput.NotebookPath != "":
+		return in.ToolInput.NotebookPath
+	}
+	return in.ToolInput.Path
+}
+
+// Evaluate judges a tool invocation against the agent's rules. Paths are
+// judged relative to the agent's worktree, so one that leaves it is never
+// inside the task's file locks.
+func (p WatcherData) Evaluate(in HookInput) policy.Decision {
+	compiled, err := policy.New(p.Rules)
+	if err != nil {
+		return policy.Decision{Effect: policy.Deny, Rule: "bad_policy", Reason: err.Error()}
+	}
+	d := compiled.Evaluate(p.request(in))
+	if d.Reason == "" {
+		d.Reason = fmt.Sprintf("%s rule %s", d.Effect, d.Rule)
+	}
+	return d
+}
+
+// request is the policy request for a tool invocation.
+func (p WatcherData) request(in HookInput) policy.Request {
+	req := policy.Request{Role: p.Role, Tool: in.ToolName, TaskID: p.TaskID}
+	if file := in.filePath(); file != "" {
+		req.Path = p.relPath(file, in.Cwd)
+	}
+	if in.ToolName == "Bash" {
+		req.Command = strings.TrimSpace(in.ToolInput.Command)
+	}
+	return req
+}
+
+// ask asks the orchestrator's approver about a call an "ask" rule matched,
+// and returns the answer as an allow or deny.
+func (p WatcherData) ask(in HookInput, d policy.Decision) policy.Decision {
+	if p.ApprovalSocket == "" {
+		d.Effect = policy.Deny
+		d.Reason += " (needs approval, and no approver is available)"
+		return d
+	}
+	resp, err := approval.Ask(p.ApprovalSocket, approval.Request{
+		AgentID: p.AgentID,
+		Role:    p.Role,
+		TaskID:  p.TaskID,
+		Tool:    in.ToolName,
+		Target:  in.target(),
+		Rule:    d.Rule,
+		Reason:  d.Reason,
+	}, p.AskTimeout)
+	if err != nil {
+		d.Effect = policy.Deny
+		d.Reason += fmt.Sprintf(" (needs approval: %v)", err)
+		return d
+	}
+	d.Effect = policy.Deny
+	if resp.Approved() {
+		d.Effect = policy.Allow
+	}
+	d.Reason += " (" + resp.Details + ")"
+	return d
+}
+
+// relPath makes file relative to the agent's worktree, falling back to the
+// hook's working directory.
+func (p WatcherData) relPath(file, cwd string) string {
+	root := p.Worktree
+	if root == "" {
+		root = cwd
+	}
+	if root == "" || !filepath.IsAbs(file) {
+		return filepath.ToSlash(filepath.Clean(file))
+	}
+	rel, err := filepath.Rel(root, file)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// WriteHookPolicy writes an agent's watcher policy for "blueflame hook".
+func WriteHookPolicy(data WatcherData, policyPath string) error {
+	if err := os.MkdirAll(filepath.Dir(policyPath), 0o755); err != nil {
+		return fmt.Errorf("create hook dir: %w", err)
+	}
+	encoded, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal hook policy: %w", err)
+	}
+	if err := os.WriteFile(policyPath, encoded, 0o644); err != nil {
+		return fmt.Errorf("write hook policy: %w", err)
+	}
+	return nil
+}
+
+// HookPolicyPath returns where agentID's watcher policy is written.
+func HookPolicyPath(hooksDir, agentID string) string {
+	return filepath.Join(hooksDir, agentID+".policy.json")
+}
+
+// RunHook implements the hook subcommand: it reads a tool invocation from
+// stdin, judges it against the policy named by --policy, records the
+// decision in the agent's audit log, and returns the exit code Claude
+// expects. It fails closed: a missing policy or unreadable input blocks.
+func RunHook(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var in HookInput
+	watcher, err := loadHookPolicy(args)
+	if err == nil {
+		if err = json.NewDecoder(stdin).Decode(&in); err != nil {
+			err = fmt.Errorf("read tool invocation: %w", err)
+		}
+	}
+	verdict := policy.Decision{Effect: policy.Deny, Rule: "hook_error", Reason: fmt.Sprintf("watcher hook: %v", err)}
+	if err == nil {
+		verdict = watcher.Evaluate(in)
+	}
+	if verdict.Effect == policy.Ask {
+		verdict = watcher.ask(in, verdict)
+	}
+
+	if watcher.AuditLogPath != "" {
+		decision := "allow"
+		if verdict.Effect != policy.Allow {
+			decision = "block"
+		}
+		rec := auditRecord{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			AgentID:   watcher.AgentID,
+			TaskID:    watcher.TaskID,
+			Tool:      in.ToolName,
+			Target:    in.target(),
+			Decision:  decision,
+			Rule:      verdict.Rule,
+			Details:   verdict.Reason,
+		}
+		if err := appendAuditRecord(watcher.AuditLogPath, rec); err != nil {
+			fmt.Fprintf(stderr, "watcher hook: audit log: %v\n", err)
+		}
+	}
+
+	if verdict.Effect == policy.Allow {
+		json.NewEncoder(stdout).Encode(map[string]string{"decision": "allow"})
+		return hookExitAllow
+	}
+	json.NewEncoder(stdout).Encode(map[string]string{"decision": "block", "reason": verdict.Reason})
+	// Claude shows a blocking hook's stderr to the agent.
+	fmt.Fprintln(stderr, verdict.Reason)
+	return hookExitBlock
+}
+
+// loadHookPolicy reads the policy named by "--policy PATH".
+func loadHookPolicy(args []string) (WatcherData, error) {
+	var policyPath string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--policy" && i+1 < len(args):
+			policyPath = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--policy="):
+			policyPath = strings.TrimPrefix(args[i], "--policy=")
+		default:
+			return WatcherData{}, fmt.Errorf("unexpected argument %q", args[i])
+		}
+	}
+	if policyPath == "" {
+		return WatcherData{}, errors.New("no --policy given")
+	}
+	data, err := os.ReadFile(policyPath)
+	if err != nil {
+		return WatcherData{}, fmt.Errorf("read policy: %w", err)
+	}
+	var watcher WatcherData
+	if err := json.Unmarshal(data, &watcher); err != nil {
+		return WatcherData{}, fmt.Errorf("parse policy %s: %w", policyPath, err)
+	}
+	return watcher, nil
+}
@@ -0,0 +1,244 @@
+// Package approval relays tool calls the permission policy asks about to a
+// human. The orchestrator serves each agent its own unix socket; the
+// agent's watcher hook sends it the call and blocks until it answers. A
+// question nobody answers in time is denied.
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout is how long a call waits for an answer when no timeout
+// is configured.
+const DefaultTimeout = 2 * time.Minute
+
+// Answers to a request.
+const (
+	ApproveOnce    = "approve_once"
+	ApproveSession = "approve_session"
+	Deny           = "deny"
+)
+
+// hookMargin is how much longer than the timeout the hook waits for the
+// orchestrator's answer before giving up on it.
+const hookMargin = 10 * time.Second
+
+// Request is a tool call awaiting approval.
+type Request struct {
+	AgentID string `json:"agent_id"`
+	Role    string `json:"role"`
+	TaskID  string `json:"task_id,omitempty"`
+	Tool    string `json:"tool"`
+	// Target is the call's path or Bash command.
+	Target string `json:"target"`
+	Rule   string `json:"rule"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Response is the answer to a request.
+type Response struct {
+	Answer  string `json:"answer"`
+	Details string `json:"details,omitempty"`
+}
+
+// Approved reports whether the call may run.
+func (r Response) Approved() bool {
+	return r.Answer == ApproveOnce || r.Answer == ApproveSession
+}
+
+// Server answers requests by asking a human, one question at a time.
+// Calls approved for the session are answered without asking again.
+type Server struct {
+	ask     func(context.Context, Request) string
+	timeout time.Duration
+	dir     string
+
+	// asking serializes questions; mu guards session and listeners.
+	asking    sync.Mutex
+	mu        sync.Mutex
+	session   map[string]bool
+	listeners map[string]net.Listener
+}
+
+// New returns a server whose agent sockets live in dir, which it creates
+// and only the user may enter. dir should be outside what agents can
+// write, and short, since socket paths are limited to about 100 bytes.
+// ask returns one of the answers; a request it hasn't answered within
+// timeout (DefaultTimeout if zero) is denied, and its context is cancelled
+// so the question is withdrawn.
+func New(dir string, ask func(context.Context, Request) string, timeout time.Duration) (*Server, error) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create approval socket dir: %w", err)
+	}
+	if err := os.Chmod(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create approval socket dir: %w", err)
+	}
+	return &Server{
+		ask:       ask,
+		timeout:   timeout,
+		dir:       dir,
+		session:   make(map[string]bool),
+		listeners: make(map[string]net.Listener),
+	}, nil
+}
+
+// Listen starts serving agentID on its own unix socket and returns the
+// socket's path. Requests arriving on it are stamped with agentID, role
+// and taskID whatever they claim, so an agent can't ask as another.
+// Listening again for agentID replaces its socket.
+func (s *Server) Listen(agentID, role, taskID string) (string, error) {
+	s.Stop(agentID)
+	path := filepath.Join(s.dir, agentID+".sock")
+	// A socket left by a session that crashed blocks the bind.
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("listen for %s: %w", agentID, err)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return "", fmt.Errorf("listen for %s: %w", agentID, err)
+	}
+	s.mu.Lock()
+	s.listeners[agentID] = l
+	s.mu.Unlock()
+	go s.serve(l, Request{AgentID: agentID, Role: role, TaskID: taskID})
+	return path, nil
+}
+
+// Stop stops serving agentID and removes its socket.
+func (s *Server) Stop(agentID string) {
+	s.mu.Lock()
+	l := s.listeners[agentID]
+	delete(s.listeners, agentID)
+	s.mu.Unlock()
+	if l != nil {
+		// Closing a unix listener removes its socket.
+		l.Close()
+	}
+}
+
+// Timeout returns how long a request waits for an answer.
+func (s *Server) Timeout() time.Duration {
+	return s.timeout
+}
+
+// Close stops serving every agent and removes their sockets.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	for agentID, l := range s.listeners {
+		l.Close()
+		delete(s.listeners, agentID)
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// serve answers requests accepted on l as from the agent in id until l is
+// closed.
+func (s *Server) serve(l net.Listener, id Request) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn, id)
+	}
+}
+
+func (s *Server) handle(conn net.Conn, id Request) {
+	defer conn.Close()
+	var req Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+	req.AgentID, req.Role, req.TaskID = id.AgentID, id.Role, id.TaskID
+	json.NewEncoder(conn).Encode(s.Decide(req))
+}
+
+// Decide answers req, asking the human unless the same call was approved
+// for the session.
+func (s *Server) Decide(req Request) Response {
+	key := req.Rule + "\x00" + req.Tool + "\x00" + req.Target
+	if s.approvedForSession(key) {
+		return Response{Answer: ApproveSession, Details: "approved earlier this session"}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	answer := make(chan string, 1)
+	go func() {
+		s.asking.Lock()
+		defer s.asking.Unlock()
+		switch {
+		case s.approvedForSession(key):
+			answer <- ApproveSession
+			return
+		case ctx.Err() != nil:
+			// Timed out waiting for another question; don't ask.
+			return
+		}
+		a := s.ask(ctx, req)
+		if ctx.Err() != nil {
+			// Too late: the call was already denied.
+			return
+		}
+		if a == ApproveSession {
+			s.mu.Lock()
+			s.session[key] = true
+			s.mu.Unlock()
+		}
+		answer <- a
+	}()
+
+	select {
+	case a := <-answer:
+		switch a {
+		case ApproveOnce:
+			return Response{Answer: a, Details: "approved once"}
+		case ApproveSession:
+			return Response{Answer: a, Details: "approved for this session"}
+		}
+		return Response{Answer: Deny, Details: "denied"}
+	case <-ctx.Done():
+		return Response{Answer: Deny, Details: fmt.Sprintf("no answer within %s", s.timeout)}
+	}
+}
+
+func (s *Server) approvedForSession(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.session[key]
+}
+
+// Ask sends req to the server at socket and waits for its answer, for at
+// most a little longer than the server's timeout.
+func Ask(socket string, req Request, timeout time.Duration) (Response, error) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	conn, err := net.DialTimeout("unix", socket, hookMargin)
+	if err != nil {
+		return Response{}, fmt.Errorf("connect to approver: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout + hookMargin))
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return Response{}, fmt.Errorf("send approval request: %w", err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return Response{}, fmt.Errorf("read approval: %w", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,131 @@
+package approval
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDecideRemembersSessionApprovals(t *testing.T) {
+	var asked atomic.Int32
+	answers := []string{ApproveOnce, ApproveSession, Deny}
+	s, err := New(t.TempDir(), func(context.Context, Request) string {
+		return answers[asked.Add(1)-1]
+	}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	req := Request{AgentID: "w-1", Tool: "Bash", Target: "go mod tidy", Rule: "confirm_deps"}
+	for i, want := range []string{ApproveOnce, ApproveSession, ApproveSession} {
+		if got := s.Decide(req); got.Answer != want {
+			t.Errorf("request %d: answer = %+v, want %s", i+1, got, want)
+		}
+	}
+	if n := asked.Load(); n != 2 {
+		t.Errorf("asked %d times, want 2", n)
+	}
+
+	req.Target = "go get example.com/x"
+	if got := s.Decide(req); got.Approved() {
+		t.Errorf("a different command was approved: %+v", got)
+	}
+}
+
+func TestDecideTimesOutToDeny(t *testing.T) {
+	var asked atomic.Int32
+	s, err := New(t.TempDir(), func(ctx context.Context, req Request) string {
+		if asked.Add(1) == 1 {
+			// Nobody answers the first question before it is withdrawn.
+			<-ctx.Done()
+			return ApproveSession
+		}
+		return ApproveOnce
+	}, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	req := Request{Tool: "Bash", Target: "make"}
+	if got := s.Decide(req); got.Answer != Deny || got.Details != "no answer within 50ms" {
+		t.Errorf("answer = %+v", got)
+	}
+	// The withdrawn question doesn't hold up the next one, and its late
+	// answer isn't remembered.
+	if got := s.Decide(req); got.Answer != ApproveOnce {
+		t.Errorf("second answer = %+v, want %s", got, ApproveOnce)
+	}
+}
+
+func TestAsk(t *testing.T) {
+	var got Request
+	s, err := New(t.TempDir(), func(_ context.Context, req Request) string {
+		got = req
+		return ApproveOnce
+	}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	socket, err := s.Listen("w-1", "worker", "task-001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := Request{AgentID: "w-1", Role: "worker", TaskID: "task-001", Tool: "Bash", Target: "go mod tidy", Rule: "confirm_deps"}
+	resp, err := Ask(socket, req, s.Timeout())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Approved() || got != req {
+		t.Errorf("response %+v for request %+v", resp, got)
+	}
+
+	s.Close()
+	if _, err := Ask(socket, req, time.Second); err == nil {
+		t.Error("expected error once the server is closed")
+	}
+}
+
+func TestAskStampsTheSocketsAgent(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "approvals")
+	var got Request
+	s, err := New(dir, func(_ context.Context, req Request) string {
+		got = req
+		return ApproveOnce
+	}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if info, err := os.Stat(dir); err != nil || info.Mode().Perm() != 0o700 {
+		t.Fatalf("socket dir = %v, %v; want mode 0700", info, err)
+	}
+	socket, err := s.Listen("w-1", "worker", "task-001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Listen("w-2", "worker", "task-002"); err != nil {
+		t.Fatal(err)
+	}
+
+	// w-1 claims to be w-2 working on another task.
+	forged := Request{AgentID: "w-2", Role: "merger", TaskID: "task-002", Tool: "Bash", Target: "git push", Rule: "confirm_push"}
+	if _, err := Ask(socket, forged, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	want := Request{AgentID: "w-1", Role: "worker", TaskID: "task-001", Tool: "Bash", Target: "git push", Rule: "confirm_push"}
+	if got != want {
+		t.Errorf("asked about %+v, want %+v", got, want)
+	}
+
+	s.Stop("w-1")
+	if _, err := Ask(socket, forged, time.Second); err == nil {
+		t.Error("expected error once the agent's socket is stopped")
+	}
+}
@@ -0,0 +1,246 @@
+// Package audit reads agents' audit logs, the JSON Lines files in
+// .blueflame/hooks/logs/ where the watcher hook records every tool call it
+// judges, the egress proxy every connection, and the spawner each agent's
+// environment. It summarizes them per agent for "blueflame audit" and for
+// validators.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kylegalloway/blueflame/internal/egress"
+	"github.com/kylegalloway/blueflame/internal/ledger"
+	"github.com/kylegalloway/blueflame/internal/policy"
+)
+
+// LogSuffix ends every agent's audit log file name.
+const LogSuffix = ".audit.jsonl"
+
+// Decisions, as the hook and the proxy record them.
+const (
+	DecisionAllow = "allow"
+	DecisionBlock = "block"
+)
+
+// environmentTool is the tool name of an agent's environment record.
+const environmentTool = "environment"
+
+// Record is one audit log entry.
+type Record struct {
+	Timestamp time.Time `json:"timestamp"`
+	AgentID   string    `json:"agent_id"`
+	TaskID    string    `json:"task_id,omitempty"`
+	Tool      string    `json:"tool"`
+	Target    string    `json:"target"`
+	Decision  string    `json:"decision"`
+	Rule      string    `json:"rule"`
+	Details   string    `json:"details"`
+}
+
+// Log is one agent's audit log, with what the session ledgers say about
+// the agent.
+type Log struct {
+	AgentID string
+	Role    string
+	Session string
+	Tasks   []string
+	Records []Record
+}
+
+// ReadLog reads the audit log at path. A truncated last line, left by an
+// agent killed mid-write, is skipped.
+func ReadLog(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var records []Record
+	var bad error
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if bad != nil {
+			return nil, bad
+		}
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			bad = fmt.Errorf("%s:%d: %w", path, n, err)
+			continue
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read audit log: %w", err)
+	}
+	return records, nil
+}
+
+// Load reads every audit log in dir, filling in each agent's role, session
+// and tasks from the ledger entries of its run.
+func Load(dir string, entries []ledger.Entry) ([]Log, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+LogSuffix))
+	if err != nil {
+		return nil, fmt.Errorf("list audit logs: %w", err)
+	}
+	runs := make(map[string]ledger.Entry, len(entries))
+	for _, e := range entries {
+		runs[e.AgentID] = e
+	}
+
+	logs := make([]Log, 0, len(paths))
+	for _, path := range paths {
+		records, err := ReadLog(path)
+		if err != nil {
+			return nil, err
+		}
+		l := Log{AgentID: strings.TrimSuffix(filepath.Base(path), LogSuffix), Records: records}
+		if e, ok := runs[l.AgentID]; ok {
+			l.Role, l.Session, l.Tasks = e.Role, e.Session, e.Tasks
+		}
+		for _, r := range records {
+			if r.TaskID != "" && !slices.Contains(l.Tasks, r.TaskID) {
+				l.Tasks = append(l.Tasks, r.TaskID)
+			}
+		}
+		logs = append(logs, l)
+	}
+	slices.SortFunc(logs, func(a, b Log) int { return a.start().Compare(b.start()) })
+	return logs, nil
+}
+
+// start is when the agent's first record was written.
+func (l Log) start() time.Time {
+	if len(l.Records) == 0 {
+		return time.Time{}
+	}
+	return l.Records[0].Timestamp
+}
+
+// Filter selects logs and records. Empty fields match anything.
+type Filter struct {
+	Session  string
+	Task     string
+	Agent    string
+	Decision string
+}
+
+// Apply returns the logs f selects, keeping only the records with its
+// decision. A log left with no records by the decision filter is dropped.
+func (f Filter) Apply(logs []Log) []Log {
+	var out []Log
+	for _, l := range logs {
+		if f.Session != "" && l.Session != f.Session ||
+			f.Task != "" && !slices.Contains(l.Tasks, f.Task) ||
+			f.Agent != "" && l.AgentID != f.Agent {
+			continue
+		}
+		if f.Decision != "" {
+			l.Records = slices.DeleteFunc(slices.Clone(l.Records), func(r Record) bool { return r.Decision != f.Decision })
+			if len(l.Records) == 0 {
+				continue
+			}
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+// Count is how often something occurred.
+type Count struct {
+	Name string
+	N    int
+}
+
+// counter tallies names, remembering the order they first appeared in.
+type counter []Count
+
+func (c *counter) add(name string) {
+	for i := range *c {
+		if (*c)[i].Name == name {
+			(*c)[i].N++
+			return
+		}
+	}
+	*c = append(*c, Count{Name: name, N: 1})
+}
+
+// sorted returns the counts, most frequent first.
+func (c counter) sorted() []Count {
+	out := slices.Clone([]Count(c))
+	slices.SortStableFunc(out, func(a, b Count) int { return b.N - a.N })
+	return out
+}
+
+// Summary is what one agent did, as its audit log tells it.
+type Summary struct {
+	AgentID string
+	Role    string
+	Session string
+	Tasks   []string
+
+	// Calls counts the tool calls the watcher judged, Blocked those it
+	// blocked.
+	Calls   int
+	Blocked int
+	Tools   []Count
+	// Blocks counts blocked calls and connections by rule; BlockedTargets
+	// lists them as "rule: target".
+	Blocks         []Count
+	BlockedTargets []string
+	// Written and Read are the files the agent was allowed to change and
+	// read, Commands the Bash commands it was allowed to run, and Hosts the
+	// hosts it reached through the egress proxy.
+	Written  []Count
+	Read     []Count
+	Commands []Count
+	Hosts    []Count
+}
+
+// Summarize tallies an agent's log.
+func Summarize(l Log) Summary {
+	s := Summary{AgentID: l.AgentID, Role: l.Role, Session: l.Session, Tasks: l.Tasks}
+	var tools, blocks, written, read, commands, hosts counter
+	for _, r := range l.Records {
+		if r.Tool == environmentTool {
+			continue
+		}
+		if r.Decision == DecisionBlock {
+			blocks.add(r.Rule)
+			s.BlockedTargets = append(s.BlockedTargets, r.Rule+": "+r.Target)
+		}
+		if r.Tool == egress.Tool {
+			if r.Decision == DecisionAllow {
+				hosts.add(r.Target)
+			}
+			continue
+		}
+
+		s.Calls++
+		tools.add(r.Tool)
+		if r.Decision == DecisionBlock {
+			s.Blocked++
+			continue
+		}
+		switch {
+		case r.Tool == "Bash":
+			commands.add(r.Target)
+		case slices.Contains(policy.WriteTools, r.Tool):
+			written.add(r.Target)
+		case r.Tool == "Read":
+			read.add(r.Target)
+		}
+	}
+	s.Tools, s.Blocks = tools.sorted(), blocks.sorted()
+	s.Written, s.Read = written.sorted(), read.sorted()
+	s.Commands, s.Hosts = commands.sorted(), hosts.sorted()
+	return s
+}
@@ -0,0 +1,182 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kylegalloway/blueflame/internal/ledger"
+)
+
+const workerLog = `{"timestamp":"2026-03-01T10:00:00Z","agent_id":"worker-1","task_id":"task-001","tool":"environment","target":"PATH,HOME","decision":"allow","rule":"env_policy","details":""}
+{"timestamp":"2026-03-01T10:00:01Z","agent_id":"worker-1","task_id":"task-001","tool":"Read","target":"src/a.go","decision":"allow","rule":"tool_allowed","details":""}
+{"timestamp":"2026-03-01T10:00:02Z","agent_id":"worker-1","task_id":"task-001","tool":"Edit","target":"src/a.go","decision":"allow","rule":"tool_allowed","details":""}
+{"timestamp":"2026-03-01T10:00:03Z","agent_id":"worker-1","task_id":"task-001","tool":"Edit","target":"src/a.go","decision":"allow","rule":"tool_allowed","details":""}
+{"timestamp":"2026-03-01T10:00:04Z","agent_id":"worker-1","task_id":"task-001","tool":"Write","target":"docs/b.md","decision":"block","rule":"file_scope","details":"outside file locks"}
+{"timestamp":"2026-03-01T10:00:05Z","agent_id":"worker-1","task_id":"task-001","tool":"Bash","target":"go test ./...","decision":"allow","rule":"tool_allowed","details":""}
+{"timestamp":"2026-03-01T10:00:06Z","agent_id":"worker-1","tool":"egress","target":"proxy.golang.org:443","decision":"allow","rule":"egress_allowlist","details":""}
+{"timestamp":"2026-03-01T10:00:07Z","agent_id":"worker-1","tool":"egress","target":"evil.example:443","decision":"block","rule":"egress_allowlist","details":""}
+`
+
+const validatorLog = `{"timestamp":"2026-03-01T09:00:00Z","agent_id":"validator-1","tool":"Read","target":"src/a.go","decision":"allow","rule":"tool_allowed","details":""}
+`
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadLogSkipsTruncatedLastLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "worker-1"+LogSuffix)
+	writeFile(t, path, workerLog+`{"timestamp":"2026-03-01T10:00:08Z","agent_id":"wor`)
+
+	records, err := ReadLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 8 {
+		t.Fatalf("got %d records, want 8", len(records))
+	}
+	want := time.Date(2026, 3, 1, 10, 0, 1, 0, time.UTC)
+	if r := records[1]; r.Tool != "Read" || r.TaskID != "task-001" || !r.Timestamp.Equal(want) {
+		t.Errorf("record = %+v", r)
+	}
+}
+
+func TestReadLogRejectsBadLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "worker-1"+LogSuffix)
+	writeFile(t, path, "not json\n"+workerLog)
+
+	if _, err := ReadLog(path); err == nil || !strings.Contains(err.Error(), ":1:") {
+		t.Errorf("err = %v, want an error naming line 1", err)
+	}
+}
+
+func TestLoadAndFilter(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "worker-1"+LogSuffix), workerLog)
+	writeFile(t, filepath.Join(dir, "validator-1"+LogSuffix), validatorLog)
+	writeFile(t, filepath.Join(dir, "notes.txt"), "ignored")
+
+	logs, err := Load(dir, []ledger.Entry{
+		{Session: "ses-1", AgentID: "worker-1", Role: "worker"},
+		{Session: "ses-2", AgentID: "validator-1", Role: "validator", Tasks: []string{"task-001"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 || logs[0].AgentID != "validator-1" || logs[1].AgentID != "worker-1" {
+		t.Fatalf("logs not ordered by start: %+v", logs)
+	}
+	if w := logs[1]; w.Role != "worker" || w.Session != "ses-1" || len(w.Tasks) != 1 || w.Tasks[0] != "task-001" {
+		t.Errorf("worker log = %+v", w)
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"all", Filter{}, []string{"validator-1", "worker-1"}},
+		{"session", Filter{Session: "ses-1"}, []string{"worker-1"}},
+		{"task", Filter{Task: "task-001"}, []string{"validator-1", "worker-1"}},
+		{"agent", Filter{Agent: "validator-1"}, []string{"validator-1"}},
+		{"blocked", Filter{Decision: DecisionBlock}, []string{"worker-1"}},
+		{"no match", Filter{Task: "task-999"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.filter.Apply(logs)
+			var ids []string
+			for _, l := range got {
+				ids = append(ids, l.AgentID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("agents = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+
+	if blocked := (Filter{Decision: DecisionBlock}).Apply(logs); len(blocked[0].Records) != 2 {
+		t.Errorf("blocked records = %+v, want 2", blocked[0].Records)
+	}
+	if len(logs[1].Records) != 8 {
+		t.Error("Apply changed the logs it was given")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "worker-1"+LogSuffix)
+	writeFile(t, path, workerLog)
+	records, err := ReadLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := Summarize(Log{AgentID: "worker-1", Role: "worker", Tasks: []string{"task-001"}, Records: records})
+	if s.Calls != 5 || s.Blocked != 1 {
+		t.Errorf("calls = %d, blocked = %d, want 5 and 1", s.Calls, s.Blocked)
+	}
+	if len(s.Tools) == 0 || s.Tools[0] != (Count{Name: "Edit", N: 2}) {
+		t.Errorf("tools = %+v, want Edit first", s.Tools)
+	}
+	if len(s.Blocks) != 2 || len(s.BlockedTargets) != 2 || s.BlockedTargets[0] != "file_scope: docs/b.md" {
+		t.Errorf("blocks = %+v, targets = %v", s.Blocks, s.BlockedTargets)
+	}
+	if len(s.Written) != 1 || s.Written[0] != (Count{Name: "src/a.go", N: 2}) {
+		t.Errorf("written = %+v", s.Written)
+	}
+	if len(s.Commands) != 1 || len(s.Read) != 1 || len(s.Hosts) != 1 || s.Hosts[0].Name != "proxy.golang.org:443" {
+		t.Errorf("commands = %+v, read = %+v, hosts = %+v", s.Commands, s.Read, s.Hosts)
+	}
+
+	compact := Compact(s)
+	for _, want := range []string{
+		"worker-1 (worker, task-001): 5 tool calls, 1 blocked",
+		"Blocked:\n  - file_scope: docs/b.md\n  - egress_allowlist: evil.example:443",
+		"Files written: src/a.go (2)",
+		"Commands: go test ./...",
+		"Network: proxy.golang.org:443",
+	} {
+		if !strings.Contains(compact, want) {
+			t.Errorf("compact summary missing %q:\n%s", want, compact)
+		}
+	}
+	if strings.Contains(compact, "environment") {
+		t.Errorf("compact summary includes the environment record:\n%s", compact)
+	}
+}
+
+func TestCompactCapsLists(t *testing.T) {
+	s := Summary{AgentID: "worker-1", Calls: 20}
+	for i := range 10 {
+		s.Written = append(s.Written, Count{Name: "src/" + strings.Repeat("x", i+1) + ".go", N: 1})
+	}
+	s.Commands = []Count{{Name: strings.Repeat("echo hi; ", 30), N: 1}}
+
+	compact := Compact(s)
+	if !strings.Contains(compact, "and 2 more") {
+		t.Errorf("written list not capped:\n%s", compact)
+	}
+	for _, line := range strings.Split(compact, "\n") {
+		if strings.HasPrefix(line, "Commands: ") && len(line) > len("Commands: ")+compactTarget {
+			t.Errorf("command not truncated: %q", line)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	if got := Format(nil); got != "No audit records match.\n" {
+		t.Errorf("Format(nil) = %q", got)
+	}
+	got := Format([]Summary{{AgentID: "worker-1", Session: "ses-1", Calls: 1, Tools: []Count{{Name: "Read", N: 1}}}})
+	for _, want := range []string{"worker-1: 1 tool calls, 0 blocked", "Session:  ses-1", "Tools:    Read"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Format missing %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// compactItems caps each list in a compact summary, and compactTarget the
+// length of each target in it.
+const (
+	compactItems  = 8
+	compactTarget = 120
+)
+
+// Format renders summaries in full for the terminal.
+func Format(summaries []Summary) string {
+	if len(summaries) == 0 {
+		return "No audit records match.\n"
+	}
+	var b strings.Builder
+	for i, s := range summaries {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(header(s))
+		if s.Session != "" {
+			fmt.Fprintf(&b, "  Session:  %s\n", s.Session)
+		}
+		writeCounts(&b, "  Tools:    ", s.Tools, 0, 0)
+		writeCounts(&b, "  Blocks:   ", s.Blocks, 0, 0)
+		for _, t := range s.BlockedTargets {
+			fmt.Fprintf(&b, "    - %s\n", t)
+		}
+		writeCounts(&b, "  Written:  ", s.Written, 0, 0)
+		writeCounts(&b, "  Read:     ", s.Read, 0, 0)
+		writeCounts(&b, "  Commands: ", s.Commands, 0, 0)
+		writeCounts(&b, "  Network:  ", s.Hosts, 0, 0)
+	}
+	return b.String()
+}
+
+// Compact renders a short summary for a validator's prompt, capping lists
+// and long targets.
+func Compact(s Summary) string {
+	var b strings.Builder
+	b.WriteString(header(s))
+	writeCounts(&b, "Tools: ", s.Tools, 0, 0)
+	if len(s.BlockedTargets) > 0 {
+		b.WriteString("Blocked:\n")
+		for i, t := range s.BlockedTargets {
+			if i == compactItems {
+				fmt.Fprintf(&b, "  - and %d more\n", len(s.BlockedTargets)-i)
+				break
+			}
+			fmt.Fprintf(&b, "  - %s\n", truncate(t, compactTarget))
+		}
+	}
+	writeCounts(&b, "Files written: ", s.Written, compactItems, compactTarget)
+	writeCounts(&b, "Commands: ", s.Commands, compactItems, compactTarget)
+	writeCounts(&b, "Network: ", s.Hosts, compactItems, compactTarget)
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// FormatRecords renders records one per line.
+func FormatRecords(logs []Log) string {
+	var b strings.Builder
+	for _, l := range logs {
+		for _, r := range l.Records {
+			fmt.Fprintf(&b, "%s  %-16s %-11s %-5s %-22s %s\n",
+				r.Timestamp.Local().Format(time.DateTime), l.AgentID, r.Tool, r.Decision, r.Rule, r.Target)
+		}
+	}
+	return b.String()
+}
+
+func header(s Summary) string {
+	var b strings.Builder
+	b.WriteString(s.AgentID)
+	if s.Role != "" {
+		fmt.Fprintf(&b, " (%s", s.Role)
+		if len(s.Tasks) > 0 {
+			fmt.Fprintf(&b, ", %s", strings.Join(s.Tasks, ", "))
+		}
+		b.WriteString(")")
+	} else if len(s.Tasks) > 0 {
+		fmt.Fprintf(&b, " (%s)", strings.Join(s.Tasks, ", "))
+	}
+	fmt.Fprintf(&b, ": %d tool calls, %d blocked\n", s.Calls, s.Blocked)
+	return b.String()
+}
+
+// writeCounts writes a labeled line of counts, at most max of them (0 for
+// all), each name cut to width (0 for no limit). Nothing is written for no
+// counts.
+func writeCounts(b *strings.Builder, label string, counts []Count, max, width int) {
+	if len(counts) == 0 {
+		return
+	}
+	parts := make([]string, 0, len(counts))
+	for i, c := range counts {
+		if max > 0 && i == max {
+			parts = append(parts, fmt.Sprintf("and %d more", len(counts)-i))
+			break
+		}
+		name := c.Name
+		if width > 0 {
+			name = truncate(name, width)
+		}
+		if c.N > 1 {
+			name += fmt.Sprintf(" (%d)", c.N)
+		}
+		parts = append(parts, name)
+	}
+	b.WriteString(label + strings.Join(parts, ", ") + "\n")
+}
+
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
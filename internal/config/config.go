@@ -2,9 +2,14 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -18,10 +23,12 @@ type Config struct {
 	Planning      PlanningConfig    `yaml:"planning"`
 	Models        ModelsConfig      `yaml:"models"`
 	Permissions   PermissionsConfig `yaml:"permissions"`
+	Policy        PolicyConfig      `yaml:"policy"`
 	Validation    ValidationConfig  `yaml:"validation"`
 	Superpowers   SuperpowersConfig `yaml:"superpowers"`
 	Beads         BeadsConfig       `yaml:"beads"`
 	Hooks         HooksConfig       `yaml:"hooks"`
+	Prompts       PromptsConfig     `yaml:"prompts"`
 }
 
 type ProjectConfig struct {
@@ -42,13 +49,54 @@ type ConcurrencyConfig struct {
 }
 
 type LimitsConfig struct {
-	AgentTimeout      time.Duration `yaml:"agent_timeout"`
-	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
-	MaxRetries        int           `yaml:"max_retries"`
-	MaxWaveCycles     int           `yaml:"max_wave_cycles"`
-	MaxSessionCostUSD float64       `yaml:"max_session_cost_usd"`
-	MaxSessionTokens  int           `yaml:"max_session_tokens"`
-	TokenBudget       TokenBudget   `yaml:"token_budget"`
+	AgentTimeout      time.Duration      `yaml:"agent_timeout"`
+	HeartbeatInterval time.Duration      `yaml:"heartbeat_interval"`
+	MaxRetries        int                `yaml:"max_retries"`
+	MaxWaveCycles     int                `yaml:"max_wave_cycles"`
+	MaxSessionCostUSD float64            `yaml:"max_session_cost_usd"`
+	MaxSessionTokens  int                `yaml:"max_session_tokens"`
+	TokenBudget       TokenBudget        `yaml:"token_budget"`
+	TaskOverrides     TaskOverrideLimits `yaml:"task_overrides"`
+
+	// RateLimit decides what happens when agents hit a rate or usage limit.
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+}
+
+// Rate limit actions.
+const (
+	RateLimitWait = "wait"
+	RateLimitExit = "exit"
+)
+
+// RateLimitConfig configures pausing when agents hit a rate or usage limit.
+type RateLimitConfig struct {
+	// OnLimit is "wait" to pause until the limit resets, or "exit" to save
+	// state and exit for a later --resume.
+	OnLimit string `yaml:"on_limit"`
+	// DefaultWait is how long to wait when the CLI gives no reset time.
+	DefaultWait time.Duration `yaml:"default_wait"`
+	// MaxWait caps waiting; longer waits exit instead.
+	MaxWait time.Duration `yaml:"max_wait"`
+	// MaxPauses caps consecutive pauses, counted until a wave gets through
+	// validation without hitting a limit; the next limit exits instead.
+	// Zero means no cap.
+	MaxPauses int `yaml:"max_pauses"`
+}
+
+// TaskOverrideLimits bounds the per-task overrides in tasks.yaml. Unset
+// budget and timeout ceilings fall back to the worker-wide settings, and
+// empty lists allow nothing extra, so by default a task can only tighten
+// its limits.
+type TaskOverrideLimits struct {
+	// Models lists the worker models a task may select.
+	Models          []string      `yaml:"models"`
+	MaxBudgetUSD    float64       `yaml:"max_budget_usd"`
+	MaxBudgetTokens int           `yaml:"max_budget_tokens"`
+	MaxTimeout      time.Duration `yaml:"max_timeout"`
+	// AllowedCommands and AllowedTools list what a task may add to the
+	// permissions every worker gets.
+	AllowedCommands []string `yaml:"allowed_commands"`
+	AllowedTools    []string `yaml:"allowed_tools"`
 }
 
 type TokenBudget struct {
@@ -61,6 +109,26 @@ type TokenBudget struct {
 	MergerUSD      float64 `yaml:"merger_usd"`
 	MergerTokens   int     `yaml:"merger_tokens"`
 	WarnThreshold  float64 `yaml:"warn_threshold"`
+
+	// CountTokens lists the token classes that count toward token budgets
+	// and limits.max_session_tokens. Empty counts all of them.
+	CountTokens []string `yaml:"count_tokens"`
+}
+
+// Token classes reported in claude usage.
+const (
+	TokenInput         = "input"
+	TokenOutput        = "output"
+	TokenCacheCreation = "cache_creation"
+	TokenCacheRead     = "cache_read"
+)
+
+// TokenClasses lists every token class.
+var TokenClasses = []string{TokenInput, TokenOutput, TokenCacheCreation, TokenCacheRead}
+
+// Counts reports whether a token class counts toward token budgets.
+func (tb *TokenBudget) Counts(class string) bool {
+	return len(tb.CountTokens) == 0 || slices.Contains(tb.CountTokens, class)
 }
 
 // BudgetUnit distinguishes USD from token budgets.
@@ -106,10 +174,108 @@ type SandboxConfig struct {
 	MaxFileSizeMB int  `yaml:"max_file_size_mb"`
 	MaxOpenFiles  int  `yaml:"max_open_files"`
 	AllowNetwork  bool `yaml:"allow_network"`
+
+	// Cgroup is "auto" to place each agent in its own cgroup v2 subtree
+	// when CgroupParent is set and delegated, or "off" to use rlimits only.
+	// CgroupParent is a delegated cgroup directory to create agent cgroups
+	// under, or "self" to let Blue Flame move itself into a child of its
+	// own cgroup and use that; empty uses rlimits only.
+	Cgroup       string `yaml:"cgroup"`
+	CgroupParent string `yaml:"cgroup_parent"`
+	// MaxCPUs caps CPU bandwidth (cpu.max) and MaxPids the number of tasks
+	// (pids.max) for an agent's whole process tree. Both need a cgroup.
+	MaxCPUs float64 `yaml:"max_cpus"`
+	MaxPids int     `yaml:"max_pids"`
+
+	// Landlock is "auto" to confine agent writes with Landlock where the
+	// kernel supports it, or "off". Confined agents may write only to
+	// their working directory, what a commit on their task branch touches
+	// in the repository's .git, their $AGENT_TMP, /dev/null and /dev/tty,
+	// the hook audit logs, and WritablePaths (such as caches; "~" expands
+	// to the home directory).
+	Landlock      string   `yaml:"landlock"`
+	WritablePaths []string `yaml:"writable_paths"`
+
+	// Egress gives agents allowlisted network access through the
+	// orchestrator's proxy instead of none.
+	Egress EgressConfig `yaml:"egress"`
+
+	// Env decides which environment variables agents see.
+	Env EnvConfig `yaml:"env"`
+}
+
+// EnvConfig is the environment policy for spawned agents. Only variables
+// matching Allow pass through from Blue Flame's environment (nil passes
+// everything); variables matching Deny are always stripped; Set adds or
+// overrides variables. Allow and Deny entries are names or globs like
+// "LC_*". Set values may reference $WORKTREE (the agent's working
+// directory), $AGENT_ID, $AGENT_TMP (a scratch directory removed when the
+// agent finishes), and Blue Flame's own environment, like $HOME.
+type EnvConfig struct {
+	Allow []string          `yaml:"allow"`
+	Set   map[string]string `yaml:"set"`
+	Deny  []string          `yaml:"deny"`
 }
 
+// EgressConfig configures the egress proxy. When enabled and AllowNetwork
+// is false, each agent runs in its own network namespace whose only way
+// out is an HTTP proxy in the orchestrator (via HTTPS_PROXY), which admits
+// connections to AllowedHosts and logs every one to the agent's audit log.
+// Entries are host names or "*.domain" for any subdomain, optionally with
+// ":port" to allow only that port.
+type EgressConfig struct {
+	Enabled      bool     `yaml:"enabled"`
+	AllowedHosts []string `yaml:"allowed_hosts"`
+}
+
+// Sandbox feature modes: "auto" uses a feature when the platform supports
+// it and falls back otherwise.
+const (
+	SandboxAuto = "auto"
+	SandboxOff  = "off"
+
+	// CgroupParentSelf opts in to creating agent cgroups under the
+	// orchestrator's own cgroup.
+	CgroupParentSelf = "self"
+)
+
 type PlanningConfig struct {
-	Interactive bool `yaml:"interactive"`
+	Interactive bool              `yaml:"interactive"`
+	RepoContext RepoContextConfig `yaml:"repo_context"`
+	// MaxRepairAttempts is how many times the planner is re-invoked with
+	// the validation errors when its output doesn't match the plan schema.
+	MaxRepairAttempts int            `yaml:"max_repair_attempts"`
+	Lint              PlanLintConfig `yaml:"lint"`
+	Ensemble          EnsembleConfig `yaml:"ensemble"`
+}
+
+// EnsembleConfig configures multi-planner mode, used when
+// concurrency.planning is greater than 1.
+type EnsembleConfig struct {
+	// Models are assigned to planners in turn; empty uses models.planner.
+	Models []string `yaml:"models"`
+	// Variations are planning-approach hints assigned to planners in turn;
+	// empty uses built-in variations.
+	Variations []string `yaml:"variations"`
+	// Judge runs a judge agent to rank the candidate plans.
+	Judge bool `yaml:"judge"`
+}
+
+// PlanLintConfig configures the lint pass run on each proposed plan.
+type PlanLintConfig struct {
+	// AutoFix passes lint findings to the planner when re-planning.
+	AutoFix bool `yaml:"auto_fix"`
+	// BroadLockRatio is the fraction of repo files one task may lock
+	// before it is flagged.
+	BroadLockRatio float64 `yaml:"broad_lock_ratio"`
+}
+
+// RepoContextConfig bounds the repository context pack given to the planner.
+type RepoContextConfig struct {
+	// MaxTokens caps the pack. Zero takes the default; a negative value
+	// turns the pack off.
+	MaxTokens     int `yaml:"max_tokens"`
+	GitLogEntries int `yaml:"git_log_entries"`
 }
 
 type ModelsConfig struct {
@@ -117,6 +283,92 @@ type ModelsConfig struct {
 	Worker    string `yaml:"worker"`
 	Validator string `yaml:"validator"`
 	Merger    string `yaml:"merger"`
+
+	// Routing picks worker and validator models by task complexity.
+	Routing RoutingConfig `yaml:"routing"`
+}
+
+// Complexity levels a planner may estimate, lowest first.
+var (
+	RiskLevels = []string{"low", "medium", "high"}
+	SizeLevels = []string{"small", "medium", "large"}
+)
+
+// RoutingConfig maps planner complexity estimates to models and budgets.
+// Bands are tried in order; a task takes the first band it fits.
+type RoutingConfig struct {
+	Bands []RoutingBand `yaml:"bands"`
+}
+
+// RoutingBand is one row of the routing table. Its Max fields bound the
+// estimates a task may have to fit; unset bounds match anything. Unset
+// models and budgets keep the role defaults.
+type RoutingBand struct {
+	Name     string `yaml:"name"`
+	MaxFiles int    `yaml:"max_files"`
+	MaxRisk  string `yaml:"max_risk"`
+	MaxSize  string `yaml:"max_size"`
+
+	WorkerModel     string  `yaml:"worker_model"`
+	ValidatorModel  string  `yaml:"validator_model"`
+	WorkerUSD       float64 `yaml:"worker_usd"`
+	WorkerTokens    int     `yaml:"worker_tokens"`
+	ValidatorUSD    float64 `yaml:"validator_usd"`
+	ValidatorTokens int     `yaml:"validator_tokens"`
+}
+
+// Route returns the first band that fits a task's estimates, or nil. A
+// missing estimate (zero files, empty risk or size) only fits bands that
+// leave that bound unset, so unestimated tasks aren't routed cheaply.
+func (r *RoutingConfig) Route(files int, risk, size string) *RoutingBand {
+	for i := range r.Bands {
+		b := &r.Bands[i]
+		if b.MaxFiles > 0 && (files == 0 || files > b.MaxFiles) {
+			continue
+		}
+		if !withinLevel(RiskLevels, risk, b.MaxRisk) || !withinLevel(SizeLevels, size, b.MaxSize) {
+			continue
+		}
+		return b
+	}
+	return nil
+}
+
+// Band returns the band with the given name, or nil.
+func (r *RoutingConfig) Band(name string) *RoutingBand {
+	for i := range r.Bands {
+		if r.Bands[i].Name == name {
+			return &r.Bands[i]
+		}
+	}
+	return nil
+}
+
+func (b *RoutingBand) WorkerBudget() BudgetSpec {
+	return budgetFor(b.WorkerUSD, b.WorkerTokens)
+}
+
+func (b *RoutingBand) ValidatorBudget() BudgetSpec {
+	return budgetFor(b.ValidatorUSD, b.ValidatorTokens)
+}
+
+// withinLevel reports whether v is at or below limit in levels. An empty
+// limit allows anything; an empty or unknown v exceeds any limit.
+func withinLevel(levels []string, v, limit string) bool {
+	if limit == "" {
+		return true
+	}
+	i := levelIndex(levels, v)
+	return i >= 0 && i <= levelIndex(levels, limit)
+}
+
+func levelIndex(levels []string, v string) int {
+	for i, l := range levels {
+		if l == v {
+			return i
+		}
+	}
+	return -1
 }
 
 type PermissionsConfig struct {
@@ -127,6 +379,35 @@ type PermissionsConfig struct {
 	BashRules    BashRules `yaml:"bash_rules"`
 }
 
+// PolicyConfig holds ordered permission rules. They are checked before the
+// permissions lists, and the first rule matching a tool call decides it.
+type PolicyConfig struct {
+	Rules []PolicyRule `yaml:"rules"`
+	// AskTimeout is how long a call an "ask" rule matched waits for a
+	// human's answer before it is denied.
+	AskTimeout time.Duration `yaml:"ask_timeout"`
+}
+
+// PolicyRule matches tool calls by role, tool, path glob ("**" spans
+// directories), Bash command regex and task ID glob; empty conditions match
+// anything. Effect is allow, deny or ask.
+type PolicyRule struct {
+	Name     string   `yaml:"name"`
+	Effect   string   `yaml:"effect"`
+	Roles    []string `yaml:"roles"`
+	Tools    []string `yaml:"tools"`
+	Paths    []string `yaml:"paths"`
+	Commands []string `yaml:"commands"`
+	Tasks    []string `yaml:"tasks"`
+	Reason   string   `yaml:"reason"`
+}
+
+// PolicyEffects are the effects a policy rule may have.
+var PolicyEffects = []string{"allow", "deny", "ask"}
+
+// PolicyRoles are the agent roles a policy rule may name.
+var PolicyRoles = []string{"planner", "judge", "worker", "validator", "merger"}
+
 type BashRules struct {
 	AllowedCommands []string `yaml:"allowed_commands"`
 	BlockedPatterns []string `yaml:"blocked_patterns"`
@@ -138,6 +419,7 @@ type ValidationConfig struct {
 	RequireTests          RequireTestsConfig          `yaml:"require_tests"`
 	FileScope             FileScopeConfig             `yaml:"file_scope"`
 	ValidatorDiagnostics  ValidatorDiagnosticsConfig  `yaml:"validator_diagnostics"`
+	Quorum                QuorumConfig                `yaml:"quorum"`
 }
 
 type CommitFormatConfig struct {
@@ -157,6 +439,8 @@ type RequireTestsConfig struct {
 }
 
 type FileScopeConfig struct {
+	// Enforce makes PostCheck fail a worker whose changes fall outside its
+	// task's file_locks. The watcher blocks such writes regardless.
 	Enforce bool `yaml:"enforce"`
 }
 
@@ -166,6 +450,57 @@ type ValidatorDiagnosticsConfig struct {
 	Timeout  time.Duration `yaml:"timeout"`
 }
 
+// Validator quorum policies.
+const (
+	QuorumUnanimous     = "unanimous"
+	QuorumMajority      = "majority"
+	QuorumAnyFailBlocks = "any-fail-blocks"
+)
+
+// QuorumRule sets how many validators review a task and how their verdicts
+// combine.
+type QuorumRule struct {
+	Validators int      `yaml:"validators"`
+	Models     []string `yaml:"models"`
+	Policy     string   `yaml:"policy"`
+}
+
+// QuorumConfig is the default quorum rule plus overrides keyed by task ID
+// or cohesion group.
+type QuorumConfig struct {
+	QuorumRule `yaml:",inline"`
+	Overrides  map[string]QuorumRule `yaml:"overrides"`
+}
+
+// RuleFor returns the quorum rule for a task: its own override, else its
+// cohesion group's, else the default. Fields left unset in an override
+// inherit the default.
+func (q *QuorumConfig) RuleFor(taskID, cohesionGroup string) QuorumRule {
+	rule := q.QuorumRule
+	o, ok := q.Overrides[taskID]
+	if !ok && cohesionGroup != "" {
+		o, ok = q.Overrides[cohesionGroup]
+	}
+	if ok {
+		if o.Validators > 0 {
+			rule.Validators = o.Validators
+		}
+		if len(o.Models) > 0 {
+			rule.Models = o.Models
+		}
+		if o.Policy != "" {
+			rule.Policy = o.Policy
+		}
+	}
+	if rule.Validators < 1 {
+		rule.Validators = 1
+	}
+	if rule.Policy == "" {
+		rule.Policy = QuorumUnanimous
+	}
+	return rule
+}
+
 type SuperpowersConfig struct {
 	Enabled bool     `yaml:"enabled"`
 	Skills  []string `yaml:"skills"`
@@ -191,6 +526,33 @@ type HooksConfig struct {
 	OnFailure     string `yaml:"on_failure"`
 }
 
+// PromptsConfig configures user-overridable prompt templates.
+type PromptsConfig struct {
+	// TemplatesDir holds <role>.<kind>.tmpl files (kind is "system" or "task").
+	// Relative paths are resolved against project.repo. Roles without a
+	// template file fall back to the built-in prompts.
+	TemplatesDir string `yaml:"templates_dir"`
+	// WorkerContext pre-reads a task's files into the worker prompt.
+	WorkerContext WorkerContextConfig `yaml:"worker_context"`
+}
+
+// WorkerContextConfig configures per-task code excerpts for worker prompts.
+type WorkerContextConfig struct {
+	Enabled   bool `yaml:"enabled"`
+	MaxTokens int  `yaml:"max_tokens"`
+}
+
+// promptTemplateName matches the recognized prompt template file names.
+var promptTemplateName = regexp.MustCompile(`^(planner|worker|validator|merger)\.(system|task)\.tmpl$`)
+
+// ResolvePath resolves a path relative to the project repo.
+func (p ProjectConfig) ResolvePath(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(p.Repo, path)
+}
+
 // Load reads and parses a blueflame.yaml file, applying defaults and validation.
 func Load(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
@@ -233,6 +595,10 @@ func Validate(cfg *Config) error {
 		return fmt.Errorf("project.repo %q is not a directory", cfg.Project.Repo)
 	}
 
+	if cfg.Concurrency.Planning < 1 || cfg.Concurrency.Planning > 8 {
+		return fmt.Errorf("concurrency.planning must be 1-8, got %d", cfg.Concurrency.Planning)
+	}
+
 	if cfg.Concurrency.Development < 1 || cfg.Concurrency.Development > 8 {
 		return fmt.Errorf("concurrency.development must be 1-8, got %d", cfg.Concurrency.Development)
 	}
@@ -260,6 +626,20 @@ func Validate(cfg *Config) error {
 	if err := validateRoleBudget("merger", tb.MergerUSD, tb.MergerTokens); err != nil {
 		return err
 	}
+	for _, class := range tb.CountTokens {
+		if !slices.Contains(TokenClasses, class) {
+			return fmt.Errorf("limits.token_budget.count_tokens: unknown token class %q (want one of %v)", class, TokenClasses)
+		}
+	}
+
+	if cfg.Policy.AskTimeout < 0 {
+		return fmt.Errorf("policy.ask_timeout must be >= 0, got %s", cfg.Policy.AskTimeout)
+	}
+	for i, r := range cfg.Policy.Rules {
+		if err := validatePolicyRule(r); err != nil {
+			return fmt.Errorf("policy.rules[%d]: %w", i, err)
+		}
+	}
 
 	// Validate glob patterns
 	for _, p := range cfg.Permissions.AllowedPaths {
@@ -286,6 +666,98 @@ func Validate(cfg *Config) error {
 		}
 	}
 
+	if r := cfg.Planning.Lint.BroadLockRatio; r < 0 || r > 1 {
+		return fmt.Errorf("planning.lint.broad_lock_ratio must be between 0 and 1, got %g", r)
+	}
+	if cfg.Planning.MaxRepairAttempts < 0 {
+		return fmt.Errorf("planning.max_repair_attempts must be >= 0, got %d", cfg.Planning.MaxRepairAttempts)
+	}
+	if cfg.Planning.RepoContext.GitLogEntries < 0 {
+		return fmt.Errorf("planning.repo_context.git_log_entries must be >= 0, got %d", cfg.Planning.RepoContext.GitLogEntries)
+	}
+
+	rl := cfg.Limits.RateLimit
+	if rl.OnLimit != "" && rl.OnLimit != RateLimitWait && rl.OnLimit != RateLimitExit {
+		return fmt.Errorf("limits.rate_limit.on_limit must be %s or %s, got %q", RateLimitWait, RateLimitExit, rl.OnLimit)
+	}
+	if rl.DefaultWait < 0 || rl.MaxWait < 0 {
+		return fmt.Errorf("limits.rate_limit waits must be >= 0")
+	}
+	if rl.MaxPauses < 0 {
+		return fmt.Errorf("limits.rate_limit.max_pauses must be >= 0, got %d", rl.MaxPauses)
+	}
+
+	sb := cfg.Sandbox
+	if sb.Cgroup != "" && sb.Cgroup != SandboxAuto && sb.Cgroup != SandboxOff {
+		return fmt.Errorf("sandbox.cgroup must be %s or %s, got %q", SandboxAuto, SandboxOff, sb.Cgroup)
+	}
+	if p := sb.CgroupParent; p != "" && p != CgroupParentSelf && !filepath.IsAbs(p) {
+		return fmt.Errorf("sandbox.cgroup_parent must be an absolute path or %q, got %q", CgroupParentSelf, p)
+	}
+	if sb.Landlock != "" && sb.Landlock != SandboxAuto && sb.Landlock != SandboxOff {
+		return fmt.Errorf("sandbox.landlock must be %s or %s, got %q", SandboxAuto, SandboxOff, sb.Landlock)
+	}
+	if sb.MaxCPUs < 0 || sb.MaxPids < 0 {
+		return fmt.Errorf("sandbox.max_cpus and sandbox.max_pids must be >= 0")
+	}
+	if err := validateEnvPolicy(sb.Env); err != nil {
+		return err
+	}
+	for _, host := range sb.Egress.AllowedHosts {
+		if err := validateEgressHost(host); err != nil {
+			return err
+		}
+	}
+
+	to := cfg.Limits.TaskOverrides
+	if to.MaxBudgetUSD < 0 || to.MaxBudgetTokens < 0 || to.MaxTimeout < 0 {
+		return fmt.Errorf("limits.task_overrides ceilings must be >= 0")
+	}
+
+	if err := validateRouting(cfg.Models.Routing); err != nil {
+		return err
+	}
+
+	if err := validateQuorumRule("validation.quorum", cfg.Validation.Quorum.QuorumRule); err != nil {
+		return err
+	}
+	for key, rule := range cfg.Validation.Quorum.Overrides {
+		if err := validateQuorumRule(fmt.Sprintf("validation.quorum.overrides[%s]", key), rule); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Prompts.WorkerContext.MaxTokens < 0 {
+		return fmt.Errorf("prompts.worker_context.max_tokens must be >= 0, got %d", cfg.Prompts.WorkerContext.MaxTokens)
+	}
+
+	if err := validatePromptsDir(cfg); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validatePromptsDir checks that the templates directory exists and holds only
+// recognized template names, so a misspelled file doesn't silently fall back
+// to the built-in prompt. Template syntax is checked by the prompt renderer.
+func validatePromptsDir(cfg *Config) error {
+	if cfg.Prompts.TemplatesDir == "" {
+		return nil
+	}
+	dir := cfg.Project.ResolvePath(cfg.Prompts.TemplatesDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("prompts.templates_dir %q: %w", cfg.Prompts.TemplatesDir, err)
+	}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".tmpl" {
+			continue
+		}
+		if !promptTemplateName.MatchString(e.Name()) {
+			return fmt.Errorf("prompts.templates_dir: unrecognized template %q (want <planner|worker|validator|merger>.<system|task>.tmpl)", e.Name())
+		}
+	}
 	return nil
 }
 
@@ -295,3 +767,111 @@ func validateRoleBudget(role string, usd float64, tokens int) error {
 	}
 	return nil
 }
+
+func validateQuorumRule(field string, rule QuorumRule) error {
+	if rule.Validators < 0 || rule.Validators > 8 {
+		return fmt.Errorf("%s.validators must be 0-8, got %d", field, rule.Validators)
+	}
+	switch rule.Policy {
+	case "", QuorumUnanimous, QuorumMajority, QuorumAnyFailBlocks:
+	default:
+		return fmt.Errorf("%s.policy must be %s, %s, or %s, got %q",
+			field, QuorumUnanimous, QuorumMajority, QuorumAnyFailBlocks, rule.Policy)
+	}
+	return nil
+}
+
+func validateRouting(r RoutingConfig) error {
+	seen := make(map[string]bool, len(r.Bands))
+	for i, b := range r.Bands {
+		field := fmt.Sprintf("models.routing.bands[%d]", i)
+		if b.Name == "" {
+			return fmt.Errorf("%s.name is required", field)
+		}
+		if seen[b.Name] {
+			return fmt.Errorf("%s: duplicate band name %q", field, b.Name)
+		}
+		seen[b.Name] = true
+		if b.MaxFiles < 0 {
+			return fmt.Errorf("%s.max_files must be >= 0, got %d", field, b.MaxFiles)
+		}
+		if b.MaxRisk != "" && levelIndex(RiskLevels, b.MaxRisk) < 0 {
+			return fmt.Errorf("%s.max_risk must be one of %v, got %q", field, RiskLevels, b.MaxRisk)
+		}
+		if b.MaxSize != "" && levelIndex(SizeLevels, b.MaxSize) < 0 {
+			return fmt.Errorf("%s.max_size must be one of %v, got %q", field, SizeLevels, b.MaxSize)
+		}
+		if b.WorkerUSD > 0 && b.WorkerTokens > 0 {
+			return fmt.Errorf("%s: at most one of worker_usd or worker_tokens may be non-zero", field)
+		}
+		if b.ValidatorUSD > 0 && b.ValidatorTokens > 0 {
+			return fmt.Errorf("%s: at most one of validator_usd or validator_tokens may be non-zero", field)
+		}
+	}
+	return nil
+}
+
+// validateEgressHost checks a sandbox.egress.allowed_hosts entry: a host
+// name or "*.domain", optionally with ":port".
+func validateEgressHost(entry string) error {
+	host := entry
+	if h, port, err := net.SplitHostPort(entry); err == nil {
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("sandbox.egress.allowed_hosts: bad port in %q", entry)
+		}
+		host = h
+	}
+	host = strings.TrimPrefix(host, "*.")
+	if host == "" || strings.ContainsAny(host, "/*: ") {
+		return fmt.Errorf("sandbox.egress.allowed_hosts: %q is not a host name, \"*.domain\", or either with \":port\"", entry)
+	}
+	return nil
+}
+
+// validateEnvPolicy checks sandbox.env: patterns must be valid globs, and
+// nothing set explicitly may match a deny pattern, since those are always
+// stripped.
+func validateEnvPolicy(env EnvConfig) error {
+	for _, list := range [][]string{env.Allow, env.Deny} {
+		for _, pattern := range list {
+			if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+				return fmt.Errorf("sandbox.env: bad pattern %q", pattern)
+			}
+		}
+	}
+	for name := range env.Set {
+		if name == "" || strings.ContainsAny(name, "= ") {
+			return fmt.Errorf("sandbox.env.set: bad variable name %q", name)
+		}
+		for _, pattern := range env.Deny {
+			if ok, _ := path.Match(pattern, name); ok {
+				return fmt.Errorf("sandbox.env.set: %s matches deny pattern %q", name, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// validatePolicyRule checks one policy rule's effect, roles and patterns.
+func validatePolicyRule(r PolicyRule) error {
+	if !slices.Contains(PolicyEffects, r.Effect) {
+		return fmt.Errorf("effect %q must be one of %v", r.Effect, PolicyEffects)
+	}
+	for _, role := range r.Roles {
+		if !slices.Contains(PolicyRoles, role) {
+			return fmt.Errorf("unknown role %q (want one of %v)", role, PolicyRoles)
+		}
+	}
+	for _, g := range append(slices.Clone(r.Paths), r.Tasks...) {
+		if _, err := path.Match(g, ""); err != nil {
+			return fmt.Errorf("invalid glob %q: %w", g, err)
+		}
+	}
+	for _, c := range r.Commands {
+		if _, err := regexp.Compile(c); err != nil {
+			return fmt.Errorf("invalid command regex %q: %w", c, err)
+		}
+	}
+	return nil
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -66,6 +68,9 @@ func TestParseFullConfig(t *testing.T) {
 		t.Errorf("len(validator_diagnostics.commands) = %d, want 2",
 			len(cfg.Validation.ValidatorDiagnostics.Commands))
 	}
+	if r := cfg.Validation.Quorum.RuleFor("task-001", "auth"); r.Validators != 3 || r.Policy != QuorumMajority || len(r.Models) != 2 {
+		t.Errorf("quorum rule for auth = %+v, want 3 majority validators on 2 models", r)
+	}
 
 	// Budget checks
 	wb := cfg.Limits.TokenBudget.WorkerBudget()
@@ -270,3 +275,288 @@ func replaceString(s, old, new string) string {
 	}
 	return result
 }
+
+func TestValidatePromptsDir(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	promptsDir := filepath.Join(repoDir, "prompts")
+	if err := os.MkdirAll(promptsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(promptsDir, "worker.task.tmpl"), []byte("{{.Task.ID}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{
+		Project: ProjectConfig{Name: "test", Repo: repoDir},
+		Prompts: PromptsConfig{TemplatesDir: "prompts"},
+	}
+	applyDefaults(cfg)
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A misspelled template name must not silently fall back to the built-in.
+	if err := os.WriteFile(filepath.Join(promptsDir, "worker.sytem.tmpl"), []byte(""), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for unrecognized template name")
+	}
+
+	cfg.Prompts.TemplatesDir = "missing"
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for missing templates_dir")
+	}
+}
+
+func TestQuorumRuleFor(t *testing.T) {
+	q := QuorumConfig{
+		QuorumRule: QuorumRule{Validators: 1, Models: []string{"haiku"}, Policy: QuorumUnanimous},
+		Overrides: map[string]QuorumRule{
+			"auth":     {Validators: 3, Policy: QuorumMajority},
+			"task-009": {Models: []string{"sonnet", "opus"}},
+		},
+	}
+
+	if r := q.RuleFor("task-001", "auth"); r.Validators != 3 || r.Policy != QuorumMajority || r.Models[0] != "haiku" {
+		t.Errorf("group override = %+v", r)
+	}
+	if r := q.RuleFor("task-009", "auth"); r.Validators != 1 || len(r.Models) != 2 {
+		t.Errorf("task override should take precedence over group, got %+v", r)
+	}
+	if r := (&QuorumConfig{}).RuleFor("task-001", ""); r.Validators != 1 || r.Policy != QuorumUnanimous {
+		t.Errorf("zero config = %+v, want 1 unanimous validator", r)
+	}
+}
+
+func TestValidateRejectsBadQuorum(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	cfg := &Config{
+		Project: ProjectConfig{Name: "test", Repo: repoDir},
+	}
+	applyDefaults(cfg)
+	cfg.Validation.Quorum.Overrides = map[string]QuorumRule{"auth": {Policy: "most"}}
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for unknown quorum policy")
+	}
+
+	cfg.Validation.Quorum.Overrides = nil
+	cfg.Validation.Quorum.Validators = 9
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for validators = 9")
+	}
+}
+
+func TestRoutingRoute(t *testing.T) {
+	r := RoutingConfig{Bands: []RoutingBand{
+		{Name: "trivial", MaxFiles: 2, MaxRisk: "low", MaxSize: "small", WorkerModel: "haiku"},
+		{Name: "normal", MaxRisk: "medium", WorkerModel: "sonnet"},
+		{Name: "hard", WorkerModel: "opus"},
+	}}
+
+	tests := []struct {
+		files      int
+		risk, size string
+		want       string
+	}{
+		{1, "low", "small", "trivial"},
+		{3, "low", "small", "normal"},
+		{1, "medium", "large", "normal"},
+		{1, "high", "small", "hard"},
+		{0, "low", "small", "normal"}, // no file estimate can't fit max_files
+		{1, "", "small", "hard"},      // no risk estimate can't fit max_risk
+	}
+	for _, tt := range tests {
+		band := r.Route(tt.files, tt.risk, tt.size)
+		if band == nil || band.Name != tt.want {
+			t.Errorf("Route(%d, %q, %q) = %v, want %s", tt.files, tt.risk, tt.size, band, tt.want)
+		}
+	}
+	if b := r.Band("hard"); b == nil || b.WorkerModel != "opus" {
+		t.Errorf("Band(hard) = %v", b)
+	}
+	if b := (&RoutingConfig{}).Route(1, "low", "small"); b != nil {
+		t.Errorf("empty routing table should route nothing, got %v", b)
+	}
+}
+
+func TestValidateRejectsBadRouting(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	cfg := &Config{
+		Project: ProjectConfig{Name: "test", Repo: repoDir},
+	}
+	applyDefaults(cfg)
+
+	bad := []RoutingBand{
+		{MaxRisk: "low"},
+		{Name: "a", MaxRisk: "tiny"},
+		{Name: "a", MaxSize: "huge"},
+		{Name: "a", WorkerUSD: 1, WorkerTokens: 1000},
+	}
+	for _, b := range bad {
+		cfg.Models.Routing.Bands = []RoutingBand{b}
+		if err := Validate(cfg); err == nil {
+			t.Errorf("expected error for band %+v", b)
+		}
+	}
+	cfg.Models.Routing.Bands = []RoutingBand{{Name: "a"}, {Name: "a"}}
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for duplicate band names")
+	}
+}
+
+func TestValidateRejectsUnknownTokenClass(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	cfg := &Config{
+		Project: ProjectConfig{Name: "test", Repo: repoDir},
+	}
+	applyDefaults(cfg)
+	cfg.Limits.TokenBudget.CountTokens = []string{TokenInput, "cache"}
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for unknown token class")
+	}
+	cfg.Limits.TokenBudget.CountTokens = []string{TokenInput, TokenOutput}
+	if err := Validate(cfg); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+func TestValidateRejectsBadRateLimitPolicy(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	cfg := &Config{
+		Project: ProjectConfig{Name: "test", Repo: repoDir},
+	}
+	applyDefaults(cfg)
+	if cfg.Limits.RateLimit.OnLimit != RateLimitWait || cfg.Limits.RateLimit.DefaultWait != 15*time.Minute || cfg.Limits.RateLimit.MaxPauses != 5 {
+		t.Errorf("rate limit defaults = %+v", cfg.Limits.RateLimit)
+	}
+	cfg.Limits.RateLimit.OnLimit = "retry"
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for unknown on_limit")
+	}
+	cfg.Limits.RateLimit.OnLimit = RateLimitWait
+	cfg.Limits.RateLimit.MaxPauses = -1
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for negative max_pauses")
+	}
+	cfg.Limits.RateLimit.MaxPauses = 5
+	cfg.Limits.RateLimit.OnLimit = RateLimitExit
+	if err := Validate(cfg); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+func TestValidateCgroupParent(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	cfg := &Config{
+		Project: ProjectConfig{Name: "test", Repo: repoDir},
+	}
+	applyDefaults(cfg)
+	for _, parent := range []string{"", CgroupParentSelf, "/sys/fs/cgroup/blueflame"} {
+		cfg.Sandbox.CgroupParent = parent
+		if err := Validate(cfg); err != nil {
+			t.Errorf("cgroup_parent %q: %v", parent, err)
+		}
+	}
+	cfg.Sandbox.CgroupParent = "user.slice"
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for relative cgroup_parent")
+	}
+}
+
+func TestValidateEgressHosts(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	cfg := &Config{
+		Project: ProjectConfig{Name: "test", Repo: repoDir},
+	}
+	applyDefaults(cfg)
+	if !slices.Contains(cfg.Sandbox.Egress.AllowedHosts, "api.anthropic.com") {
+		t.Errorf("default egress hosts = %v", cfg.Sandbox.Egress.AllowedHosts)
+	}
+	cfg.Sandbox.Egress.AllowedHosts = []string{"api.anthropic.com", "*.npmjs.org", "pypi.org:443"}
+	if err := Validate(cfg); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+	for _, bad := range []string{"https://pypi.org", "*", "pypi.org:99999", ""} {
+		cfg.Sandbox.Egress.AllowedHosts = []string{bad}
+		if err := Validate(cfg); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestValidateEnvPolicy(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	cfg := &Config{
+		Project: ProjectConfig{Name: "test", Repo: repoDir},
+	}
+	applyDefaults(cfg)
+	if !slices.Contains(cfg.Sandbox.Env.Deny, "AWS_*") || !slices.Contains(cfg.Sandbox.Env.Allow, "ANTHROPIC_*") {
+		t.Errorf("default env policy = %+v", cfg.Sandbox.Env)
+	}
+	cfg.Sandbox.Env.Set = map[string]string{"GOCACHE": "$WORKTREE/.cache/go", "TMPDIR": "$AGENT_TMP"}
+	if err := Validate(cfg); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+
+	cfg.Sandbox.Env.Set = map[string]string{"AWS_PROFILE": "dev"}
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error setting a denied variable")
+	}
+	cfg.Sandbox.Env.Set = nil
+	cfg.Sandbox.Env.Allow = []string{"LC_["}
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for a malformed pattern")
+	}
+}
+
+func TestValidatePolicyRules(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	cfg := &Config{
+		Project: ProjectConfig{Name: "test", Repo: repoDir},
+	}
+	applyDefaults(cfg)
+	cfg.Policy.Rules = []PolicyRule{
+		{Name: "docs", Effect: "allow", Roles: []string{"worker"}, Tools: []string{"Write"}, Paths: []string{"docs/**"}},
+		{Effect: "ask", Tools: []string{"Bash"}, Commands: []string{`^git\s+push`}, Tasks: []string{"task-*"}},
+	}
+	if err := Validate(cfg); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+
+	for _, bad := range []PolicyRule{
+		{Effect: "maybe"},
+		{Effect: "deny", Roles: []string{"reviewer"}},
+		{Effect: "deny", Paths: []string{"src/["}},
+		{Effect: "deny", Commands: []string{"("}},
+	} {
+		cfg.Policy.Rules = []PolicyRule{bad}
+		if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "policy.rules[0]") {
+			t.Errorf("Validate(%+v) = %v", bad, err)
+		}
+	}
+
+	cfg.Policy.Rules = nil
+	if cfg.Policy.AskTimeout != 2*time.Minute {
+		t.Errorf("default ask_timeout = %s", cfg.Policy.AskTimeout)
+	}
+	cfg.Policy.AskTimeout = -time.Second
+	if err := Validate(cfg); err == nil {
+		t.Error("expected error for a negative ask_timeout")
+	}
+}
+
+func TestRepoContextCanBeTurnedOff(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	cfg := &Config{
+		Project: ProjectConfig{Name: "test", Repo: repoDir},
+	}
+	cfg.Planning.RepoContext.MaxTokens = -1
+	applyDefaults(cfg)
+	if cfg.Planning.RepoContext.MaxTokens != -1 {
+		t.Errorf("max_tokens = %d, defaults should keep -1", cfg.Planning.RepoContext.MaxTokens)
+	}
+	if err := Validate(cfg); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
@@ -30,6 +30,29 @@ func applyDefaults(cfg *Config) {
 		cfg.Concurrency.AdaptiveMinRAMPerAgentMB = 600
 	}
 
+	// Planning defaults
+	if cfg.Planning.MaxRepairAttempts == 0 {
+		cfg.Planning.MaxRepairAttempts = 2
+	}
+	if cfg.Planning.Lint.BroadLockRatio == 0 {
+		cfg.Planning.Lint.BroadLockRatio = 0.5
+	}
+	if cfg.Planning.RepoContext.MaxTokens == 0 {
+		cfg.Planning.RepoContext.MaxTokens = 8000
+	}
+	if cfg.Planning.RepoContext.GitLogEntries == 0 {
+		cfg.Planning.RepoContext.GitLogEntries = 20
+	}
+
+	// Prompt defaults
+	if cfg.Prompts.WorkerContext.MaxTokens == 0 {
+		cfg.Prompts.WorkerContext.MaxTokens = 6000
+	}
+
+	if cfg.Policy.AskTimeout == 0 {
+		cfg.Policy.AskTimeout = 2 * time.Minute
+	}
+
 	// Limits defaults
 	if cfg.Limits.AgentTimeout == 0 {
 		cfg.Limits.AgentTimeout = 300 * time.Second
@@ -46,6 +69,18 @@ func applyDefaults(cfg *Config) {
 	if cfg.Limits.TokenBudget.WarnThreshold == 0 {
 		cfg.Limits.TokenBudget.WarnThreshold = 0.8
 	}
+	if cfg.Limits.RateLimit.OnLimit == "" {
+		cfg.Limits.RateLimit.OnLimit = RateLimitWait
+	}
+	if cfg.Limits.RateLimit.DefaultWait == 0 {
+		cfg.Limits.RateLimit.DefaultWait = 15 * time.Minute
+	}
+	if cfg.Limits.RateLimit.MaxWait == 0 {
+		cfg.Limits.RateLimit.MaxWait = 6 * time.Hour
+	}
+	if cfg.Limits.RateLimit.MaxPauses == 0 {
+		cfg.Limits.RateLimit.MaxPauses = 5
+	}
 
 	// Sandbox defaults
 	if cfg.Sandbox.MaxCPUSeconds == 0 {
@@ -60,6 +95,47 @@ func applyDefaults(cfg *Config) {
 	if cfg.Sandbox.MaxOpenFiles == 0 {
 		cfg.Sandbox.MaxOpenFiles = 1024
 	}
+	if cfg.Sandbox.Cgroup == "" {
+		cfg.Sandbox.Cgroup = SandboxAuto
+	}
+	if cfg.Sandbox.MaxPids == 0 {
+		cfg.Sandbox.MaxPids = 4096
+	}
+	if cfg.Sandbox.Landlock == "" {
+		cfg.Sandbox.Landlock = SandboxAuto
+	}
+	if cfg.Sandbox.WritablePaths == nil {
+		// The claude CLI keeps its session state here.
+		cfg.Sandbox.WritablePaths = []string{"~/.claude", "~/.claude.json", "~/.cache"}
+	}
+	if cfg.Sandbox.Env.Allow == nil {
+		// What tools and the claude CLI need; credentials for anything else
+		// stay behind.
+		cfg.Sandbox.Env.Allow = []string{
+			"PATH", "HOME", "USER", "LOGNAME", "SHELL", "TERM", "LANG", "LC_*", "TZ", "TMPDIR",
+			"XDG_*", "ANTHROPIC_*", "CLAUDE_*",
+			"GOPATH", "GOROOT", "GOMODCACHE", "GOPROXY", "GOPRIVATE", "GOFLAGS",
+			"NODE_PATH", "NVM_DIR",
+			"SSL_CERT_FILE", "SSL_CERT_DIR",
+			"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy",
+		}
+	}
+	if cfg.Sandbox.Env.Deny == nil {
+		cfg.Sandbox.Env.Deny = []string{
+			"AWS_*", "AZURE_*", "GOOGLE_APPLICATION_CREDENTIALS",
+			"GITHUB_TOKEN", "GH_TOKEN", "GITLAB_TOKEN", "NPM_TOKEN",
+			"*_PASSWORD", "*_SECRET", "*_SECRET_*", "SSH_AUTH_SOCK",
+		}
+	}
+	if cfg.Sandbox.Egress.AllowedHosts == nil {
+		// The model API and the common package registries.
+		cfg.Sandbox.Egress.AllowedHosts = []string{
+			"api.anthropic.com",
+			"proxy.golang.org", "sum.golang.org",
+			"registry.npmjs.org",
+			"pypi.org", "files.pythonhosted.org",
+		}
+	}
 
 	// Model defaults
 	if cfg.Models.Planner == "" {
@@ -79,4 +155,10 @@ func applyDefaults(cfg *Config) {
 	if cfg.Validation.ValidatorDiagnostics.Timeout == 0 {
 		cfg.Validation.ValidatorDiagnostics.Timeout = 120 * time.Second
 	}
+	if cfg.Validation.Quorum.Validators == 0 {
+		cfg.Validation.Quorum.Validators = 1
+	}
+	if cfg.Validation.Quorum.Policy == "" {
+		cfg.Validation.Quorum.Policy = QuorumUnanimous
+	}
 }
@@ -0,0 +1,311 @@
+// Package egress is the orchestrator's allowlisting HTTP proxy. Agents
+// without network access reach the outside world only through it: each
+// agent gets its own unix socket, every connection is checked against the
+// host allowlist, and every decision is appended to the agent's audit log.
+package egress
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Decisions recorded in the audit log, matching the watcher hook's.
+const (
+	DecisionAllow = "allow"
+	DecisionBlock = "block"
+)
+
+// dialTimeout bounds connecting to an upstream host.
+const dialTimeout = 30 * time.Second
+
+// Event is one proxied connection as recorded in an agent's audit log. The
+// first fields match the watcher hook's entries.
+type Event struct {
+	Timestamp     string `json:"timestamp"`
+	AgentID       string `json:"agent_id"`
+	Tool          string `json:"tool"`
+	Target        string `json:"target"`
+	Decision      string `json:"decision"`
+	Rule          string `json:"rule"`
+	Details       string `json:"details"`
+	Method        string `json:"method"`
+	BytesSent     int64  `json:"bytes_sent"`
+	BytesReceived int64  `json:"bytes_received"`
+	DurationMS    int64  `json:"duration_ms"`
+}
+
+// Tool is the audit log's tool name for proxied connections.
+const Tool = "egress"
+
+// Proxy admits connections to allowlisted hosts. Entries are host names,
+// "*.domain" for any subdomain of domain, and either with ":port" to allow
+// only that port.
+type Proxy struct {
+	allowed []string
+	dir     string
+
+	// Dial connects to upstream hosts. Tests point it at local stand-ins.
+	Dial func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	mu        sync.Mutex
+	listeners map[net.Listener]bool
+	auditMu   sync.Mutex
+}
+
+// New returns a proxy allowing allowedHosts. Agent sockets are created in
+// a fresh temporary directory, since socket paths are limited to about 100
+// bytes.
+func New(allowedHosts []string) (*Proxy, error) {
+	dir, err := os.MkdirTemp("", "blueflame-egress-")
+	if err != nil {
+		return nil, fmt.Errorf("create egress socket dir: %w", err)
+	}
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	return &Proxy{
+		allowed:   allowedHosts,
+		dir:       dir,
+		Dial:      dialer.DialContext,
+		listeners: make(map[net.Listener]bool),
+	}, nil
+}
+
+// Allowed reports whether host:port may be reached and which allowlist
+// entry admits it.
+func (p *Proxy) Allowed(host, port string) (string, bool) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, entry := range p.allowed {
+		pattern, entryPort := entry, ""
+		if h, pt, err := net.SplitHostPort(entry); err == nil {
+			pattern, entryPort = h, pt
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+		pattern = strings.ToLower(pattern)
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return entry, true
+			}
+			continue
+		}
+		if host == pattern {
+			return entry, true
+		}
+	}
+	return "", false
+}
+
+// Listen starts serving agentID on its own unix socket and returns the
+// socket's path. Connections are logged to auditPath. Closing the returned
+// closer stops serving and removes the socket.
+func (p *Proxy) Listen(agentID, auditPath string) (string, io.Closer, error) {
+	path := filepath.Join(p.dir, agentID+".sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return "", nil, fmt.Errorf("listen for %s: %w", agentID, err)
+	}
+	p.mu.Lock()
+	p.listeners[l] = true
+	p.mu.Unlock()
+	go p.serve(l, agentID, auditPath)
+	return path, l, nil
+}
+
+// serve proxies connections accepted on l for agentID until l is closed.
+func (p *Proxy) serve(l net.Listener, agentID, auditPath string) {
+	defer func() {
+		p.mu.Lock()
+		delete(p.listeners, l)
+		p.mu.Unlock()
+	}()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go p.handle(conn, agentID, auditPath)
+	}
+}
+
+// Close stops serving every agent and removes the socket directory.
+func (p *Proxy) Close() error {
+	p.mu.Lock()
+	for l := range p.listeners {
+		l.Close()
+	}
+	p.mu.Unlock()
+	return os.RemoveAll(p.dir)
+}
+
+// handle serves one client connection: a CONNECT tunnel, or a single
+// plain-HTTP request in absolute form.
+func (p *Proxy) handle(conn net.Conn, agentID, auditPath string) {
+	defer conn.Close()
+	start := time.Now()
+	br := bufio.NewReader(conn)
+	req, err := http.ReadRequest(br)
+	if err != nil {
+		return
+	}
+
+	ev := Event{AgentID: agentID, Tool: Tool, Method: req.Method}
+	defer func() {
+		ev.DurationMS = time.Since(start).Milliseconds()
+		p.audit(auditPath, ev)
+	}()
+
+	host, port, target := requestTarget(req)
+	ev.Target = target
+	if host == "" {
+		ev.Decision, ev.Rule, ev.Details = DecisionBlock, "bad_request", "request has no target host"
+		writeStatus(conn, http.StatusBadRequest)
+		return
+	}
+	rule, ok := p.Allowed(host, port)
+	if !ok {
+		ev.Decision, ev.Rule, ev.Details = DecisionBlock, "host_not_allowed", "host is not in sandbox.egress.allowed_hosts"
+		writeStatus(conn, http.StatusForbidden)
+		return
+	}
+	ev.Decision, ev.Rule = DecisionAllow, rule
+
+	upstream, err := p.Dial(context.Background(), "tcp", target)
+	if err != nil {
+		ev.Details = fmt.Sprintf("dial: %v", err)
+		writeStatus(conn, http.StatusBadGateway)
+		return
+	}
+	defer upstream.Close()
+
+	if req.Method == http.MethodConnect {
+		if _, err := io.WriteString(conn, "HTTP/1.1 200 Connection Established\r\n\r\n"); err != nil {
+			return
+		}
+		// Read through br: the client may already have sent the start of
+		// its TLS handshake.
+		ev.BytesSent, ev.BytesReceived = tunnel(conn, br, upstream)
+		return
+	}
+
+	// Forward one request; Connection: close keeps a kept-alive client
+	// from reaching a second host unchecked. The Host header is replaced
+	// with the checked host so a client can't name an allowed host in the
+	// URL and another in the header.
+	req.RequestURI = ""
+	req.Host = req.URL.Host
+	req.Close = true
+	req.Header.Del("Proxy-Connection")
+	req.Header.Del("Proxy-Authorization")
+	counted := &countingWriter{w: upstream}
+	if err := req.Write(counted); err != nil {
+		ev.Details = fmt.Sprintf("forward request: %v", err)
+		writeStatus(conn, http.StatusBadGateway)
+		return
+	}
+	ev.BytesSent = counted.n
+	ev.BytesReceived, _ = io.Copy(conn, upstream)
+}
+
+// requestTarget returns the host, port, and host:port a proxy request is
+// for.
+func requestTarget(req *http.Request) (string, string, string) {
+	if req.Method == http.MethodConnect {
+		host, port, err := net.SplitHostPort(req.RequestURI)
+		if err != nil {
+			return "", "", req.RequestURI
+		}
+		return host, port, req.RequestURI
+	}
+	if req.URL == nil || req.URL.Host == "" {
+		return "", "", req.RequestURI
+	}
+	host, port := req.URL.Hostname(), req.URL.Port()
+	if port == "" {
+		port = "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return host, port, net.JoinHostPort(host, port)
+}
+
+// tunnel copies bytes both ways until either side closes, and returns how
+// many went upstream and how many came back. Client bytes are read from
+// clientReader, which wraps client.
+func tunnel(client net.Conn, clientReader io.Reader, upstream net.Conn) (int64, int64) {
+	var sent int64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sent, _ = io.Copy(upstream, clientReader)
+		closeWrite(upstream)
+	}()
+	received, _ := io.Copy(client, upstream)
+	closeWrite(client)
+	<-done
+	return sent, received
+}
+
+// closeWrite half-closes conn when it supports it, so the other side sees
+// EOF.
+func closeWrite(conn net.Conn) {
+	if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+		cw.CloseWrite()
+		return
+	}
+	conn.Close()
+}
+
+func writeStatus(w io.Writer, code int) {
+	fmt.Fprintf(w, "HTTP/1.1 %d %s\r\nContent-Length: 0\r\nConnection: close\r\n\r\n", code, http.StatusText(code))
+}
+
+// audit appends ev to the agent's audit log. Errors are dropped: a
+// connection isn't refused because its log line couldn't be written.
+func (p *Proxy) audit(path string, ev Event) {
+	if path == "" {
+		return
+	}
+	ev.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	if ev.Details == "" && ev.Decision == DecisionAllow {
+		ev.Details = strconv.FormatInt(ev.BytesSent, 10) + " bytes sent, " +
+			strconv.FormatInt(ev.BytesReceived, 10) + " received"
+	}
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	p.auditMu.Lock()
+	defer p.auditMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.Write(append(line, '\n'))
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,190 @@
+package egress
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAllowed(t *testing.T) {
+	p := &Proxy{allowed: []string{"api.anthropic.com", "*.npmjs.org", "pypi.org:443"}}
+	tests := []struct {
+		host, port string
+		want       bool
+	}{
+		{"api.anthropic.com", "443", true},
+		{"API.Anthropic.com.", "443", true},
+		{"evil-api.anthropic.com", "443", false},
+		{"registry.npmjs.org", "443", true},
+		{"npmjs.org", "443", false},
+		{"pypi.org", "443", true},
+		{"pypi.org", "80", false},
+		{"example.com", "443", false},
+	}
+	for _, tt := range tests {
+		if _, got := p.Allowed(tt.host, tt.port); got != tt.want {
+			t.Errorf("Allowed(%s, %s) = %v, want %v", tt.host, tt.port, got, tt.want)
+		}
+	}
+}
+
+// echoServer accepts one connection and echoes what it reads.
+func echoServer(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(conn, conn)
+	}()
+	return l.Addr().String()
+}
+
+// newTestProxy serves a proxy on a unix socket, resolving
+// api.example.test:443 to a local echo server.
+func newTestProxy(t *testing.T) (string, string) {
+	t.Helper()
+	p, err := New([]string{"api.example.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	upstream := echoServer(t)
+	p.Dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if addr != "api.example.test:443" {
+			return nil, errors.New("unknown stand-in " + addr)
+		}
+		return net.Dial(network, upstream)
+	}
+	audit := filepath.Join(t.TempDir(), "logs", "w-1.audit.jsonl")
+	socket, _, err := p.Listen("w-1", audit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return socket, audit
+}
+
+func connect(t *testing.T, socket, target string) (net.Conn, *bufio.Reader, int) {
+	t.Helper()
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(conn, "CONNECT "+target+" HTTP/1.1\r\nHost: "+target+"\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, br, resp.StatusCode
+}
+
+func readAudit(t *testing.T, path string) []Event {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []Event
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var ev Event
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("bad audit line %q: %v", line, err)
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+func TestConnectTunnelsToAllowedHost(t *testing.T) {
+	socket, audit := newTestProxy(t)
+
+	conn, br, status := connect(t, socket, "api.example.test:443")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d", status)
+	}
+	io.WriteString(conn, "ping")
+	conn.(*net.UnixConn).CloseWrite()
+	echoed, _ := io.ReadAll(br)
+	conn.Close()
+	if string(echoed) != "ping" {
+		t.Errorf("echoed %q", echoed)
+	}
+
+	events := readAudit(t, audit)
+	if len(events) != 1 {
+		t.Fatalf("events = %+v", events)
+	}
+	ev := events[0]
+	if ev.Decision != DecisionAllow || ev.Tool != Tool || ev.Target != "api.example.test:443" || ev.AgentID != "w-1" {
+		t.Errorf("event = %+v", ev)
+	}
+	if ev.BytesSent != 4 || ev.BytesReceived != 4 {
+		t.Errorf("bytes = %d sent, %d received", ev.BytesSent, ev.BytesReceived)
+	}
+}
+
+func TestConnectBlocksOtherHosts(t *testing.T) {
+	socket, audit := newTestProxy(t)
+
+	conn, _, status := connect(t, socket, "exfil.example.com:443")
+	conn.Close()
+	if status != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", status)
+	}
+	events := readAudit(t, audit)
+	if len(events) != 1 || events[0].Decision != DecisionBlock || events[0].Rule != "host_not_allowed" {
+		t.Errorf("events = %+v", events)
+	}
+}
+
+func TestPlainHTTPForwardsTheCheckedHost(t *testing.T) {
+	socket, _ := newTestProxy(t)
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, "GET http://api.example.test:443/v1 HTTP/1.1\r\nHost: blocked.example\r\n\r\n")
+	// The upstream echoes, so what comes back is the forwarded request.
+	forwarded, err := http.ReadRequest(bufio.NewReader(conn))
+	if err != nil {
+		t.Fatalf("read forwarded request: %v", err)
+	}
+	if forwarded.Host != "api.example.test:443" {
+		t.Errorf("forwarded Host = %q, want the allowed host", forwarded.Host)
+	}
+}
+
+func TestCloseRemovesSockets(t *testing.T) {
+	p, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	socket, _, err := p.Listen("w-1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(socket); !os.IsNotExist(err) {
+		t.Errorf("socket still exists: %v", err)
+	}
+}
@@ -0,0 +1,331 @@
+// Package estimate predicts what a plan will cost from the recorded costs
+// and outcomes of earlier sessions in the same repository.
+package estimate
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/kylegalloway/blueflame/internal/ledger"
+)
+
+// FileName is the estimator history's file name within .blueflame.
+const FileName = "estimates.json"
+
+const (
+	// MaxSamples is how many recent cost samples are kept per role and
+	// per role/model.
+	MaxSamples = 200
+	// MinSamples is how many samples a role/model needs before its own
+	// distribution is used instead of the role's.
+	MinSamples = 3
+
+	// z80 is the normal quantile for an 80% two-sided interval.
+	z80 = 1.2816
+)
+
+// Roles, matching the agent package's role names. Planning runs (planners
+// and the ensemble judge) are recorded once per session under keyPlanning.
+const (
+	rolePlanner   = "planner"
+	roleJudge     = "judge"
+	roleWorker    = "worker"
+	roleValidator = "validator"
+	roleMerger    = "merger"
+
+	keyPlanning = "planning"
+)
+
+// Without any history the estimator falls back to a fixed guess per task.
+const (
+	fallbackLow  = 0.50
+	fallbackHigh = 3.00
+)
+
+// History is what the estimator has learned, persisted between sessions.
+type History struct {
+	Sessions int `json:"sessions"`
+	// Costs holds per-task run costs keyed by role ("worker") and by role
+	// and model ("worker/sonnet"). A run shared by several tasks, like a
+	// merge, contributes its cost split evenly.
+	Costs map[string][]float64 `json:"costs"`
+	// Tasks is how many tasks ran; Attempts is how many worker attempts
+	// they took in total.
+	Tasks    int `json:"tasks"`
+	Attempts int `json:"attempts"`
+	// Validations is how many validation verdicts tasks received, and
+	// Passes how many of them passed.
+	Validations int `json:"validations"`
+	Passes      int `json:"validation_passes"`
+}
+
+// TaskOutcome is how one task fared in a session.
+type TaskOutcome struct {
+	Attempts    int
+	Validations int
+	Passes      int
+}
+
+// TaskInput describes a planned task: the worker model and one validator
+// model per validator in its quorum.
+type TaskInput struct {
+	WorkerModel     string
+	ValidatorModels []string
+}
+
+// Range is an estimated cost with an 80% confidence interval.
+type Range struct {
+	Mean float64
+	Low  float64
+	High float64
+}
+
+// String renders the range as "$1.20 ($0.60 - $1.90)".
+func (r Range) String() string {
+	return fmt.Sprintf("$%.2f ($%.2f - $%.2f)", r.Mean, r.Low, r.High)
+}
+
+// Contains reports whether cost falls within the interval.
+func (r Range) Contains(cost float64) bool {
+	return cost >= r.Low && cost <= r.High
+}
+
+// Plan is the estimate for a whole plan.
+type Plan struct {
+	Tasks []Range
+	Total Range
+	// Learned is false when there is no history yet and the estimate is
+	// the fixed per-task guess.
+	Learned bool
+}
+
+// Stats summarizes the history.
+type Stats struct {
+	Sessions   int
+	WorkerRuns int
+	RetryRate  float64 // extra attempts per task
+	PassRate   float64 // share of validations that passed
+}
+
+// Estimator estimates plan costs and learns from finished sessions. An
+// Estimator with no path only learns in memory.
+type Estimator struct {
+	path    string
+	history History
+}
+
+// New returns an estimator with no history.
+func New() *Estimator {
+	return &Estimator{history: History{Costs: make(map[string][]float64)}}
+}
+
+// Open returns an estimator backed by path, loading its history if the file
+// exists.
+func Open(path string) (*Estimator, error) {
+	e := New()
+	e.path = path
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return e, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read estimates: %w", err)
+	}
+	if err := json.Unmarshal(data, &e.history); err != nil {
+		return nil, fmt.Errorf("parse estimates: %w", err)
+	}
+	if e.history.Costs == nil {
+		e.history.Costs = make(map[string][]float64)
+	}
+	return e, nil
+}
+
+// Path returns the history file, or "" for an in-memory estimator.
+func (e *Estimator) Path() string {
+	return e.path
+}
+
+// Save writes the history atomically. An in-memory estimator does nothing.
+func (e *Estimator) Save() error {
+	if e.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(e.history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal estimates: %w", err)
+	}
+	dir := filepath.Dir(e.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create estimates dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, "estimates-*.json.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("write temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("close temp file: %w", err)
+	}
+	if err := os.Rename(tmpPath, e.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("rename temp file: %w", err)
+	}
+	return nil
+}
+
+// Record learns from a finished session's ledger entries and task outcomes.
+func (e *Estimator) Record(entries []ledger.Entry, outcomes []TaskOutcome) {
+	h := &e.history
+	h.Sessions++
+	var planning float64
+	for _, entry := range entries {
+		if entry.Role == rolePlanner || entry.Role == roleJudge {
+			planning += entry.CostUSD
+			continue
+		}
+		share := entry.CostUSD
+		if len(entry.Tasks) > 1 {
+			share /= float64(len(entry.Tasks))
+		}
+		e.addSample(entry.Role, share)
+		if entry.Model != "" {
+			e.addSample(entry.Role+"/"+entry.Model, share)
+		}
+	}
+	if planning > 0 {
+		e.addSample(keyPlanning, planning)
+	}
+	for _, o := range outcomes {
+		h.Tasks++
+		h.Attempts += o.Attempts
+		h.Validations += o.Validations
+		h.Passes += o.Passes
+	}
+}
+
+func (e *Estimator) addSample(key string, cost float64) {
+	samples := append(e.history.Costs[key], cost)
+	if len(samples) > MaxSamples {
+		samples = samples[len(samples)-MaxSamples:]
+	}
+	e.history.Costs[key] = samples
+}
+
+// Stats summarizes what the estimator has learned.
+func (e *Estimator) Stats() Stats {
+	h := e.history
+	s := Stats{Sessions: h.Sessions, WorkerRuns: len(h.Costs[roleWorker])}
+	if h.Tasks > 0 {
+		s.RetryRate = float64(h.Attempts-h.Tasks) / float64(h.Tasks)
+	}
+	if h.Validations > 0 {
+		s.PassRate = float64(h.Passes) / float64(h.Validations)
+	}
+	return s
+}
+
+// Task estimates one task: its expected worker attempts, each followed by
+// its validators, plus its share of a merge.
+func (e *Estimator) Task(in TaskInput) Range {
+	mean, variance, ok := e.task(in)
+	if !ok {
+		return Range{Mean: (fallbackLow + fallbackHigh) / 2, Low: fallbackLow, High: fallbackHigh}
+	}
+	return interval(mean, variance)
+}
+
+// Plan estimates every task and the plan as a whole, including planning.
+// Task variances add, so the plan's interval is relatively narrower than
+// any one task's.
+func (e *Estimator) Plan(inputs []TaskInput) Plan {
+	p := Plan{Tasks: make([]Range, len(inputs)), Learned: e.learned()}
+	var mean, variance float64
+	for i, in := range inputs {
+		p.Tasks[i] = e.Task(in)
+		if p.Learned {
+			m, v, _ := e.task(in)
+			mean += m
+			variance += v
+		}
+	}
+	if !p.Learned {
+		n := float64(len(inputs))
+		p.Total = Range{Mean: n * (fallbackLow + fallbackHigh) / 2, Low: n * fallbackLow, High: n * fallbackHigh}
+		return p
+	}
+	pm, pv := e.dist(keyPlanning, "")
+	p.Total = interval(mean+pm, variance+pv)
+	return p
+}
+
+// learned reports whether there is worker history to estimate from.
+func (e *Estimator) learned() bool {
+	return len(e.history.Costs[roleWorker]) > 0
+}
+
+// task returns a task's mean cost and variance, or false without worker
+// history.
+func (e *Estimator) task(in TaskInput) (float64, float64, bool) {
+	if !e.learned() {
+		return 0, 0, false
+	}
+	attempts := 1.0
+	if h := e.history; h.Tasks > 0 && h.Attempts > h.Tasks {
+		attempts = float64(h.Attempts) / float64(h.Tasks)
+	}
+	mean, variance := e.dist(roleWorker, in.WorkerModel)
+	for _, model := range in.ValidatorModels {
+		m, v := e.dist(roleValidator, model)
+		mean += m
+		variance += v
+	}
+	mean *= attempts
+	variance *= attempts
+	m, v := e.dist(roleMerger, "")
+	return mean + m, variance + v, true
+}
+
+// dist returns the mean and variance of a role's costs, using the
+// role/model samples when there are enough of them.
+func (e *Estimator) dist(role, model string) (float64, float64) {
+	samples := e.history.Costs[role]
+	if model != "" {
+		if s := e.history.Costs[role+"/"+model]; len(s) >= MinSamples {
+			samples = s
+		}
+	}
+	return meanVariance(samples)
+}
+
+func meanVariance(samples []float64) (float64, float64) {
+	if len(samples) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, s := range samples {
+		sum += s
+	}
+	mean := sum / float64(len(samples))
+	if len(samples) < 2 {
+		return mean, 0
+	}
+	var sq float64
+	for _, s := range samples {
+		sq += (s - mean) * (s - mean)
+	}
+	return mean, sq / float64(len(samples)-1)
+}
+
+func interval(mean, variance float64) Range {
+	spread := z80 * math.Sqrt(variance)
+	return Range{Mean: mean, Low: max(0, mean-spread), High: mean + spread}
+}
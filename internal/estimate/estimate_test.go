@@ -0,0 +1,85 @@
+package estimate
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/kylegalloway/blueflame/internal/ledger"
+)
+
+func TestPlanWithoutHistoryUsesFixedGuess(t *testing.T) {
+	p := New().Plan([]TaskInput{{}, {}})
+	if p.Learned {
+		t.Error("estimate without history should not be learned")
+	}
+	if p.Total.Low != 1.00 || p.Total.High != 6.00 {
+		t.Errorf("total = %+v, want $1.00 - $6.00", p.Total)
+	}
+}
+
+func TestRecordAndEstimate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	e, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Record([]ledger.Entry{
+		{Role: "planner", Model: "sonnet", CostUSD: 0.30},
+		{Role: "worker", Model: "sonnet", Tasks: []string{"a"}, CostUSD: 1.00},
+		{Role: "worker", Model: "sonnet", Tasks: []string{"a"}, CostUSD: 1.20},
+		{Role: "worker", Model: "sonnet", Tasks: []string{"b"}, CostUSD: 0.80},
+		{Role: "validator", Model: "haiku", Tasks: []string{"a"}, CostUSD: 0.10},
+		{Role: "validator", Model: "haiku", Tasks: []string{"b"}, CostUSD: 0.10},
+		{Role: "merger", Model: "sonnet", Tasks: []string{"a", "b"}, CostUSD: 0.40},
+	}, []TaskOutcome{
+		{Attempts: 2, Validations: 2, Passes: 1},
+		{Attempts: 1, Validations: 1, Passes: 1},
+	})
+	if err := e.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := e.Stats()
+	if stats.Sessions != 1 || stats.WorkerRuns != 3 || stats.RetryRate != 0.5 || math.Abs(stats.PassRate-2.0/3) > 1e-9 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	// 1.5 attempts x (worker 1.00 + validator 0.10) + merge share 0.20.
+	task := e.Task(TaskInput{WorkerModel: "sonnet", ValidatorModels: []string{"haiku"}})
+	if math.Abs(task.Mean-1.85) > 1e-9 {
+		t.Errorf("task mean = %.4f, want 1.85", task.Mean)
+	}
+	if !(task.Low < task.Mean && task.High > task.Mean) {
+		t.Errorf("task interval = %+v", task)
+	}
+
+	plan := e.Plan([]TaskInput{{WorkerModel: "sonnet"}, {WorkerModel: "sonnet"}})
+	if !plan.Learned || len(plan.Tasks) != 2 {
+		t.Fatalf("plan = %+v", plan)
+	}
+	// Two tasks without validators (1.5 + 0.2 each) plus planning.
+	if math.Abs(plan.Total.Mean-3.70) > 1e-9 {
+		t.Errorf("plan mean = %.4f, want 3.70", plan.Total.Mean)
+	}
+}
+
+func TestModelSamplesNeedMinimum(t *testing.T) {
+	e := New()
+	e.Record([]ledger.Entry{
+		{Role: "worker", Model: "sonnet", CostUSD: 1.00},
+		{Role: "worker", Model: "sonnet", CostUSD: 1.00},
+		{Role: "worker", Model: "sonnet", CostUSD: 1.00},
+		{Role: "worker", Model: "opus", CostUSD: 4.00},
+	}, nil)
+	if got := e.Task(TaskInput{WorkerModel: "sonnet"}).Mean; got != 1.00 {
+		t.Errorf("sonnet mean = %.2f, want its own 1.00", got)
+	}
+	if got := e.Task(TaskInput{WorkerModel: "opus"}).Mean; got != 1.75 {
+		t.Errorf("opus mean = %.2f, want the role-wide 1.75 with one sample", got)
+	}
+}
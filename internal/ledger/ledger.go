@@ -0,0 +1,226 @@
+// Package ledger records the cost of every agent run in a session.
+package ledger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FileName is the ledger's file name within a session directory.
+const FileName = "ledger.jsonl"
+
+// Entry is one agent run.
+type Entry struct {
+	Time                time.Time `json:"time"`
+	Session             string    `json:"session"`
+	Wave                int       `json:"wave"`
+	Tasks               []string  `json:"tasks,omitempty"`
+	Role                string    `json:"role"`
+	Model               string    `json:"model,omitempty"`
+	AgentID             string    `json:"agent_id"`
+	ExitCode            int       `json:"exit_code"`
+	CostUSD             float64   `json:"cost_usd"`
+	InputTokens         int       `json:"input_tokens"`
+	OutputTokens        int       `json:"output_tokens"`
+	CacheCreationTokens int       `json:"cache_creation_tokens"`
+	CacheReadTokens     int       `json:"cache_read_tokens"`
+	DurationMS          int64     `json:"duration_ms"`
+
+	// Resource usage of the run's processes, when it was measured.
+	CPUMS           int64 `json:"cpu_ms,omitempty"`
+	UserCPUMS       int64 `json:"user_cpu_ms,omitempty"`
+	SystemCPUMS     int64 `json:"system_cpu_ms,omitempty"`
+	PeakMemoryBytes int64 `json:"peak_memory_bytes,omitempty"`
+	BlockReadBytes  int64 `json:"block_read_bytes,omitempty"`
+	BlockWriteBytes int64 `json:"block_write_bytes,omitempty"`
+
+	// Models breaks the run down by the models it actually used, when the
+	// CLI reported that.
+	Models map[string]ModelUsage `json:"models,omitempty"`
+}
+
+// ModelUsage is one model's share of a run.
+type ModelUsage struct {
+	CostUSD             float64 `json:"cost_usd"`
+	InputTokens         int     `json:"input_tokens"`
+	OutputTokens        int     `json:"output_tokens"`
+	CacheCreationTokens int     `json:"cache_creation_tokens"`
+	CacheReadTokens     int     `json:"cache_read_tokens"`
+}
+
+// Tokens returns the run's tokens in every class.
+func (e Entry) Tokens() int {
+	return e.InputTokens + e.OutputTokens + e.CacheCreationTokens + e.CacheReadTokens
+}
+
+// Tokens returns the model's tokens in every class.
+func (m ModelUsage) Tokens() int {
+	return m.InputTokens + m.OutputTokens + m.CacheCreationTokens + m.CacheReadTokens
+}
+
+// Ledger appends entries to a JSON Lines file and keeps them in memory for
+// the session summary. A Ledger with no path only keeps them in memory.
+type Ledger struct {
+	path    string
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// New returns an in-memory ledger.
+func New() *Ledger {
+	return &Ledger{}
+}
+
+// Open returns a ledger backed by path, loading any entries already there
+// so a resumed session keeps its earlier runs.
+func Open(path string) (*Ledger, error) {
+	entries, err := Load(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return &Ledger{path: path, entries: entries}, nil
+}
+
+// Path returns the ledger file, or "" for an in-memory ledger.
+func (l *Ledger) Path() string {
+	return l.path
+}
+
+// Append records an entry and writes it to the ledger file.
+func (l *Ledger) Append(e Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, e)
+	if l.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal ledger entry: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return fmt.Errorf("create ledger dir: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open ledger: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("write ledger: %w", err)
+	}
+	return f.Close()
+}
+
+// Entries returns a copy of the recorded entries.
+func (l *Ledger) Entries() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Entry(nil), l.entries...)
+}
+
+// Load reads the entries in a ledger file.
+func Load(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("parse %s line %d: %w", path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// Line is one row of a cost breakdown.
+type Line struct {
+	Key     string
+	Runs    int
+	CostUSD float64
+	Tokens  int
+}
+
+// Unattributed is the breakdown key for runs without a task, like planning.
+const Unattributed = "(session)"
+
+// ByRole breaks costs down by agent role.
+func ByRole(entries []Entry) []Line {
+	return breakdown(entries, func(e Entry) []Line {
+		return []Line{{Key: e.Role, CostUSD: e.CostUSD, Tokens: e.Tokens()}}
+	})
+}
+
+// ByModel breaks costs down by model, using a run's per-model usage when
+// it has one and its requested model otherwise.
+func ByModel(entries []Entry) []Line {
+	return breakdown(entries, func(e Entry) []Line {
+		if len(e.Models) == 0 {
+			return []Line{{Key: e.Model, CostUSD: e.CostUSD, Tokens: e.Tokens()}}
+		}
+		var parts []Line
+		for model, mu := range e.Models {
+			parts = append(parts, Line{Key: model, CostUSD: mu.CostUSD, Tokens: mu.Tokens()})
+		}
+		sort.Slice(parts, func(i, j int) bool { return parts[i].Key < parts[j].Key })
+		return parts
+	})
+}
+
+// ByTask breaks costs down by task. A run for several tasks, like a merge,
+// is split evenly between them; runs for no task go under Unattributed.
+func ByTask(entries []Entry) []Line {
+	return breakdown(entries, func(e Entry) []Line {
+		if len(e.Tasks) == 0 {
+			return []Line{{Key: Unattributed, CostUSD: e.CostUSD, Tokens: e.Tokens()}}
+		}
+		share := 1 / float64(len(e.Tasks))
+		parts := make([]Line, len(e.Tasks))
+		for i, id := range e.Tasks {
+			parts[i] = Line{Key: id, CostUSD: e.CostUSD * share, Tokens: int(float64(e.Tokens()) * share)}
+		}
+		return parts
+	})
+}
+
+// breakdown totals the parts of each entry by key, counting one run per
+// part, and orders the lines most expensive first.
+func breakdown(entries []Entry, parts func(Entry) []Line) []Line {
+	index := make(map[string]int)
+	var lines []Line
+	for _, e := range entries {
+		for _, p := range parts(e) {
+			i, ok := index[p.Key]
+			if !ok {
+				i = len(lines)
+				index[p.Key] = i
+				lines = append(lines, Line{Key: p.Key})
+			}
+			lines[i].Runs++
+			lines[i].CostUSD += p.CostUSD
+			lines[i].Tokens += p.Tokens
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].CostUSD > lines[j].CostUSD })
+	return lines
+}
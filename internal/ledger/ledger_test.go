@@ -0,0 +1,94 @@
+package ledger
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLedgerAppendAndReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ses-1", FileName)
+	l, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := l.Append(Entry{Session: "ses-1", Role: "planner", CostUSD: 0.10}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := l.Append(Entry{Session: "ses-1", Role: "worker", Tasks: []string{"task-001"}, CostUSD: 0.50}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	// A resumed session picks up the earlier runs.
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	entries := reopened.Entries()
+	if len(entries) != 2 || entries[1].Tasks[0] != "task-001" {
+		t.Fatalf("entries = %+v", entries)
+	}
+}
+
+func TestInMemoryLedger(t *testing.T) {
+	l := New()
+	if err := l.Append(Entry{Role: "worker"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if l.Path() != "" || len(l.Entries()) != 1 {
+		t.Errorf("path = %q, entries = %d", l.Path(), len(l.Entries()))
+	}
+}
+
+func TestBreakdowns(t *testing.T) {
+	entries := []Entry{
+		{Role: "planner", Model: "opus", CostUSD: 0.30, InputTokens: 100},
+		{Role: "worker", Model: "sonnet", Tasks: []string{"task-001"}, CostUSD: 1.00, OutputTokens: 400},
+		{Role: "validator", Model: "haiku", Tasks: []string{"task-001"}, CostUSD: 0.10},
+		{Role: "merger", Model: "sonnet", Tasks: []string{"task-001", "task-002"}, CostUSD: 0.40, InputTokens: 200},
+	}
+
+	byTask := ByTask(entries)
+	want := map[string]float64{"task-001": 1.30, "(session)": 0.30, "task-002": 0.20}
+	if len(byTask) != len(want) {
+		t.Fatalf("ByTask = %+v", byTask)
+	}
+	for _, l := range byTask {
+		if diff := l.CostUSD - want[l.Key]; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("%s cost = %.2f, want %.2f", l.Key, l.CostUSD, want[l.Key])
+		}
+	}
+	if byTask[0].Key != "task-001" || byTask[0].Runs != 3 || byTask[0].Tokens != 500 {
+		t.Errorf("most expensive task = %+v, want task-001 with 3 runs and 500 tokens", byTask[0])
+	}
+
+	byModel := ByModel(entries)
+	if byModel[0].Key != "sonnet" || byModel[0].Runs != 2 {
+		t.Errorf("ByModel = %+v", byModel)
+	}
+	if got := len(ByRole(entries)); got != 4 {
+		t.Errorf("ByRole has %d lines, want 4", got)
+	}
+}
+
+func TestByModelUsesPerModelUsage(t *testing.T) {
+	entries := []Entry{
+		{Model: "sonnet", CostUSD: 0.50, InputTokens: 100, CacheReadTokens: 900, Models: map[string]ModelUsage{
+			"claude-sonnet": {CostUSD: 0.45, InputTokens: 80, CacheReadTokens: 900},
+			"claude-haiku":  {CostUSD: 0.05, InputTokens: 20},
+		}},
+		{Model: "claude-haiku", CostUSD: 0.10, InputTokens: 50},
+	}
+	lines := ByModel(entries)
+	if len(lines) != 2 {
+		t.Fatalf("ByModel = %+v", lines)
+	}
+	if lines[0].Key != "claude-sonnet" || lines[0].Tokens != 980 {
+		t.Errorf("first line = %+v, want claude-sonnet with 980 tokens", lines[0])
+	}
+	if lines[1].Key != "claude-haiku" || lines[1].Runs != 2 || lines[1].Tokens != 70 {
+		t.Errorf("second line = %+v, want claude-haiku over 2 runs", lines[1])
+	}
+	if got := entries[0].Tokens(); got != 1000 {
+		t.Errorf("entry tokens = %d, want all classes (1000)", got)
+	}
+}
@@ -0,0 +1,219 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/kylegalloway/blueflame/internal/agent"
+	"github.com/kylegalloway/blueflame/internal/config"
+	"github.com/kylegalloway/blueflame/internal/tasks"
+	"github.com/kylegalloway/blueflame/internal/ui"
+)
+
+// defaultPlanVariations steer ensemble planners toward different
+// decompositions when planning.ensemble.variations is empty. The first
+// planner gets no variation.
+var defaultPlanVariations = []string{
+	"",
+	"Maximize parallelism: prefer many small, independent tasks with no dependencies between them.",
+	"Minimize coordination: prefer fewer, cohesive tasks with strictly non-overlapping file_locks.",
+	"Follow the existing package structure: one task per package or module that needs changes.",
+}
+
+// ensemblePlan is one valid plan from a planner ensemble.
+type ensemblePlan struct {
+	label     string
+	output    *agent.PlannerOutput
+	raw       string
+	plan      []tasks.Task
+	depth     int
+	overlaps  int
+	judgeRank int
+	score     float64
+}
+
+// runEnsemble spawns concurrency.planning planners with different models
+// and prompt variations, scores their plans, optionally has a judge rank
+// them, and lets the human pick one or have a synthesizer merge them.
+func (o *Orchestrator) runEnsemble(ctx context.Context, data agent.PlannerPromptData) (*agent.PlannerOutput, error) {
+	n := o.config.Concurrency.Planning
+	ens := o.config.Planning.Ensemble
+	variations := ens.Variations
+	if len(variations) == 0 {
+		variations = defaultPlanVariations
+	}
+
+	type ensembleResult struct {
+		index   int
+		label   string
+		attempt planAttempt
+	}
+	resultCh := make(chan ensembleResult, n)
+	for i := 0; i < n; i++ {
+		cfg := o.ensembleConfig()
+		if len(ens.Models) > 0 {
+			cfg.Models.Planner = ens.Models[i%len(ens.Models)]
+		}
+		d := data
+		d.Variation = variations[i%len(variations)]
+		label := fmt.Sprintf("planner %d (%s)", i+1, cfg.Models.Planner)
+		go func(i int, label string, cfg *config.Config, d agent.PlannerPromptData) {
+			resultCh <- ensembleResult{index: i, label: label, attempt: o.planWithRepair(ctx, d, cfg)}
+		}(i, label, cfg, d)
+	}
+
+	results := make([]ensembleResult, n)
+	for i := 0; i < n; i++ {
+		r := <-resultCh
+		results[r.index] = r
+	}
+
+	var plans []*ensemblePlan
+	var firstErr error
+	var limited *agent.RateLimit
+	for _, r := range results {
+		o.recordPlanAttempt(r.label, r.attempt)
+		if r.attempt.rateLimit != nil && limited == nil {
+			limited = r.attempt.rateLimit
+		}
+		if r.attempt.err != nil {
+			o.ui.Warn(fmt.Sprintf("%s failed: %v", r.label, r.attempt.err))
+			if firstErr == nil {
+				firstErr = r.attempt.err
+			}
+			continue
+		}
+		plans = append(plans, &ensemblePlan{
+			label:  r.label,
+			output: r.attempt.output,
+			raw:    r.attempt.raw,
+			plan:   plannerTasks(r.attempt.output),
+		})
+	}
+	if len(plans) == 0 && limited != nil {
+		o.noteRateLimit("planners", limited)
+		return nil, errPlannerRateLimited
+	}
+	if len(plans) == 0 {
+		return nil, fmt.Errorf("all %d planners failed: %w", n, firstErr)
+	}
+	if len(plans) == 1 {
+		return plans[0].output, nil
+	}
+
+	for _, p := range plans {
+		p.score = scorePlan(p, o.scheduler.maxConcurrency)
+	}
+	if ens.Judge {
+		o.judgePlans(ctx, data.Description, plans)
+	}
+	sort.SliceStable(plans, func(i, j int) bool { return plans[i].score > plans[j].score })
+
+	candidates := make([]ui.PlanCandidate, len(plans))
+	for i, p := range plans {
+		candidates[i] = p.candidate()
+	}
+	choice := o.ui.PlanSelect(candidates)
+	if choice != ui.PlanSynthesize {
+		if choice < 0 || choice >= len(plans) {
+			choice = 0
+		}
+		return plans[choice].output, nil
+	}
+
+	o.ui.Info("Synthesizing plans...")
+	synth := data
+	for _, p := range plans {
+		synth.Candidates = append(synth.Candidates, p.raw)
+	}
+	attempt := o.planWithRepair(ctx, synth, o.ensembleConfig())
+	o.recordPlanAttempt("Synthesizer", attempt)
+	if attempt.err != nil {
+		o.ui.Warn(fmt.Sprintf("synthesizer failed, using the top-scored plan: %v", attempt.err))
+		return plans[0].output, nil
+	}
+	return attempt.output, nil
+}
+
+// ensembleConfig returns a copy of the config for ensemble agents. They run
+// non-interactively since several share one terminal.
+func (o *Orchestrator) ensembleConfig() *config.Config {
+	cfg := *o.config
+	cfg.Planning.Interactive = false
+	return &cfg
+}
+
+// scorePlan rates a plan from 0 to 1 on parallelism (tasks per dependency
+// layer, relative to the worker limit), lock overlap and task count (plans
+// with far more tasks than workers pay in per-agent overhead). It also
+// fills in depth and overlaps.
+func scorePlan(p *ensemblePlan, concurrency int) float64 {
+	concurrency = max(concurrency, 1)
+	for _, l := range dependencyLayers(p.plan) {
+		p.depth = max(p.depth, l+1)
+	}
+	p.overlaps = len(lintOverlappingLocks(p.plan))
+
+	parallelism := min(parallelismOf(p), float64(concurrency)) / float64(concurrency)
+	countScore := 1.0
+	if n := len(p.plan); n > 2*concurrency {
+		countScore = float64(2*concurrency) / float64(n)
+	}
+	return 0.5*parallelism + 0.3/float64(1+p.overlaps) + 0.2*countScore
+}
+
+func parallelismOf(p *ensemblePlan) float64 {
+	if p.depth == 0 {
+		return 0
+	}
+	return float64(len(p.plan)) / float64(p.depth)
+}
+
+// judgePlans asks a judge agent to rank the plans and adds a bonus to each
+// plan's score by rank. A failed judge leaves the scores unchanged.
+func (o *Orchestrator) judgePlans(ctx context.Context, description string, plans []*ensemblePlan) {
+	data := agent.JudgePromptData{Description: description}
+	for _, p := range plans {
+		data.Plans = append(data.Plans, p.raw)
+	}
+	judgeAgent, err := o.spawner.SpawnJudge(ctx, data, o.ensembleConfig())
+	if err != nil {
+		o.ui.Warn(fmt.Sprintf("spawn judge: %v", err))
+		return
+	}
+	result := agent.CollectResult(judgeAgent)
+	o.accumulateCost(result)
+	if result.ExitCode != 0 {
+		o.ui.Warn(fmt.Sprintf("judge failed with exit code %d", result.ExitCode))
+		return
+	}
+	out, err := agent.ParseJudgeOutput(result.RawStdout, len(plans))
+	if err != nil {
+		o.ui.Warn(err.Error())
+		return
+	}
+	for rank, n := range out.Ranking {
+		p := plans[n-1]
+		p.judgeRank = rank + 1
+		p.score += 0.5 * float64(len(plans)-rank) / float64(len(plans))
+	}
+	if out.Notes != "" {
+		o.ui.Info(fmt.Sprintf("Judge: %s", out.Notes))
+	}
+}
+
+func (p *ensemblePlan) candidate() ui.PlanCandidate {
+	c := ui.PlanCandidate{
+		Label:       p.label,
+		Depth:       p.depth,
+		Parallelism: parallelismOf(p),
+		Overlaps:    p.overlaps,
+		JudgeRank:   p.judgeRank,
+		Score:       p.score,
+	}
+	for _, t := range p.plan {
+		c.Tasks = append(c.Tasks, fmt.Sprintf("%s: %s", t.ID, t.Title))
+	}
+	return c
+}
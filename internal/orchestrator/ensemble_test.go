@@ -0,0 +1,128 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kylegalloway/blueflame/internal/agent"
+	"github.com/kylegalloway/blueflame/internal/tasks"
+	"github.com/kylegalloway/blueflame/internal/ui"
+)
+
+const (
+	serialPlan = `{"tasks":[
+		{"id":"task-001","title":"Serial A","description":"a","priority":1,"file_locks":["a/"]},
+		{"id":"task-002","title":"Serial B","description":"b","priority":2,"dependencies":["task-001"],"file_locks":["b/"]}
+	]}`
+	parallelPlan = `{"tasks":[
+		{"id":"task-001","title":"Parallel A","description":"a","priority":1,"file_locks":["a/"]},
+		{"id":"task-002","title":"Parallel B","description":"b","priority":1,"file_locks":["b/"]}
+	]}`
+	synthesizedPlan = `{"tasks":[
+		{"id":"task-001","title":"Synth","description":"s","priority":1,"file_locks":["a/"]}
+	]}`
+)
+
+// runEnsembleSession runs planning with an ensemble of two planners and
+// aborts at plan approval, returning the plan that was proposed.
+func runEnsembleSession(t *testing.T, spawner *agent.MockSpawner, prompter *ui.ScriptedPrompter, judge bool) []tasks.Task {
+	t.Helper()
+	cfg := testOrchestratorConfig(t)
+	cfg.Concurrency.Planning = 2
+	cfg.Planning.Ensemble.Judge = judge
+	prompter.PlanDecisions = []ui.PlanDecision{ui.PlanAbort}
+
+	taskStore := tasks.NewTaskStore(cfg.Project.TasksFile)
+	orch := New(cfg, spawner, prompter, taskStore, nil)
+	if err := orch.Run(context.Background(), "Build it"); err != ErrPlanRejected {
+		t.Fatalf("Run: %v, want ErrPlanRejected", err)
+	}
+	return taskStore.Tasks()
+}
+
+func TestEnsemblePicksBestScoredPlan(t *testing.T) {
+	spawner := &agent.MockSpawner{
+		PlannerResults: []agent.MockResult{{Output: serialPlan}, {Output: parallelPlan}},
+	}
+	plan := runEnsembleSession(t, spawner, &ui.ScriptedPrompter{}, false)
+
+	if len(plan) != 2 || plan[0].Title != "Parallel A" {
+		t.Errorf("expected the parallel plan to be chosen by default, got %+v", plan)
+	}
+
+	variations := map[string]bool{}
+	for _, call := range spawner.PlannerCalls {
+		variations[call.Variation] = true
+	}
+	if !variations[""] || !variations[defaultPlanVariations[1]] {
+		t.Errorf("planners should get distinct variations, got %v", variations)
+	}
+}
+
+func TestEnsembleHumanPicksPlan(t *testing.T) {
+	spawner := &agent.MockSpawner{
+		PlannerResults: []agent.MockResult{{Output: serialPlan}, {Output: parallelPlan}},
+	}
+	prompter := &ui.ScriptedPrompter{PlanSelections: []int{1}}
+	plan := runEnsembleSession(t, spawner, prompter, false)
+
+	if plan[0].Title != "Serial A" {
+		t.Errorf("expected the second-ranked (serial) plan, got %+v", plan)
+	}
+}
+
+func TestEnsembleJudgeRanking(t *testing.T) {
+	spawner := &agent.MockSpawner{
+		PlannerResults: []agent.MockResult{{Output: serialPlan}, {Output: serialPlan}},
+		JudgeResult:    &agent.MockResult{Output: `{"ranking":[2,1],"notes":"plan 2 is clearer"}`},
+	}
+	prompter := &ui.ScriptedPrompter{}
+	runEnsembleSession(t, spawner, prompter, true)
+
+	if len(spawner.JudgeCalls) != 1 || len(spawner.JudgeCalls[0].Plans) != 2 {
+		t.Fatalf("judge calls = %+v, want one call with 2 plans", spawner.JudgeCalls)
+	}
+	found := false
+	for _, m := range prompter.Messages {
+		if strings.Contains(m, "Judge: plan 2 is clearer") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("judge notes should be shown")
+	}
+}
+
+func TestEnsembleSynthesize(t *testing.T) {
+	spawner := &agent.MockSpawner{
+		PlannerResults: []agent.MockResult{{Output: serialPlan}, {Output: parallelPlan}, {Output: synthesizedPlan}},
+	}
+	prompter := &ui.ScriptedPrompter{PlanSelections: []int{ui.PlanSynthesize}}
+	plan := runEnsembleSession(t, spawner, prompter, false)
+
+	if len(spawner.PlannerCalls) != 3 {
+		t.Fatalf("planner calls = %d, want 3", len(spawner.PlannerCalls))
+	}
+	if got := len(spawner.PlannerCalls[2].Candidates); got != 2 {
+		t.Errorf("synthesizer got %d candidates, want 2", got)
+	}
+	if len(plan) != 1 || plan[0].Title != "Synth" {
+		t.Errorf("expected synthesized plan, got %+v", plan)
+	}
+}
+
+func TestEnsembleFallsBackWhenPlannersFail(t *testing.T) {
+	spawner := &agent.MockSpawner{
+		PlannerResults: []agent.MockResult{{Output: `not a plan`}, {Output: `not a plan`}},
+	}
+	cfg := testOrchestratorConfig(t)
+	cfg.Concurrency.Planning = 2
+
+	taskStore := tasks.NewTaskStore(cfg.Project.TasksFile)
+	orch := New(cfg, spawner, &ui.ScriptedPrompter{}, taskStore, nil)
+	err := orch.Run(context.Background(), "Build it")
+	if err == nil || !strings.Contains(err.Error(), "all 2 planners failed") {
+		t.Errorf("expected all-planners-failed error, got %v", err)
+	}
+}
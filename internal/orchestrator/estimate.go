@@ -0,0 +1,78 @@
+package orchestrator
+
+import (
+	"fmt"
+
+	"github.com/kylegalloway/blueflame/internal/agent"
+	"github.com/kylegalloway/blueflame/internal/config"
+	"github.com/kylegalloway/blueflame/internal/estimate"
+	"github.com/kylegalloway/blueflame/internal/tasks"
+)
+
+// estimateCost estimates a plan from past sessions' costs and outcomes.
+func (o *Orchestrator) estimateCost(plan []tasks.Task) estimate.Plan {
+	inputs := make([]estimate.TaskInput, len(plan))
+	for i := range plan {
+		inputs[i] = EstimateInput(&plan[i], o.config)
+	}
+	return o.estimator.Plan(inputs)
+}
+
+// EstimateInput describes the models a task will run with: its routed or
+// overridden worker, and one validator per quorum member.
+func EstimateInput(t *tasks.Task, cfg *config.Config) estimate.TaskInput {
+	settings, _ := agent.ResolveTaskSettings(t, cfg)
+	validator := agent.RoutedValidatorConfig(t, cfg).Models.Validator
+	rule := cfg.Validation.Quorum.RuleFor(t.ID, t.CohesionGroup)
+	in := estimate.TaskInput{WorkerModel: settings.Model}
+	for n := 0; n < rule.Validators; n++ {
+		model := validator
+		if len(rule.Models) > 0 {
+			model = rule.Models[n%len(rule.Models)]
+		}
+		in.ValidatorModels = append(in.ValidatorModels, model)
+	}
+	return in
+}
+
+// formatEstimate renders a plan estimate for the plan display.
+func formatEstimate(p estimate.Plan) string {
+	if !p.Learned {
+		return fmt.Sprintf("$%.2f - $%.2f (no history yet)", p.Total.Low, p.Total.High)
+	}
+	return p.Total.String() + ", 80% interval"
+}
+
+// recordEstimates teaches the estimator this session's costs and task
+// outcomes and saves its history. Worker attempts are counted from the
+// ledger; validation verdicts from each task's history.
+func (o *Orchestrator) recordEstimates() {
+	entries := o.ledger.Entries()
+	attempts := make(map[string]int)
+	for _, e := range entries {
+		if e.Role == agent.RoleWorker && len(e.Tasks) == 1 {
+			attempts[e.Tasks[0]]++
+		}
+	}
+	var outcomes []estimate.TaskOutcome
+	for _, t := range o.taskStore.Tasks() {
+		if attempts[t.ID] == 0 {
+			continue
+		}
+		outcome := estimate.TaskOutcome{Attempts: attempts[t.ID]}
+		for _, h := range t.History {
+			if h.Result == "validation_failed" {
+				outcome.Validations++
+			}
+		}
+		if t.Result.Status == "pass" {
+			outcome.Validations++
+			outcome.Passes++
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	o.estimator.Record(entries, outcomes)
+	if err := o.estimator.Save(); err != nil {
+		o.ui.Warn(fmt.Sprintf("save cost estimates: %v", err))
+	}
+}
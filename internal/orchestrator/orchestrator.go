@@ -7,14 +7,19 @@ import (
 	"errors"
 	"fmt"
 	"os/exec"
+	"path/filepath"
 	"strings"
-	"text/template"
 	"time"
 
 	"github.com/kylegalloway/blueflame/internal/agent"
+	"github.com/kylegalloway/blueflame/internal/approval"
+	"github.com/kylegalloway/blueflame/internal/audit"
 	"github.com/kylegalloway/blueflame/internal/config"
+	"github.com/kylegalloway/blueflame/internal/estimate"
+	"github.com/kylegalloway/blueflame/internal/ledger"
 	"github.com/kylegalloway/blueflame/internal/locks"
 	"github.com/kylegalloway/blueflame/internal/memory"
+	"github.com/kylegalloway/blueflame/internal/repocontext"
 	"github.com/kylegalloway/blueflame/internal/state"
 	"github.com/kylegalloway/blueflame/internal/tasks"
 	"github.com/kylegalloway/blueflame/internal/ui"
@@ -25,6 +30,7 @@ var (
 	ErrPlanRejected    = errors.New("plan rejected by user")
 	ErrBudgetExceeded  = errors.New("session budget exceeded")
 	ErrMaxWaveCycles   = errors.New("max wave cycles reached")
+	ErrRateLimited     = errors.New("paused for rate limit")
 )
 
 // Orchestrator manages the wave-based execution cycle.
@@ -39,13 +45,26 @@ type Orchestrator struct {
 	lifecycle *agent.LifecycleManager
 	worktrees *worktree.Manager
 	locks     *locks.Manager
-	memory      memory.Provider
-	hooksDir    string
-	watcherTmpl *template.Template
+	memory         memory.Provider
+	hooksDir       string
+	hookExecutable string
+
+	// approvals answers watcher hooks' "ask" decisions while Run runs.
+	approvals *approval.Server
 
 	sessionCost   float64
 	sessionTokens int
 
+	// ledger records every agent run; sessionsDir holds per-session
+	// directories where it is written.
+	ledger      *ledger.Ledger
+	sessionsDir string
+
+	// estimator predicts plan costs from past sessions; planEstimate is
+	// the approved plan's estimate, compared with actuals in the summary.
+	estimator    *estimate.Estimator
+	planEstimate *estimate.Range
+
 	// agentLocks tracks which lock paths each agent holds, for per-agent release.
 	agentLocks map[string][]string
 
@@ -60,6 +79,19 @@ type Orchestrator struct {
 
 	// configHash stores the hash of the initial config for drift detection.
 	configHash string
+
+	// rateLimit is set when an agent hits a rate or usage limit, and the
+	// session pauses before spawning more agents. sleep waits out the
+	// pause; tests replace it.
+	rateLimit *agent.RateLimit
+	sleep     func(ctx context.Context, d time.Duration) error
+	// rateLimitPauses counts pauses since a wave last got through
+	// validation without hitting a limit.
+	rateLimitPauses int
+
+	// hostResources reports available RAM in MB and CPUs for concurrency
+	// recommendations; nil uses agent.HostResources. Tests replace it.
+	hostResources func() (int, int)
 }
 
 // New creates a new Orchestrator.
@@ -73,6 +105,9 @@ func New(cfg *config.Config, spawner agent.AgentSpawner, prompter ui.Prompter, t
 		ui:         prompter,
 		stateMgr:   stateMgr,
 		agentLocks: make(map[string][]string),
+		ledger:     ledger.New(),
+		estimator:  estimate.New(),
+		sleep:      sleepContext,
 		state: &state.OrchestratorState{
 			SessionID: fmt.Sprintf("ses-%s", time.Now().Format("20060102-150405")),
 			StartTime: time.Now(),
@@ -90,10 +125,30 @@ func (o *Orchestrator) SetLockManager(lm *locks.Manager) {
 	o.locks = lm
 }
 
-// SetHooksDir sets the base directory for generated hook scripts and the watcher template.
-func (o *Orchestrator) SetHooksDir(dir string, tmpl *template.Template) {
+// SetHooksDir sets the base directory for watcher policies and audit logs,
+// and the blueflame executable whose hook subcommand agents run.
+func (o *Orchestrator) SetHooksDir(dir, executable string) {
 	o.hooksDir = dir
-	o.watcherTmpl = tmpl
+	o.hookExecutable = executable
+}
+
+// askApproval relays a watcher hook's approval request to the human.
+func (o *Orchestrator) askApproval(ctx context.Context, req approval.Request) string {
+	switch o.ui.ToolApproval(ctx, ui.ToolApprovalRequest{
+		AgentID: req.AgentID,
+		TaskID:  req.TaskID,
+		Tool:    req.Tool,
+		Target:  req.Target,
+		Rule:    req.Rule,
+		Reason:  req.Reason,
+	}) {
+	case ui.ToolApproveOnce:
+		return approval.ApproveOnce
+	case ui.ToolApproveSession:
+		return approval.ApproveSession
+	default:
+		return approval.Deny
+	}
 }
 
 // SetMemoryProvider sets the memory provider for cross-session context.
@@ -106,6 +161,19 @@ func (o *Orchestrator) SetLifecycleManager(lm *agent.LifecycleManager) {
 	o.lifecycle = lm
 }
 
+// SetSessionsDir sets the directory for per-session files such as the cost
+// ledger. Each session writes to a subdirectory named by its session ID.
+func (o *Orchestrator) SetSessionsDir(dir string) {
+	o.sessionsDir = dir
+}
+
+// SetEstimator sets the cost estimator, which learns from each finished
+// session. Without one, estimates use an in-memory estimator with no
+// history.
+func (o *Orchestrator) SetEstimator(e *estimate.Estimator) {
+	o.estimator = e
+}
+
 // SetRecoveryState sets crash recovery state. When set, Run() will skip
 // planning and resume at the recovered wave cycle.
 func (o *Orchestrator) SetRecoveryState(rs *state.OrchestratorState) {
@@ -124,6 +192,20 @@ func (o *Orchestrator) Run(ctx context.Context, taskDescription string) error {
 		go o.lifecycle.MonitorLoop(monitorCtx)
 	}
 
+	// Relay tool calls the policy asks about to the human.
+	if o.hooksDir != "" {
+		srv, err := approval.New(filepath.Join(o.hooksDir, "approvals"), o.askApproval, o.config.Policy.AskTimeout)
+		if err != nil {
+			o.ui.Warn(fmt.Sprintf("tool approvals unavailable, ask rules will deny: %v", err))
+		} else {
+			o.approvals = srv
+			defer func() {
+				srv.Close()
+				o.approvals = nil
+			}()
+		}
+	}
+
 	startCycle := 1
 
 	if o.recoveryState != nil {
@@ -135,6 +217,12 @@ func (o *Orchestrator) Run(ctx context.Context, taskDescription string) error {
 		o.state.SessionCost = o.recoveryState.SessionCost
 		o.state.SessionTokens = o.recoveryState.SessionTokens
 		startCycle = o.recoveryState.WaveCycle
+		if until := o.recoveryState.RateLimitedUntil; until != nil && until.After(time.Now()) {
+			o.rateLimit = &agent.RateLimit{Message: "usage limit from the previous run", ResetAt: *until}
+		}
+		if err := o.openLedger(); err != nil {
+			return err
+		}
 
 		// Load tasks from disk (already persisted from previous session)
 		if err := o.taskStore.Load(); err != nil {
@@ -168,6 +256,9 @@ func (o *Orchestrator) Run(ctx context.Context, taskDescription string) error {
 			pending, done, failed, merged))
 	} else {
 		// Normal path: run planning
+		if err := o.openLedger(); err != nil {
+			return err
+		}
 		o.state.Phase = "planning"
 		o.persistState()
 
@@ -192,9 +283,16 @@ func (o *Orchestrator) Run(ctx context.Context, taskDescription string) error {
 			}
 
 			plan, err := o.runPlanning(ctx, taskDescription, priorContext)
+			if errors.Is(err, errPlannerRateLimited) {
+				if err := o.pauseForRateLimit(ctx); err != nil {
+					return err
+				}
+				continue
+			}
 			if err != nil {
 				return fmt.Errorf("planning: %w", err)
 			}
+			o.rateLimitPauses = 0
 
 			// Store planned tasks
 			o.taskStore.SetFile(&tasks.TaskFile{
@@ -208,7 +306,8 @@ func (o *Orchestrator) Run(ctx context.Context, taskDescription string) error {
 			}
 
 			// Display the plan
-			o.ui.Info(fmt.Sprintf("\nPlanned %d task(s), estimated cost: %s\n", len(plan), o.estimateCost(len(plan))))
+			est := o.estimateCost(plan)
+			o.ui.Info(fmt.Sprintf("\nPlanned %d task(s), estimated cost: %s\n", len(plan), formatEstimate(est)))
 			for i, t := range plan {
 				deps := "none"
 				if len(t.Dependencies) > 0 {
@@ -221,13 +320,31 @@ func (o *Orchestrator) Run(ctx context.Context, taskDescription string) error {
 				o.ui.Info(fmt.Sprintf("  %d. [%s] %s (priority %d)", i+1, t.ID, t.Title, t.Priority))
 				o.ui.Info(fmt.Sprintf("     %s", t.Description))
 				o.ui.Info(fmt.Sprintf("     deps: %s | locks: %s", deps, locks))
+				if t.Complexity != nil {
+					o.ui.Info(fmt.Sprintf("     complexity: %s -> %s", t.Complexity, o.formatRoute(&t)))
+				}
+				if t.HasOverrides() {
+					o.ui.Info(fmt.Sprintf("     overrides: %s", formatOverrides(&t)))
+				}
+				if est.Learned {
+					o.ui.Info(fmt.Sprintf("     estimate: %s", est.Tasks[i]))
+				}
+			}
+			o.checkTaskOverrides(plan)
+
+			findings := o.lintPlan(plan)
+			if len(findings) > 0 {
+				o.ui.Info(fmt.Sprintf("\nPlan lint: %d finding(s)", len(findings)))
+				for _, f := range findings {
+					o.ui.Warn(f.String())
+				}
 			}
 
 			// Present plan for approval
-			decision, feedback := o.ui.PlanApproval(len(plan), o.estimateCost(len(plan)))
+			decision, feedback := o.ui.PlanApproval(len(plan), formatEstimate(est))
 			switch decision {
 			case ui.PlanApprove:
-				// Continue to development
+				o.planEstimate = &est.Total
 			case ui.PlanAbort:
 				return ErrPlanRejected
 			case ui.PlanReplan:
@@ -236,12 +353,21 @@ func (o *Orchestrator) Run(ctx context.Context, taskDescription string) error {
 				if feedback != "" {
 					priorContext += fmt.Sprintf("\n\nUser feedback on previous plan: %s", feedback)
 				}
+				if o.config.Planning.Lint.AutoFix && len(findings) > 0 {
+					priorContext += "\n\n" + FormatLintFindings(findings)
+				}
 				continue
 			case ui.PlanEdit:
 				// Human edits tasks.yaml, then reload
 				if err := o.taskStore.Load(); err != nil {
 					return fmt.Errorf("reload tasks after edit: %w", err)
 				}
+				if o.routeTasks(o.taskStore.Tasks()) {
+					if err := o.taskStore.Save(); err != nil {
+						return fmt.Errorf("save tasks: %w", err)
+					}
+				}
+				o.checkTaskOverrides(o.taskStore.Tasks())
 			}
 			break
 		}
@@ -267,6 +393,12 @@ func (o *Orchestrator) Run(ctx context.Context, taskDescription string) error {
 			break
 		}
 
+		if o.rateLimit != nil {
+			if err := o.pauseForRateLimit(ctx); err != nil {
+				return err
+			}
+		}
+
 		// Wave 2: Development
 		o.state.Phase = "development"
 		o.persistState()
@@ -275,6 +407,10 @@ func (o *Orchestrator) Run(ctx context.Context, taskDescription string) error {
 		if err := o.taskStore.Save(); err != nil {
 			o.ui.Warn(fmt.Sprintf("save tasks after development: %v", err))
 		}
+		if o.rateLimit != nil {
+			cycle-- // pause, then redo this cycle
+			continue
+		}
 
 		// Wave 3: Validation
 		o.runHook("pre_validation", o.config.Hooks.PreValidation)
@@ -285,6 +421,11 @@ func (o *Orchestrator) Run(ctx context.Context, taskDescription string) error {
 		if err := o.taskStore.Save(); err != nil {
 			o.ui.Warn(fmt.Sprintf("save tasks after validation: %v", err))
 		}
+		if o.rateLimit != nil {
+			cycle--
+			continue
+		}
+		o.rateLimitPauses = 0
 
 		// Wave 4: Merge
 		o.state.Phase = "merge"
@@ -347,12 +488,14 @@ func (o *Orchestrator) Run(ctx context.Context, taskDescription string) error {
 			}
 			continue
 		case ui.SessionStop:
+			o.recordEstimates()
 			return nil
 		}
 	}
 
 	// Clean up session resources
 	o.cleanupSession()
+	o.recordEstimates()
 
 	// Save session to memory provider
 	if o.memory != nil {
@@ -399,27 +542,218 @@ func (o *Orchestrator) saveSessionMemory() {
 	}
 }
 
-func (o *Orchestrator) runPlanning(ctx context.Context, description string, priorContext string) ([]tasks.Task, error) {
-	plannerAgent, err := o.spawner.SpawnPlanner(ctx, description, priorContext, o.config)
+// lintPlan runs the plan linter with the session's repo and limits.
+func (o *Orchestrator) lintPlan(plan []tasks.Task) []LintFinding {
+	files, err := repocontext.ListFiles(o.config.Project.Repo)
 	if err != nil {
-		return nil, fmt.Errorf("spawn planner: %w", err)
+		o.ui.Warn(fmt.Sprintf("plan lint: list repo files: %v", err))
+	}
+	return LintPlan(plan, PlanLintOptions{
+		RepoDir:        o.config.Project.Repo,
+		Files:          files,
+		Concurrency:    o.scheduler.maxConcurrency,
+		MaxWaveCycles:  o.config.Limits.MaxWaveCycles,
+		BroadLockRatio: o.config.Planning.Lint.BroadLockRatio,
+	})
+}
+
+// checkTaskOverrides warns about per-task overrides that exceed
+// limits.task_overrides and will be clamped or dropped at spawn.
+func (o *Orchestrator) checkTaskOverrides(plan []tasks.Task) {
+	for i := range plan {
+		_, notes := agent.ResolveTaskSettings(&plan[i], o.config)
+		for _, n := range notes {
+			o.ui.Warn(fmt.Sprintf("%s: %s", plan[i].ID, n))
+		}
 	}
+}
 
-	result := agent.CollectResult(plannerAgent)
-	o.accumulateCost(result)
+// routeTasks picks a models.routing band for each estimated task that has
+// no route yet and reports whether any task changed.
+func (o *Orchestrator) routeTasks(plan []tasks.Task) bool {
+	changed := false
+	for i := range plan {
+		t := &plan[i]
+		if t.Route != "" || t.Complexity == nil {
+			continue
+		}
+		if band := o.config.Models.Routing.Route(t.Complexity.Files, t.Complexity.Risk, t.Complexity.Size); band != nil {
+			t.Route = band.Name
+			changed = true
+		}
+	}
+	return changed
+}
 
-	if result.ExitCode != 0 {
-		return nil, fmt.Errorf("planner failed with exit code %d", result.ExitCode)
+// formatRoute describes the models a task's route selected.
+func (o *Orchestrator) formatRoute(t *tasks.Task) string {
+	if t.Route == "" {
+		return "default models"
 	}
+	settings, _ := agent.ResolveTaskSettings(&tasks.Task{Route: t.Route}, o.config)
+	validator := agent.RoutedValidatorConfig(t, o.config)
+	return fmt.Sprintf("route %s (worker %s, validator %s)", t.Route, settings.Model, validator.Models.Validator)
+}
 
-	planOutput, err := agent.ParsePlannerOutput(result.RawStdout)
+// formatOverrides renders a task's overrides for the plan display.
+func formatOverrides(t *tasks.Task) string {
+	var parts []string
+	if t.Model != "" {
+		parts = append(parts, "model="+t.Model)
+	}
+	if t.BudgetUSD > 0 {
+		parts = append(parts, fmt.Sprintf("budget=$%.2f", t.BudgetUSD))
+	}
+	if t.BudgetTokens > 0 {
+		parts = append(parts, fmt.Sprintf("budget=%d tokens", t.BudgetTokens))
+	}
+	if t.Timeout > 0 {
+		parts = append(parts, fmt.Sprintf("timeout=%v", t.Timeout))
+	}
+	if len(t.ExtraCommands) > 0 {
+		parts = append(parts, "commands+="+strings.Join(t.ExtraCommands, ","))
+	}
+	if len(t.ExtraTools) > 0 {
+		parts = append(parts, "tools+="+strings.Join(t.ExtraTools, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+// buildRepoContext summarizes the repository for the planner. It is rebuilt
+// on every planning pass so re-plans see merged work. Failures only warn:
+// planning can proceed without it. A negative max_tokens turns it off.
+func (o *Orchestrator) buildRepoContext() string {
+	rc := o.config.Planning.RepoContext
+	if rc.MaxTokens < 0 {
+		return ""
+	}
+	pack, err := repocontext.Build(o.config.Project.Repo, repocontext.Options{
+		MaxTokens:     rc.MaxTokens,
+		GitLogEntries: rc.GitLogEntries,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("parse planner output: %w", err)
+		o.ui.Warn(fmt.Sprintf("build repo context: %v", err))
+		return ""
+	}
+	return pack.String()
+}
+
+func (o *Orchestrator) runPlanning(ctx context.Context, description string, priorContext string) ([]tasks.Task, error) {
+	data := agent.PlannerPromptData{
+		Description:  description,
+		PriorContext: priorContext,
+		RepoContext:  o.buildRepoContext(),
 	}
 
-	// Convert planner tasks to task store tasks
+	var planOutput *agent.PlannerOutput
+	if o.config.Concurrency.Planning > 1 {
+		out, err := o.runEnsemble(ctx, data)
+		if err != nil {
+			return nil, err
+		}
+		planOutput = out
+	} else {
+		attempt := o.planWithRepair(ctx, data, o.config)
+		o.recordPlanAttempt("Planner", attempt)
+		if attempt.rateLimit != nil {
+			o.noteRateLimit("planner", attempt.rateLimit)
+			return nil, errPlannerRateLimited
+		}
+		if attempt.err != nil {
+			return nil, attempt.err
+		}
+		planOutput = attempt.output
+	}
+
+	plan := plannerTasks(planOutput)
+	o.routeTasks(plan)
+	return plan, nil
+}
+
+// planAttempt is the outcome of one planner run including its repairs.
+type planAttempt struct {
+	output  *agent.PlannerOutput
+	raw     string // the accepted plan as the planner wrote it
+	results []agent.AgentResult
+	repairs int
+	err     error
+	// rateLimit is set when the planner hit a rate or usage limit.
+	rateLimit *agent.RateLimit
+}
+
+// planWithRepair runs a planner and, while its output fails schema
+// validation, re-invokes it with the problems and its previous output, up
+// to MaxRepairAttempts times, rather than discarding the planning spend.
+// It doesn't touch orchestrator state, so ensemble planners can run it
+// concurrently.
+func (o *Orchestrator) planWithRepair(ctx context.Context, data agent.PlannerPromptData, cfg *config.Config) planAttempt {
+	var pa planAttempt
+	for attempt := 0; ; attempt++ {
+		plannerAgent, err := o.spawner.SpawnPlanner(ctx, data, cfg)
+		if err != nil {
+			pa.err = fmt.Errorf("spawn planner: %w", err)
+			return pa
+		}
+
+		result := agent.CollectResult(plannerAgent)
+		pa.results = append(pa.results, result)
+
+		if result.Failure == agent.FailureRateLimited {
+			pa.rateLimit = result.RateLimit
+			if pa.rateLimit == nil {
+				pa.rateLimit = &agent.RateLimit{}
+			}
+			pa.err = fmt.Errorf("planner rate limited: %s", pa.rateLimit.Message)
+			return pa
+		}
+		if result.ExitCode != 0 {
+			pa.err = fmt.Errorf("planner failed with exit code %d", result.ExitCode)
+			return pa
+		}
+
+		var problems []string
+		output, err := agent.ParsePlannerOutput(result.RawStdout)
+		if err == nil {
+			err = agent.ValidatePlannerOutput(output)
+		}
+		var verr *agent.PlanValidationError
+		switch {
+		case err == nil:
+			pa.output = output
+			pa.raw = agent.ResultText(result.RawStdout)
+			return pa
+		case errors.As(err, &verr):
+			problems = verr.Problems
+		default:
+			problems = []string{err.Error()}
+		}
+
+		if attempt >= cfg.Planning.MaxRepairAttempts {
+			pa.err = fmt.Errorf("planner output invalid after %d attempt(s): %s", attempt+1, strings.Join(problems, "; "))
+			return pa
+		}
+		pa.repairs++
+		data.RepairErrors = problems
+		data.PreviousOutput = agent.ResultText(result.RawStdout)
+	}
+}
+
+// recordPlanAttempt accounts for a planner run's cost and reports repairs.
+func (o *Orchestrator) recordPlanAttempt(label string, pa planAttempt) {
+	for _, result := range pa.results {
+		o.accumulateCost(result)
+	}
+	if pa.repairs > 0 {
+		o.ui.Warn(fmt.Sprintf("%s output failed validation; asked for %d repair(s)", label, pa.repairs))
+	}
+}
+
+// plannerTasks converts planner tasks to pending task store tasks.
+func plannerTasks(out *agent.PlannerOutput) []tasks.Task {
 	var storeTasks []tasks.Task
-	for _, pt := range planOutput.Tasks {
+	for _, pt := range out.Tasks {
+		// The timeout was checked by ValidatePlannerOutput.
+		timeout, _ := time.ParseDuration(pt.Timeout)
 		storeTasks = append(storeTasks, tasks.Task{
 			ID:            pt.ID,
 			Title:         pt.Title,
@@ -429,15 +763,16 @@ func (o *Orchestrator) runPlanning(ctx context.Context, description string, prio
 			CohesionGroup: pt.CohesionGroup,
 			Dependencies:  pt.Dependencies,
 			FileLocks:     pt.FileLocks,
+			Model:         pt.Model,
+			BudgetUSD:     pt.BudgetUSD,
+			BudgetTokens:  pt.BudgetTokens,
+			Timeout:       timeout,
+			ExtraCommands: pt.ExtraCommands,
+			ExtraTools:    pt.ExtraTools,
+			Complexity:    pt.Complexity,
 		})
 	}
-
-	// Validate dependencies
-	if err := tasks.ValidateDependencies(storeTasks); err != nil {
-		return nil, fmt.Errorf("invalid task dependencies: %w", err)
-	}
-
-	return storeTasks, nil
+	return storeTasks
 }
 
 func (o *Orchestrator) runDevelopment(ctx context.Context) []agent.AgentResult {
@@ -490,12 +825,21 @@ func (o *Orchestrator) runDevelopment(ctx context.Context) []agent.AgentResult {
 		// Generate watcher hooks and .claude/settings.json
 		if o.hooksDir != "" {
 			hookData := agent.BuildWatcherData(agentID, agent.RoleWorker, task, o.config, o.hooksDir)
-			hookPath := fmt.Sprintf("%s/%s-watcher.sh", o.hooksDir, agentID)
-			if err := agent.GenerateWatcherHookFromTemplate(o.watcherTmpl, hookData, hookPath); err != nil {
+			hookData.Worktree = wtPath
+			if o.approvals != nil {
+				if socket, err := o.approvals.Listen(agentID, agent.RoleWorker, task.ID); err != nil {
+					o.ui.Warn(fmt.Sprintf("tool approvals unavailable for %s, ask rules will deny: %v", task.ID, err))
+				} else {
+					hookData.ApprovalSocket = socket
+					hookData.AskTimeout = o.approvals.Timeout()
+				}
+			}
+			policyPath := agent.HookPolicyPath(o.hooksDir, agentID)
+			if err := agent.WriteHookPolicy(hookData, policyPath); err != nil {
 				// Non-fatal: log and continue without hooks
 				o.ui.Warn(fmt.Sprintf("generate hooks for %s: %v", task.ID, err))
 			} else {
-				if err := agent.GenerateAgentSettings(wtPath, hookPath); err != nil {
+				if err := agent.GenerateAgentSettings(wtPath, o.hookExecutable, policyPath); err != nil {
 					o.ui.Warn(fmt.Sprintf("generate settings for %s: %v", task.ID, err))
 				}
 			}
@@ -567,6 +911,18 @@ func (o *Orchestrator) handleDevelopmentResults(results []agent.AgentResult) {
 
 		// Release per-agent locks
 		o.releaseAgentLocks(result.AgentID)
+		if o.approvals != nil {
+			o.approvals.Stop(result.AgentID)
+		}
+
+		if result.Failure == agent.FailureRateLimited {
+			// Not the task's fault: run it again after the pause without
+			// spending a retry.
+			o.noteRateLimit(task.ID, result.RateLimit)
+			task.ResetClaimed()
+			o.discardWorktree(result.AgentID)
+			continue
+		}
 
 		if result.ExitCode == 0 {
 			// Run postcheck to validate filesystem changes
@@ -591,12 +947,19 @@ func (o *Orchestrator) handleDevelopmentResults(results []agent.AgentResult) {
 						CostUSD:    result.CostUSD,
 						TokensUsed: result.TokensUsed,
 					})
+					o.discardWorktree(result.AgentID)
 				}
 			} else {
 				task.Complete()
 			}
 		} else {
-			task.Fail(fmt.Sprintf("exit code %d", result.ExitCode))
+			outcome, reason := "failed", fmt.Sprintf("exit code %d", result.ExitCode)
+			if result.Failure == agent.FailureOOMKilled {
+				outcome = agent.FailureOOMKilled
+				reason = fmt.Sprintf("killed for exceeding sandbox.max_memory_mb (peak %d MB)", result.Resources.PeakMemoryBytes>>20)
+				o.ui.Warn(fmt.Sprintf("%s: %s", task.ID, reason))
+			}
+			task.Fail(reason)
 			o.runHook("on_failure", o.config.Hooks.OnFailure)
 
 			// Check retries
@@ -605,11 +968,12 @@ func (o *Orchestrator) handleDevelopmentResults(results []agent.AgentResult) {
 					Attempt:    task.RetryCount + 1,
 					AgentID:    result.AgentID,
 					Timestamp:  time.Now(),
-					Result:     "failed",
-					Notes:      fmt.Sprintf("exit code %d", result.ExitCode),
+					Result:     outcome,
+					Notes:      reason,
 					CostUSD:    result.CostUSD,
 					TokensUsed: result.TokensUsed,
 				})
+				o.discardWorktree(result.AgentID)
 			} else {
 				// Cascade failure to dependents
 				tasks.CascadeFailure(task.ID, o.taskStore.Tasks())
@@ -618,6 +982,17 @@ func (o *Orchestrator) handleDevelopmentResults(results []agent.AgentResult) {
 	}
 }
 
+// discardWorktree removes the worktree of a requeued attempt so the retry
+// can recreate the task branch from the base branch.
+func (o *Orchestrator) discardWorktree(agentID string) {
+	if o.worktrees == nil || agentID == "" {
+		return
+	}
+	if err := o.worktrees.Remove(agentID); err != nil {
+		o.ui.Warn(fmt.Sprintf("remove worktree for %s: %v", agentID, err))
+	}
+}
+
 // releaseAgentLocks releases file locks held by a specific agent.
 func (o *Orchestrator) releaseAgentLocks(agentID string) {
 	if o.locks == nil {
@@ -629,9 +1004,9 @@ func (o *Orchestrator) releaseAgentLocks(agentID string) {
 	}
 }
 
-func (o *Orchestrator) runValidation(ctx context.Context) []agent.AgentResult {
+func (o *Orchestrator) runValidation(ctx context.Context) []taskValidation {
 	allTasks := o.taskStore.Tasks()
-	var results []agent.AgentResult
+	var results []taskValidation
 
 	for i := range allTasks {
 		task := &allTasks[i]
@@ -649,21 +1024,74 @@ func (o *Orchestrator) runValidation(ctx context.Context) []agent.AgentResult {
 			}
 		}
 
-		// Build audit summary from git log
-		auditSummary := o.gitLogSummary(task)
+		auditSummary := o.auditSummary(task)
+
+		rule := o.config.Validation.Quorum.RuleFor(task.ID, task.CohesionGroup)
+		tv := taskValidation{taskID: task.ID, policy: rule.Policy}
+		routed := agent.RoutedValidatorConfig(task, o.config)
+		for n := 0; n < rule.Validators; n++ {
+			cfg := routed
+			if len(rule.Models) > 0 {
+				c := *routed
+				c.Models.Validator = rule.Models[n%len(rule.Models)]
+				cfg = &c
+			}
+			label := fmt.Sprintf("validator %d (%s)", n+1, cfg.Models.Validator)
 
-		valAgent, err := o.spawner.SpawnValidator(ctx, task, diff, auditSummary, o.config)
-		if err != nil {
-			continue
+			valAgent, err := o.spawner.SpawnValidator(ctx, task, diff, auditSummary, cfg)
+			if err != nil {
+				if rule.Validators > 1 {
+					o.ui.Warn(fmt.Sprintf("spawn %s for %s: %v", label, task.ID, err))
+				}
+				continue
+			}
+			result := agent.CollectResult(valAgent)
+			tv.runs = append(tv.runs, validatorRun{
+				label:  label,
+				model:  cfg.Models.Validator,
+				result: result,
+			})
+			// Every later validator would run straight into the same
+			// limit; stop here so the pause comes first.
+			if result.Failure == agent.FailureRateLimited {
+				return append(results, tv)
+			}
+		}
+		if len(tv.runs) > 0 {
+			results = append(results, tv)
 		}
-
-		result := agent.CollectResult(valAgent)
-		results = append(results, result)
 	}
 
 	return results
 }
 
+// auditCaveat introduces the audit log summary in a validator's prompt.
+const auditCaveat = "Self-reported: the worker's own processes can write to its audit log, " +
+	"so a clean summary proves nothing. Use it to find things to check in the diff, " +
+	"and treat blocked calls as attempts worth a closer look."
+
+// auditSummary tells a validator what the task's worker did: its audit
+// log, summarized, and its commits. The log is written where the worker's
+// own processes can write too, so the summary says it is self-reported.
+func (o *Orchestrator) auditSummary(task *tasks.Task) string {
+	var parts []string
+	if o.hooksDir != "" && task.AgentID != "" {
+		records, err := audit.ReadLog(agent.AuditLogPath(o.hooksDir, task.AgentID))
+		if err == nil {
+			parts = append(parts, auditCaveat+"\n"+audit.Compact(audit.Summarize(audit.Log{
+				AgentID: task.AgentID,
+				Role:    agent.RoleWorker,
+				Tasks:   []string{task.ID},
+				Records: records,
+			})))
+		}
+	}
+	if commits := o.gitLogSummary(task); commits != "" {
+		parts = append(parts, "Commits:\n"+commits)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
 // gitLogSummary returns a one-line-per-commit summary of work on a task branch.
 func (o *Orchestrator) gitLogSummary(task *tasks.Task) string {
 	if task.Worktree == "" {
@@ -682,16 +1110,28 @@ func (o *Orchestrator) gitLogSummary(task *tasks.Task) string {
 	return strings.TrimSpace(string(output))
 }
 
-func (o *Orchestrator) handleValidationResults(results []agent.AgentResult) {
-	for _, result := range results {
-		o.accumulateCost(result)
+func (o *Orchestrator) handleValidationResults(results []taskValidation) {
+	for _, tv := range results {
+		for _, run := range tv.runs {
+			o.accumulateCost(run.result)
+		}
 
-		task := o.taskStore.FindTask(result.TaskID)
+		task := o.taskStore.FindTask(tv.taskID)
 		if task == nil {
 			continue
 		}
 
-		if result.ExitCode != 0 {
+		// A limited validator says nothing about the work; the task stays
+		// done and is validated again after the pause.
+		if rl := tv.rateLimited(); rl != nil {
+			o.noteRateLimit(task.ID, rl)
+			continue
+		}
+
+		// Only when no validator ran to completion is the human asked what
+		// to do; otherwise crashed validators count against the quorum.
+		if crashed := tv.crashed(); crashed != nil {
+			result := crashed.result
 			decision := o.ui.ValidatorFailed(task.ID, fmt.Errorf("exit code %d", result.ExitCode))
 			switch decision {
 			case ui.ValidatorRetryTask:
@@ -710,13 +1150,33 @@ func (o *Orchestrator) handleValidationResults(results []agent.AgentResult) {
 			continue
 		}
 
-		valOutput, err := agent.ParseValidatorOutput(result.RawStdout)
-		if err != nil {
-			task.SetValidationResult("fail", "validator output parse error: "+err.Error())
-			continue
+		reviews, issues := tv.verdicts()
+		status, notes := decideQuorum(tv.policy, reviews)
+		task.SetValidationResult(status, notes)
+		task.Result.Issues = issues
+		if len(reviews) > 1 {
+			task.Result.Reviews = reviews
+		} else {
+			task.Result.Reviews = nil
 		}
 
-		task.SetValidationResult(valOutput.Status, valOutput.Notes)
+		// Send the work back with the review's issues as a checklist. They
+		// live on in the history entry; the result is cleared so the session
+		// summary only counts issues from the latest review.
+		if status == "fail" && task.RetryCount < o.config.Limits.MaxRetries {
+			agentID := task.AgentID
+			task.Requeue("validation failed", tasks.HistoryEntry{
+				Attempt:   task.RetryCount + 1,
+				AgentID:   agentID,
+				Timestamp: time.Now(),
+				Result:    "validation_failed",
+				Notes:     notes,
+				Issues:    issues,
+			})
+			task.Result.Issues = nil
+			task.Result.Reviews = nil
+			o.discardWorktree(agentID)
+		}
 	}
 }
 
@@ -829,6 +1289,8 @@ func (o *Orchestrator) presentChangesets(ctx context.Context, changesets []Chang
 			CohesionGroup: cs.CohesionGroup,
 			Description:   cs.Description,
 			TaskIDs:       cs.TaskIDs,
+			Reviews:       o.changesetReviews(cs),
+			Issues:        o.changesetIssues(cs),
 		}
 
 		decision, reason := o.ui.ChangesetReview(info)
@@ -853,7 +1315,7 @@ func (o *Orchestrator) presentChangesets(ctx context.Context, changesets []Chang
 					continue
 				}
 				mergeResult := agent.CollectResult(mergerAgent)
-				o.accumulateCost(mergeResult)
+				o.accumulateCost(mergeResult, cs.TaskIDs...)
 
 				if mergeResult.ExitCode != 0 {
 					o.ui.Warn(fmt.Sprintf("merger exited %d for group %s", mergeResult.ExitCode, cs.CohesionGroup))
@@ -896,12 +1358,15 @@ func (o *Orchestrator) presentChangesets(ctx context.Context, changesets []Chang
 			requeued += len(cs.TaskIDs)
 			for _, taskID := range cs.TaskIDs {
 				if task := o.taskStore.FindTask(taskID); task != nil {
+					agentID := task.AgentID
 					task.Requeue("changeset rejected", tasks.HistoryEntry{
 						Attempt:         task.RetryCount + 1,
 						Timestamp:       time.Now(),
 						Result:          "rejected",
 						RejectionReason: reason,
+						Issues:          task.Result.Issues,
 					})
+					o.discardWorktree(agentID)
 				}
 			}
 		case ui.ChangesetSkip:
@@ -949,11 +1414,72 @@ func (o *Orchestrator) checkBudgetCircuitBreaker() error {
 	return nil
 }
 
-func (o *Orchestrator) accumulateCost(result agent.AgentResult) {
+// accumulateCost adds an agent run to the session totals and the ledger.
+// The run is attributed to taskIDs, or to its own task if none are given.
+func (o *Orchestrator) accumulateCost(result agent.AgentResult, taskIDs ...string) {
 	o.sessionCost += result.CostUSD
-	o.sessionTokens += result.TokensUsed
+	o.sessionTokens += result.Usage.Counted(&o.config.Limits.TokenBudget)
 	o.state.SessionCost = o.sessionCost
 	o.state.SessionTokens = o.sessionTokens
+
+	if len(taskIDs) == 0 && result.TaskID != "" {
+		taskIDs = []string{result.TaskID}
+	}
+	usage := result.Usage
+	res := result.Resources
+	var models map[string]ledger.ModelUsage
+	for model, mu := range result.ModelUsage {
+		if models == nil {
+			models = make(map[string]ledger.ModelUsage, len(result.ModelUsage))
+		}
+		models[model] = ledger.ModelUsage{
+			CostUSD:             mu.CostUSD,
+			InputTokens:         mu.InputTokens,
+			OutputTokens:        mu.OutputTokens,
+			CacheCreationTokens: mu.CacheCreationInputTokens,
+			CacheReadTokens:     mu.CacheReadInputTokens,
+		}
+	}
+	err := o.ledger.Append(ledger.Entry{
+		Time:                time.Now(),
+		Session:             o.state.SessionID,
+		Wave:                o.state.WaveCycle,
+		Tasks:               taskIDs,
+		Role:                result.Role,
+		Model:               result.Model,
+		AgentID:             result.AgentID,
+		ExitCode:            result.ExitCode,
+		CostUSD:             result.CostUSD,
+		InputTokens:         usage.InputTokens,
+		OutputTokens:        usage.OutputTokens,
+		CacheCreationTokens: usage.CacheCreationInputTokens,
+		CacheReadTokens:     usage.CacheReadInputTokens,
+		Models:              models,
+		DurationMS:          result.Duration.Milliseconds(),
+		CPUMS:               res.CPUTime.Milliseconds(),
+		UserCPUMS:           res.UserCPU.Milliseconds(),
+		SystemCPUMS:         res.SystemCPU.Milliseconds(),
+		PeakMemoryBytes:     res.PeakMemoryBytes,
+		BlockReadBytes:      res.BlockReadBytes,
+		BlockWriteBytes:     res.BlockWriteBytes,
+	})
+	if err != nil {
+		o.ui.Warn(fmt.Sprintf("cost ledger: %v", err))
+	}
+}
+
+// openLedger opens the session's cost ledger once the session ID is known.
+// Without a sessions directory the ledger stays in memory.
+func (o *Orchestrator) openLedger() error {
+	if o.sessionsDir == "" {
+		return nil
+	}
+	l, err := ledger.Open(filepath.Join(o.sessionsDir, o.state.SessionID, ledger.FileName))
+	if err != nil {
+		return fmt.Errorf("open cost ledger: %w", err)
+	}
+	o.ledger = l
+	return nil
 }
 
 func (o *Orchestrator) persistState() {
@@ -962,12 +1488,6 @@ func (o *Orchestrator) persistState() {
 	}
 }
 
-func (o *Orchestrator) estimateCost(taskCount int) string {
-	low := float64(taskCount) * 0.50
-	high := float64(taskCount) * 3.00
-	return fmt.Sprintf("$%.2f - $%.2f", low, high)
-}
-
 func (o *Orchestrator) buildSessionState(approved, requeued int) ui.SessionState {
 	var requeuedTasks []string
 	var blocked int
@@ -996,8 +1516,14 @@ func (o *Orchestrator) buildSessionState(approved, requeued int) ui.SessionState
 // SessionSummary returns the accumulated session results for cost summary display.
 func (o *Orchestrator) SessionSummary() ui.CostSummary {
 	allTasks := o.taskStore.Tasks()
-	var completed, failed, merged int
+	entries := o.ledger.Entries()
+	var completed, failed, merged, issues int
+	bySeverity := make(map[string]int)
 	for _, t := range allTasks {
+		for _, issue := range t.Result.Issues {
+			issues++
+			bySeverity[issue.Severity]++
+		}
 		switch t.Status {
 		case tasks.StatusDone:
 			completed++
@@ -1007,6 +1533,11 @@ func (o *Orchestrator) SessionSummary() ui.CostSummary {
 			merged++
 		}
 	}
+	var est *ui.CostEstimate
+	if e := o.planEstimate; e != nil {
+		est = &ui.CostEstimate{Mean: e.Mean, Low: e.Low, High: e.High}
+	}
+	resources, recommendation := o.resourceSummary(entries)
 	return ui.CostSummary{
 		SessionID:      o.state.SessionID,
 		TotalCost:      o.sessionCost,
@@ -1015,7 +1546,42 @@ func (o *Orchestrator) SessionSummary() ui.CostSummary {
 		TasksCompleted: completed,
 		TasksFailed:    failed,
 		TasksMerged:    merged,
+		Issues:         issues,
+		IssueSeverity:  bySeverity,
+		TokenClasses:   tokenClasses(entries),
+		ByRole:         costLines(ledger.ByRole(entries)),
+		ByModel:        costLines(ledger.ByModel(entries)),
+		ByTask:         costLines(ledger.ByTask(entries)),
+		LedgerPath:     o.ledger.Path(),
+		Estimate:       est,
+		Resources:      resources,
+		Recommendation: recommendation,
+	}
+}
+
+// tokenClasses totals the ledger's tokens by class, in config.TokenClasses
+// order.
+func tokenClasses(entries []ledger.Entry) []ui.TokenClass {
+	totals := make(map[string]int)
+	for _, e := range entries {
+		totals[config.TokenInput] += e.InputTokens
+		totals[config.TokenOutput] += e.OutputTokens
+		totals[config.TokenCacheCreation] += e.CacheCreationTokens
+		totals[config.TokenCacheRead] += e.CacheReadTokens
+	}
+	var classes []ui.TokenClass
+	for _, c := range config.TokenClasses {
+		classes = append(classes, ui.TokenClass{Name: c, Tokens: totals[c]})
+	}
+	return classes
+}
+
+func costLines(lines []ledger.Line) []ui.CostLine {
+	out := make([]ui.CostLine, len(lines))
+	for i, l := range lines {
+		out[i] = ui.CostLine{Label: l.Key, Runs: l.Runs, CostUSD: l.CostUSD, Tokens: l.Tokens}
 	}
+	return out
 }
 
 // cleanupSession releases all locks and removes stale worktrees.
@@ -2,12 +2,16 @@ package orchestrator
 
 import (
 	"context"
+	"errors"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/kylegalloway/blueflame/internal/agent"
 	"github.com/kylegalloway/blueflame/internal/config"
+	"github.com/kylegalloway/blueflame/internal/estimate"
+	"github.com/kylegalloway/blueflame/internal/ledger"
 	"github.com/kylegalloway/blueflame/internal/state"
 	"github.com/kylegalloway/blueflame/internal/tasks"
 	"github.com/kylegalloway/blueflame/internal/ui"
@@ -459,6 +463,63 @@ func TestEmptyPlanHandled(t *testing.T) {
 	}
 }
 
+func TestPlannerRepairLoop(t *testing.T) {
+	cfg := testOrchestratorConfig(t)
+	cfg.Planning.MaxRepairAttempts = 2
+
+	valid := `{"tasks":[{"id":"task-001","title":"T","description":"d","priority":1,"file_locks":["a/"]}]}`
+	spawner := &agent.MockSpawner{
+		PlannerResults: []agent.MockResult{
+			{Output: `{"tasks":[{"id":"task-001","title":"T","priority":1,"file_locks":["../a"],"dependencies":["task-009"]}]}`},
+			{Output: valid},
+		},
+		WorkerResults: map[string]agent.MockResult{
+			"task-001": {Output: `{"result":"done"}`},
+		},
+	}
+	prompter := &ui.ScriptedPrompter{
+		PlanDecisions:      []ui.PlanDecision{ui.PlanApprove},
+		ChangesetDecisions: []ui.ChangesetDecision{ui.ChangesetApprove},
+		SessionDecisions:   []ui.SessionDecision{ui.SessionStop},
+	}
+
+	taskStore := tasks.NewTaskStore(cfg.Project.TasksFile)
+	orch := New(cfg, spawner, prompter, taskStore, nil)
+	if err := orch.Run(context.Background(), "Test"); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if len(spawner.PlannerCalls) != 2 {
+		t.Fatalf("planner calls = %d, want 2", len(spawner.PlannerCalls))
+	}
+	repair := spawner.PlannerCalls[1]
+	if len(repair.RepairErrors) != 3 {
+		t.Errorf("repair errors = %v, want 3 problems", repair.RepairErrors)
+	}
+	if !strings.Contains(repair.PreviousOutput, `"../a"`) {
+		t.Errorf("repair prompt should include previous output, got %q", repair.PreviousOutput)
+	}
+}
+
+func TestPlannerRepairGivesUp(t *testing.T) {
+	cfg := testOrchestratorConfig(t)
+	cfg.Planning.MaxRepairAttempts = 1
+
+	spawner := &agent.MockSpawner{
+		PlannerResult: &agent.MockResult{Output: `not json at all`},
+	}
+	taskStore := tasks.NewTaskStore(cfg.Project.TasksFile)
+	orch := New(cfg, spawner, &ui.ScriptedPrompter{}, taskStore, nil)
+
+	err := orch.Run(context.Background(), "Test")
+	if err == nil || !strings.Contains(err.Error(), "invalid after 2 attempt(s)") {
+		t.Errorf("expected give-up error, got %v", err)
+	}
+	if len(spawner.PlannerCalls) != 2 {
+		t.Errorf("planner calls = %d, want 2", len(spawner.PlannerCalls))
+	}
+}
+
 func TestChangesetOrderingByDependency(t *testing.T) {
 	cfg := testOrchestratorConfig(t)
 
@@ -672,3 +733,472 @@ func TestConfigDriftDetection(t *testing.T) {
 		t.Error("different config should produce different hash")
 	}
 }
+
+func TestPlannerTaskOverrides(t *testing.T) {
+	cfg := testOrchestratorConfig(t)
+	cfg.Models.Worker = "sonnet"
+	cfg.Limits.TaskOverrides.Models = []string{"opus"}
+
+	spawner := &agent.MockSpawner{
+		PlannerResult: &agent.MockResult{
+			Output: `{"tasks":[
+				{"id":"task-001","title":"Hard","description":"d","priority":1,"file_locks":["a/"],"model":"opus","timeout":"10m"},
+				{"id":"task-002","title":"Odd","description":"d","priority":1,"file_locks":["b/"],"model":"gpt"}
+			]}`,
+		},
+	}
+	prompter := &ui.ScriptedPrompter{PlanDecisions: []ui.PlanDecision{ui.PlanAbort}}
+
+	taskStore := tasks.NewTaskStore(cfg.Project.TasksFile)
+	orch := New(cfg, spawner, prompter, taskStore, nil)
+	if err := orch.Run(context.Background(), "Test"); err != ErrPlanRejected {
+		t.Fatalf("Run: %v, want ErrPlanRejected", err)
+	}
+
+	task := taskStore.FindTask("task-001")
+	if task.Model != "opus" || task.Timeout != 10*time.Minute {
+		t.Errorf("task-001 overrides = %q/%v, want opus/10m", task.Model, task.Timeout)
+	}
+	var shown, warned bool
+	for _, m := range prompter.Messages {
+		if strings.Contains(m, "overrides: model=opus timeout=10m0s") {
+			shown = true
+		}
+		if strings.Contains(m, `task-002: model "gpt" is not in limits.task_overrides.models`) {
+			warned = true
+		}
+	}
+	if !shown || !warned {
+		t.Errorf("overrides shown=%v warned=%v; messages: %v", shown, warned, prompter.Messages)
+	}
+}
+
+func TestPlannerComplexityRouting(t *testing.T) {
+	cfg := testOrchestratorConfig(t)
+	cfg.Models.Worker = "sonnet"
+	cfg.Models.Validator = "haiku"
+	cfg.Models.Routing.Bands = []config.RoutingBand{
+		{Name: "trivial", MaxFiles: 2, MaxRisk: "low", MaxSize: "small", WorkerModel: "haiku"},
+		{Name: "gnarly", WorkerModel: "opus", ValidatorModel: "sonnet"},
+	}
+
+	spawner := &agent.MockSpawner{
+		PlannerResult: &agent.MockResult{
+			Output: `{"tasks":[
+				{"id":"task-001","title":"Rename","description":"d","priority":1,"file_locks":["a/"],"complexity":{"files":1,"risk":"low","size":"small"}},
+				{"id":"task-002","title":"Refactor","description":"d","priority":1,"file_locks":["b/"],"complexity":{"files":12,"risk":"high","size":"large"}},
+				{"id":"task-003","title":"Unknown","description":"d","priority":1,"file_locks":["c/"]}
+			]}`,
+		},
+	}
+	prompter := &ui.ScriptedPrompter{PlanDecisions: []ui.PlanDecision{ui.PlanAbort}}
+
+	taskStore := tasks.NewTaskStore(cfg.Project.TasksFile)
+	orch := New(cfg, spawner, prompter, taskStore, nil)
+	if err := orch.Run(context.Background(), "Test"); err != ErrPlanRejected {
+		t.Fatalf("Run: %v, want ErrPlanRejected", err)
+	}
+
+	for id, want := range map[string]string{"task-001": "trivial", "task-002": "gnarly", "task-003": ""} {
+		if got := taskStore.FindTask(id).Route; got != want {
+			t.Errorf("%s route = %q, want %q", id, got, want)
+		}
+	}
+	want := "complexity: 12 file(s), risk high, size large -> route gnarly (worker opus, validator sonnet)"
+	found := false
+	for _, m := range prompter.Messages {
+		if strings.Contains(m, want) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("routing decision not shown; messages: %v", prompter.Messages)
+	}
+
+	task := taskStore.FindTask("task-002")
+	task.Status = tasks.StatusDone
+	if err := task.Requeue("retry", tasks.HistoryEntry{Attempt: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if task.History[0].Route != "gnarly" {
+		t.Errorf("history route = %q, want gnarly", task.History[0].Route)
+	}
+}
+
+func TestCostLedgerAttributesRuns(t *testing.T) {
+	cfg := testOrchestratorConfig(t)
+	spawner := &agent.MockSpawner{
+		PlannerResult: &agent.MockResult{
+			Output: `{"tasks":[{"id":"task-001","title":"A","description":"a","priority":1,"file_locks":["a/"]}]}`,
+		},
+		WorkerResults: map[string]agent.MockResult{
+			"task-001": {Output: `{"result":"done","total_cost_usd":0.50,"usage":{"input_tokens":100,"output_tokens":50,"cache_read_input_tokens":900}}`},
+		},
+	}
+	prompter := &ui.ScriptedPrompter{
+		PlanDecisions:      []ui.PlanDecision{ui.PlanApprove},
+		ChangesetDecisions: []ui.ChangesetDecision{ui.ChangesetApprove},
+		SessionDecisions:   []ui.SessionDecision{ui.SessionStop},
+	}
+
+	sessionsDir := t.TempDir()
+	taskStore := tasks.NewTaskStore(cfg.Project.TasksFile)
+	orch := New(cfg, spawner, prompter, taskStore, nil)
+	orch.SetSessionsDir(sessionsDir)
+	if err := orch.Run(context.Background(), "Test"); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	summary := orch.SessionSummary()
+	entries, err := ledger.Load(filepath.Join(sessionsDir, summary.SessionID, ledger.FileName))
+	if err != nil {
+		t.Fatalf("load ledger: %v", err)
+	}
+	roles := map[string]ledger.Entry{}
+	for _, e := range entries {
+		roles[e.Role] = e
+	}
+	for _, role := range []string{agent.RolePlanner, agent.RoleWorker, agent.RoleValidator, agent.RoleMerger} {
+		if _, ok := roles[role]; !ok {
+			t.Errorf("no ledger entry for %s in %+v", role, entries)
+		}
+	}
+	w := roles[agent.RoleWorker]
+	if w.Model != "sonnet" || w.Wave != 1 || len(w.Tasks) != 1 || w.Tasks[0] != "task-001" || w.CacheReadTokens != 900 {
+		t.Errorf("worker entry = %+v", w)
+	}
+	if m := roles[agent.RoleMerger]; len(m.Tasks) != 1 || m.Tasks[0] != "task-001" {
+		t.Errorf("merge should be attributed to its changeset, got %+v", m.Tasks)
+	}
+	if len(summary.ByTask) == 0 || summary.ByTask[0].Label != "task-001" || summary.ByTask[0].CostUSD != 0.50 {
+		t.Errorf("ByTask = %+v", summary.ByTask)
+	}
+	if summary.TotalTokens != 1050 {
+		t.Errorf("session tokens = %d, want 1050 (cache reads count by default)", summary.TotalTokens)
+	}
+}
+
+func TestSessionTokensCountConfiguredClasses(t *testing.T) {
+	cfg := testOrchestratorConfig(t)
+	cfg.Limits.TokenBudget.CountTokens = []string{config.TokenInput, config.TokenOutput}
+	spawner := &agent.MockSpawner{
+		PlannerResult: &agent.MockResult{
+			Output: `{"tasks":[{"id":"task-001","title":"A","description":"a","priority":1,"file_locks":["a/"]}]}`,
+		},
+		WorkerResults: map[string]agent.MockResult{
+			"task-001": {Output: `{"result":"done","usage":{"input_tokens":100,"output_tokens":50,"cache_read_input_tokens":900}}`},
+		},
+	}
+	prompter := &ui.ScriptedPrompter{
+		PlanDecisions:      []ui.PlanDecision{ui.PlanApprove},
+		ChangesetDecisions: []ui.ChangesetDecision{ui.ChangesetApprove},
+		SessionDecisions:   []ui.SessionDecision{ui.SessionStop},
+	}
+
+	orch := New(cfg, spawner, prompter, tasks.NewTaskStore(cfg.Project.TasksFile), nil)
+	if err := orch.Run(context.Background(), "Test"); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	summary := orch.SessionSummary()
+	if summary.TotalTokens != 150 {
+		t.Errorf("session tokens = %d, want 150 (input + output only)", summary.TotalTokens)
+	}
+	for _, c := range summary.TokenClasses {
+		if c.Name == config.TokenCacheRead && c.Tokens != 900 {
+			t.Errorf("cache_read usage = %d, want 900 even when not counted", c.Tokens)
+		}
+	}
+}
+
+func TestRateLimitedWorkerWaitsAndRetries(t *testing.T) {
+	cfg := testOrchestratorConfig(t)
+	cfg.Limits.RateLimit = config.RateLimitConfig{OnLimit: config.RateLimitWait, DefaultWait: 15 * time.Minute}
+	spawner := &agent.MockSpawner{
+		PlannerResult: &agent.MockResult{
+			Output: `{"tasks":[{"id":"task-001","title":"A","description":"a","priority":1,"file_locks":["a/"]}]}`,
+		},
+		WorkerResults: map[string]agent.MockResult{
+			"task-001": {ExitCode: 1, Output: `{"is_error":true,"result":"Claude AI usage limit reached"}`},
+		},
+	}
+	prompter := &ui.ScriptedPrompter{
+		PlanDecisions:      []ui.PlanDecision{ui.PlanApprove},
+		ChangesetDecisions: []ui.ChangesetDecision{ui.ChangesetApprove},
+		SessionDecisions:   []ui.SessionDecision{ui.SessionStop},
+	}
+
+	taskStore := tasks.NewTaskStore(cfg.Project.TasksFile)
+	orch := New(cfg, spawner, prompter, taskStore, nil)
+	var slept time.Duration
+	orch.sleep = func(ctx context.Context, d time.Duration) error {
+		slept += d
+		delete(spawner.WorkerResults, "task-001") // the limit has reset
+		return nil
+	}
+	if err := orch.Run(context.Background(), "Test"); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if slept != 15*time.Minute {
+		t.Errorf("slept %v, want the default wait of 15m", slept)
+	}
+	task := taskStore.FindTask("task-001")
+	if task.Status != tasks.StatusMerged {
+		t.Errorf("status = %s, want merged", task.Status)
+	}
+	if task.RetryCount != 0 || len(task.History) != 0 {
+		t.Errorf("rate limit should not consume a retry: retries=%d history=%+v", task.RetryCount, task.History)
+	}
+}
+
+func TestRateLimitPausesAreCapped(t *testing.T) {
+	cfg := testOrchestratorConfig(t)
+	cfg.Limits.RateLimit = config.RateLimitConfig{OnLimit: config.RateLimitWait, DefaultWait: time.Minute, MaxPauses: 2}
+	spawner := &agent.MockSpawner{
+		PlannerResult: &agent.MockResult{
+			Output: `{"tasks":[{"id":"task-001","title":"A","description":"a","priority":1,"file_locks":["a/"]}]}`,
+		},
+		WorkerResults: map[string]agent.MockResult{
+			"task-001": {ExitCode: 1, Output: `{"is_error":true,"api_error_status":429,"result":"API Error: 429"}`},
+		},
+	}
+	prompter := &ui.ScriptedPrompter{
+		PlanDecisions: []ui.PlanDecision{ui.PlanApprove},
+	}
+
+	taskStore := tasks.NewTaskStore(cfg.Project.TasksFile)
+	orch := New(cfg, spawner, prompter, taskStore, nil)
+	pauses := 0
+	orch.sleep = func(ctx context.Context, d time.Duration) error {
+		pauses++
+		return nil
+	}
+	err := orch.Run(context.Background(), "Test")
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Run error = %v, want ErrRateLimited", err)
+	}
+	if pauses != 2 {
+		t.Errorf("paused %d times, want 2", pauses)
+	}
+}
+
+func TestWorkerFailureMentioningLimitsUsesRetry(t *testing.T) {
+	cfg := testOrchestratorConfig(t)
+	spawner := &agent.MockSpawner{
+		PlannerResult: &agent.MockResult{
+			Output: `{"tasks":[{"id":"task-001","title":"A","description":"a","priority":1,"file_locks":["a/"]}]}`,
+		},
+		WorkerResults: map[string]agent.MockResult{
+			"task-001": {ExitCode: 1, Output: `{"is_error":true,"result":"context limit reached while fixing the 429 rate limit handler"}`},
+		},
+	}
+	prompter := &ui.ScriptedPrompter{
+		PlanDecisions:    []ui.PlanDecision{ui.PlanApprove},
+		SessionDecisions: []ui.SessionDecision{ui.SessionStop},
+	}
+
+	taskStore := tasks.NewTaskStore(cfg.Project.TasksFile)
+	orch := New(cfg, spawner, prompter, taskStore, nil)
+	orch.sleep = func(ctx context.Context, d time.Duration) error {
+		t.Fatal("an ordinary failure must not pause for a rate limit")
+		return nil
+	}
+	if err := orch.Run(context.Background(), "Test"); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if task := taskStore.FindTask("task-001"); task.RetryCount == 0 && len(task.History) == 0 {
+		t.Errorf("failure should be recorded against the task, got %+v", task)
+	}
+}
+
+func TestRateLimitedValidatorStopsValidation(t *testing.T) {
+	cfg := testOrchestratorConfig(t)
+	cfg.Limits.RateLimit = config.RateLimitConfig{OnLimit: config.RateLimitWait, DefaultWait: time.Minute}
+	cfg.Validation.Quorum.Validators = 3
+	spawner := &agent.MockSpawner{
+		PlannerResult: &agent.MockResult{
+			Output: `{"tasks":[
+				{"id":"task-001","title":"A","description":"a","priority":1,"file_locks":["a/"]},
+				{"id":"task-002","title":"B","description":"b","priority":2,"file_locks":["b/"]}
+			]}`,
+		},
+		ValidatorResults: map[string]agent.MockResult{
+			"task-001": {Output: `{"is_error":true,"error":"rate_limit","result":"API Error: rate limited"}`},
+		},
+	}
+	prompter := &ui.ScriptedPrompter{
+		PlanDecisions:      []ui.PlanDecision{ui.PlanApprove},
+		ChangesetDecisions: []ui.ChangesetDecision{ui.ChangesetApprove, ui.ChangesetApprove},
+		SessionDecisions:   []ui.SessionDecision{ui.SessionStop},
+	}
+
+	taskStore := tasks.NewTaskStore(cfg.Project.TasksFile)
+	orch := New(cfg, spawner, prompter, taskStore, nil)
+	var before []string
+	orch.sleep = func(ctx context.Context, d time.Duration) error {
+		before = append([]string(nil), spawner.ValidatorCalls...)
+		delete(spawner.ValidatorResults, "task-001") // the limit has reset
+		return nil
+	}
+	if err := orch.Run(context.Background(), "Test"); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if len(before) != 1 || before[0] != "task-001" {
+		t.Errorf("validators spawned before the pause = %v, want only the limited one", before)
+	}
+	for _, id := range []string{"task-001", "task-002"} {
+		if task := taskStore.FindTask(id); task.Status != tasks.StatusMerged {
+			t.Errorf("%s status = %s, want merged after the pause", id, task.Status)
+		}
+	}
+}
+
+func TestRateLimitedPlannerPausesAndPlansAgain(t *testing.T) {
+	cfg := testOrchestratorConfig(t)
+	cfg.Limits.RateLimit = config.RateLimitConfig{OnLimit: config.RateLimitWait, DefaultWait: time.Minute}
+	spawner := &agent.MockSpawner{
+		PlannerResults: []agent.MockResult{
+			{Output: `{"is_error":true,"result":"Claude AI usage limit reached"}`},
+			{Output: `{"tasks":[{"id":"task-001","title":"A","description":"a","priority":1,"file_locks":["a/"]}]}`},
+		},
+	}
+	prompter := &ui.ScriptedPrompter{
+		PlanDecisions:      []ui.PlanDecision{ui.PlanApprove},
+		ChangesetDecisions: []ui.ChangesetDecision{ui.ChangesetApprove},
+		SessionDecisions:   []ui.SessionDecision{ui.SessionStop},
+	}
+
+	taskStore := tasks.NewTaskStore(cfg.Project.TasksFile)
+	orch := New(cfg, spawner, prompter, taskStore, nil)
+	pauses := 0
+	orch.sleep = func(ctx context.Context, d time.Duration) error {
+		pauses++
+		return nil
+	}
+	if err := orch.Run(context.Background(), "Test"); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if pauses != 1 || len(spawner.PlannerCalls) != 2 {
+		t.Errorf("pauses = %d, planner calls = %d; want one pause, then a second plan", pauses, len(spawner.PlannerCalls))
+	}
+	if task := taskStore.FindTask("task-001"); task == nil || task.Status != tasks.StatusMerged {
+		t.Errorf("task-001 = %+v, want merged", task)
+	}
+}
+
+func TestRateLimitExitPolicyPersistsState(t *testing.T) {
+	cfg := testOrchestratorConfig(t)
+	cfg.Limits.RateLimit = config.RateLimitConfig{OnLimit: config.RateLimitExit, DefaultWait: time.Hour}
+	spawner := &agent.MockSpawner{
+		PlannerResult: &agent.MockResult{
+			Output: `{"tasks":[{"id":"task-001","title":"A","description":"a","priority":1,"file_locks":["a/"]}]}`,
+		},
+		WorkerResults: map[string]agent.MockResult{
+			"task-001": {ExitCode: 1, Output: `{"is_error":true,"result":"Claude AI usage limit reached|4102444800"}`},
+		},
+	}
+	prompter := &ui.ScriptedPrompter{
+		PlanDecisions: []ui.PlanDecision{ui.PlanApprove},
+	}
+
+	taskStore := tasks.NewTaskStore(cfg.Project.TasksFile)
+	stateMgr := state.NewManager(t.TempDir())
+	orch := New(cfg, spawner, prompter, taskStore, stateMgr)
+	err := orch.Run(context.Background(), "Test")
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Run error = %v, want ErrRateLimited", err)
+	}
+
+	saved, err := stateMgr.Load()
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	if saved.Phase != "rate_limited" || saved.RateLimitedUntil == nil || !saved.RateLimitedUntil.Equal(time.Unix(4102444800, 0)) {
+		t.Errorf("state = phase %q, until %v", saved.Phase, saved.RateLimitedUntil)
+	}
+	if task := taskStore.FindTask("task-001"); task.Status != tasks.StatusPending || task.RetryCount != 0 {
+		t.Errorf("task should be pending with no retry used, got %s/%d", task.Status, task.RetryCount)
+	}
+}
+
+func TestEstimatorLearnsAcrossSessions(t *testing.T) {
+	cfg := testOrchestratorConfig(t)
+	estPath := filepath.Join(t.TempDir(), estimate.FileName)
+	run := func() (*Orchestrator, *ui.ScriptedPrompter) {
+		spawner := &agent.MockSpawner{
+			PlannerResult: &agent.MockResult{
+				Output: `{"tasks":[{"id":"task-001","title":"A","description":"a","priority":1,"file_locks":["a/"]}]}`,
+			},
+			WorkerResults: map[string]agent.MockResult{
+				"task-001": {Output: `{"result":"done","total_cost_usd":0.80}`},
+			},
+		}
+		prompter := &ui.ScriptedPrompter{
+			PlanDecisions:      []ui.PlanDecision{ui.PlanApprove},
+			ChangesetDecisions: []ui.ChangesetDecision{ui.ChangesetApprove},
+			SessionDecisions:   []ui.SessionDecision{ui.SessionStop},
+		}
+		est, err := estimate.Open(estPath)
+		if err != nil {
+			t.Fatalf("open estimator: %v", err)
+		}
+		orch := New(cfg, spawner, prompter, tasks.NewTaskStore(filepath.Join(t.TempDir(), "tasks.yaml")), nil)
+		orch.SetEstimator(est)
+		if err := orch.Run(context.Background(), "Test"); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		return orch, prompter
+	}
+
+	_, first := run()
+	if !strings.Contains(strings.Join(first.Messages, "\n"), "no history yet") {
+		t.Errorf("first session should use the fixed guess: %v", first.Messages)
+	}
+
+	orch, second := run()
+	if !strings.Contains(strings.Join(second.Messages, "\n"), "estimate: $0.80") {
+		t.Errorf("second session should estimate from history: %v", second.Messages)
+	}
+	summary := orch.SessionSummary()
+	if summary.Estimate == nil || summary.Estimate.Mean != 0.80 {
+		t.Errorf("summary estimate = %+v", summary.Estimate)
+	}
+}
+
+func TestSessionSummaryRecommendsConcurrency(t *testing.T) {
+	cfg := testOrchestratorConfig(t)
+	spawner := &agent.MockSpawner{
+		PlannerResult: &agent.MockResult{
+			Output: `{"tasks":[{"id":"task-001","title":"A","description":"a","priority":1,"file_locks":["a/"]}]}`,
+		},
+		WorkerResults: map[string]agent.MockResult{
+			"task-001": {Resources: agent.ResourceUsage{PeakMemoryBytes: 800 << 20, BlockWriteBytes: 3 << 20}},
+		},
+	}
+	prompter := &ui.ScriptedPrompter{
+		PlanDecisions:      []ui.PlanDecision{ui.PlanApprove},
+		ChangesetDecisions: []ui.ChangesetDecision{ui.ChangesetApprove},
+		SessionDecisions:   []ui.SessionDecision{ui.SessionStop},
+	}
+	orch := New(cfg, spawner, prompter, tasks.NewTaskStore(filepath.Join(t.TempDir(), "tasks.yaml")), nil)
+	orch.hostResources = func() (int, int) { return 4000, 64 }
+	if err := orch.Run(context.Background(), "Test"); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	summary := orch.SessionSummary()
+	var worker *ui.ResourceLine
+	for i, l := range summary.Resources {
+		if l.Role == agent.RoleWorker {
+			worker = &summary.Resources[i]
+		}
+	}
+	if worker == nil || worker.PeakMemoryBytes != 800<<20 || worker.BlockWriteBytes < 3<<20 {
+		t.Fatalf("worker resources = %+v", summary.Resources)
+	}
+	rec := summary.Recommendation
+	if rec == nil || rec.Development != 4 || rec.MinRAMPerAgentMB != 1000 || rec.LimitedBy != "memory" {
+		t.Errorf("recommendation = %+v", rec)
+	}
+}
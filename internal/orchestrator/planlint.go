@@ -0,0 +1,331 @@
+package orchestrator
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kylegalloway/blueflame/internal/tasks"
+)
+
+// Plan lint finding kinds.
+const (
+	LintMissingLock   = "missing-lock"
+	LintOverlapLock   = "overlapping-locks"
+	LintBroadLock     = "broad-lock"
+	LintIdleWorkers   = "idle-workers"
+	LintCohesionLayer = "cohesion-layers"
+)
+
+// minFilesForBroadLock avoids flagging broad locks in tiny repos, where
+// any directory is "most of the repo".
+const minFilesForBroadLock = 10
+
+// LintFinding is one problem the plan linter found.
+type LintFinding struct {
+	Kind    string
+	TaskIDs []string
+	Message string
+}
+
+func (f LintFinding) String() string {
+	return fmt.Sprintf("[%s] %s", f.Kind, f.Message)
+}
+
+// PlanLintOptions configures LintPlan.
+type PlanLintOptions struct {
+	// RepoDir is used to check that file locks exist.
+	RepoDir string
+	// Files lists repo-relative files; used to size lock coverage.
+	Files []string
+	// Concurrency is the development worker limit.
+	Concurrency int
+	// MaxWaveCycles bounds how long a dependency chain can be.
+	MaxWaveCycles int
+	// BroadLockRatio is the fraction of repo files a single task may lock
+	// before it is flagged.
+	BroadLockRatio float64
+}
+
+// LintPlan reviews a proposed plan for problems that won't stop it from
+// running but will make it run badly: locks on paths that don't exist,
+// overlapping or overly broad locks, dependency chains that leave workers
+// idle, and cohesion groups split across dependency layers.
+func LintPlan(plan []tasks.Task, opts PlanLintOptions) []LintFinding {
+	var findings []LintFinding
+	findings = append(findings, lintMissingLocks(plan, opts.RepoDir)...)
+	findings = append(findings, lintOverlappingLocks(plan)...)
+	findings = append(findings, lintBroadLocks(plan, opts.Files, opts.BroadLockRatio)...)
+
+	layers := dependencyLayers(plan)
+	findings = append(findings, lintCriticalPath(plan, layers, opts)...)
+	findings = append(findings, lintCohesionLayers(plan, layers)...)
+	return findings
+}
+
+// FormatLintFindings renders findings as feedback for a re-plan.
+func FormatLintFindings(findings []LintFinding) string {
+	var b strings.Builder
+	b.WriteString("Plan lint findings to fix:")
+	for _, f := range findings {
+		fmt.Fprintf(&b, "\n- %s", f)
+	}
+	return b.String()
+}
+
+// lintMissingLocks flags locks whose path doesn't exist and whose parent
+// directory doesn't either, so they can't be a plausible new file.
+func lintMissingLocks(plan []tasks.Task, repoDir string) []LintFinding {
+	if repoDir == "" {
+		return nil
+	}
+	var findings []LintFinding
+	for _, t := range plan {
+		for _, lock := range t.FileLocks {
+			p := filepath.Join(repoDir, filepath.FromSlash(strings.TrimSuffix(lock, "/")))
+			if matches, _ := filepath.Glob(p); len(matches) > 0 {
+				continue
+			}
+			if info, err := os.Stat(filepath.Dir(p)); err == nil && info.IsDir() {
+				continue // a new file or directory in an existing one
+			}
+			findings = append(findings, LintFinding{
+				Kind:    LintMissingLock,
+				TaskIDs: []string{t.ID},
+				Message: fmt.Sprintf("%s locks %q, which doesn't exist and has no existing parent directory", t.ID, lock),
+			})
+		}
+	}
+	return findings
+}
+
+// lintOverlappingLocks flags independent tasks whose locks are identical or
+// nested. Identical locks make the scheduler run the tasks one at a time;
+// nested locks let them run together and collide at merge.
+func lintOverlappingLocks(plan []tasks.Task) []LintFinding {
+	reach := dependencyReach(plan)
+	var findings []LintFinding
+	for i := range plan {
+		for j := i + 1; j < len(plan); j++ {
+			a, b := plan[i], plan[j]
+			if reach[a.ID][b.ID] || reach[b.ID][a.ID] {
+				continue // already ordered by dependencies
+			}
+			for _, la := range a.FileLocks {
+				for _, lb := range b.FileLocks {
+					if !locksOverlap(la, lb) {
+						continue
+					}
+					effect := "will run serially"
+					if la != lb {
+						effect = "can run together and conflict at merge"
+					}
+					findings = append(findings, LintFinding{
+						Kind:    LintOverlapLock,
+						TaskIDs: []string{a.ID, b.ID},
+						Message: fmt.Sprintf("%s (%s) and %s (%s) overlap and %s", a.ID, la, b.ID, lb, effect),
+					})
+				}
+			}
+		}
+	}
+	return findings
+}
+
+func locksOverlap(a, b string) bool {
+	a, b = strings.TrimSuffix(a, "/"), strings.TrimSuffix(b, "/")
+	return a == b || strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
+
+// lintBroadLocks flags tasks whose locks cover more than ratio of the repo.
+func lintBroadLocks(plan []tasks.Task, files []string, ratio float64) []LintFinding {
+	if ratio <= 0 || len(files) < minFilesForBroadLock {
+		return nil
+	}
+	var findings []LintFinding
+	for _, t := range plan {
+		covered := 0
+		for _, f := range files {
+			if coveredByLocks(f, t.FileLocks) {
+				covered++
+			}
+		}
+		if share := float64(covered) / float64(len(files)); share > ratio {
+			findings = append(findings, LintFinding{
+				Kind:    LintBroadLock,
+				TaskIDs: []string{t.ID},
+				Message: fmt.Sprintf("%s locks %.0f%% of the repository (%d of %d files)", t.ID, share*100, covered, len(files)),
+			})
+		}
+	}
+	return findings
+}
+
+func coveredByLocks(f string, locks []string) bool {
+	for _, lock := range locks {
+		lock = strings.TrimSuffix(lock, "/")
+		if f == lock || strings.HasPrefix(f, lock+"/") {
+			return true
+		}
+		if ok, _ := path.Match(lock, f); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// lintCriticalPath flags dependency chains that force more waves than the
+// task count needs, leaving workers idle, and chains longer than the wave
+// cycle limit.
+func lintCriticalPath(plan []tasks.Task, layers map[string]int, opts PlanLintOptions) []LintFinding {
+	depth := 0
+	for _, l := range layers {
+		depth = max(depth, l+1)
+	}
+	if depth <= 1 || opts.Concurrency < 1 {
+		return nil
+	}
+
+	var findings []LintFinding
+	chain := criticalPath(plan, layers)
+	minWaves := (len(plan) + opts.Concurrency - 1) / opts.Concurrency
+	if depth > minWaves {
+		avg := float64(len(plan)) / float64(depth)
+		findings = append(findings, LintFinding{
+			Kind:    LintIdleWorkers,
+			TaskIDs: chain,
+			Message: fmt.Sprintf("critical path is %d tasks (%s) against a concurrency of %d; about %.1f of %d workers busy per wave",
+				depth, strings.Join(chain, " -> "), opts.Concurrency, avg, opts.Concurrency),
+		})
+	}
+	if opts.MaxWaveCycles > 0 && depth > opts.MaxWaveCycles {
+		findings = append(findings, LintFinding{
+			Kind:    LintIdleWorkers,
+			TaskIDs: chain,
+			Message: fmt.Sprintf("critical path of %d tasks exceeds max_wave_cycles (%d); the plan cannot finish", depth, opts.MaxWaveCycles),
+		})
+	}
+	return findings
+}
+
+// lintCohesionLayers flags cohesion groups whose tasks sit in different
+// dependency layers: the group can't be merged until its last layer is done.
+func lintCohesionLayers(plan []tasks.Task, layers map[string]int) []LintFinding {
+	groups := make(map[string][]string)
+	for _, t := range plan {
+		if t.CohesionGroup != "" {
+			groups[t.CohesionGroup] = append(groups[t.CohesionGroup], t.ID)
+		}
+	}
+	names := make([]string, 0, len(groups))
+	for g := range groups {
+		names = append(names, g)
+	}
+	sort.Strings(names)
+
+	var findings []LintFinding
+	for _, g := range names {
+		ids := groups[g]
+		lo, hi := layers[ids[0]], layers[ids[0]]
+		for _, id := range ids[1:] {
+			lo, hi = min(lo, layers[id]), max(hi, layers[id])
+		}
+		if lo != hi {
+			findings = append(findings, LintFinding{
+				Kind:    LintCohesionLayer,
+				TaskIDs: ids,
+				Message: fmt.Sprintf("cohesion group %q spans dependency layers %d-%d (%s); it merges only after its last layer", g, lo+1, hi+1, strings.Join(ids, ", ")),
+			})
+		}
+	}
+	return findings
+}
+
+// dependencyLayers assigns each task its depth in the dependency graph:
+// 0 for tasks without dependencies, 1 + the deepest dependency otherwise.
+// The plan must be acyclic.
+func dependencyLayers(plan []tasks.Task) map[string]int {
+	byID := make(map[string]*tasks.Task, len(plan))
+	for i := range plan {
+		byID[plan[i].ID] = &plan[i]
+	}
+	layers := make(map[string]int, len(plan))
+	var visit func(id string, seen map[string]bool) int
+	visit = func(id string, seen map[string]bool) int {
+		if l, ok := layers[id]; ok {
+			return l
+		}
+		t, ok := byID[id]
+		if !ok || seen[id] {
+			return 0
+		}
+		seen[id] = true
+		l := 0
+		for _, dep := range t.Dependencies {
+			if _, ok := byID[dep]; ok {
+				l = max(l, visit(dep, seen)+1)
+			}
+		}
+		layers[id] = l
+		return l
+	}
+	for _, t := range plan {
+		visit(t.ID, make(map[string]bool))
+	}
+	return layers
+}
+
+// criticalPath returns the IDs along one longest dependency chain, root first.
+func criticalPath(plan []tasks.Task, layers map[string]int) []string {
+	byID := make(map[string]*tasks.Task, len(plan))
+	var end *tasks.Task
+	for i := range plan {
+		byID[plan[i].ID] = &plan[i]
+		if end == nil || layers[plan[i].ID] > layers[end.ID] {
+			end = &plan[i]
+		}
+	}
+	var chain []string
+	for t := end; t != nil; {
+		chain = append([]string{t.ID}, chain...)
+		var next *tasks.Task
+		for _, dep := range t.Dependencies {
+			if d, ok := byID[dep]; ok && layers[dep] == layers[t.ID]-1 {
+				next = d
+				break
+			}
+		}
+		t = next
+	}
+	return chain
+}
+
+// dependencyReach maps each task ID to the set of tasks it transitively
+// depends on.
+func dependencyReach(plan []tasks.Task) map[string]map[string]bool {
+	byID := make(map[string]*tasks.Task, len(plan))
+	for i := range plan {
+		byID[plan[i].ID] = &plan[i]
+	}
+	reach := make(map[string]map[string]bool, len(plan))
+	for _, t := range plan {
+		seen := make(map[string]bool)
+		stack := append([]string{}, t.Dependencies...)
+		for len(stack) > 0 {
+			id := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if d, ok := byID[id]; ok {
+				stack = append(stack, d.Dependencies...)
+			}
+		}
+		reach[t.ID] = seen
+	}
+	return reach
+}
@@ -0,0 +1,155 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kylegalloway/blueflame/internal/agent"
+	"github.com/kylegalloway/blueflame/internal/tasks"
+	"github.com/kylegalloway/blueflame/internal/ui"
+)
+
+func findingKinds(findings []LintFinding) map[string]int {
+	kinds := make(map[string]int)
+	for _, f := range findings {
+		kinds[f.Kind]++
+	}
+	return kinds
+}
+
+func TestLintPlanMissingLocks(t *testing.T) {
+	repo := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repo, "pkg", "auth"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	plan := []tasks.Task{
+		{ID: "task-001", FileLocks: []string{"pkg/auth/"}},           // exists
+		{ID: "task-002", FileLocks: []string{"pkg/auth/session.go"}}, // new file in existing dir
+		{ID: "task-003", FileLocks: []string{"src/made/up/"}},        // implausible
+	}
+	findings := LintPlan(plan, PlanLintOptions{RepoDir: repo})
+
+	var missing []LintFinding
+	for _, f := range findings {
+		if f.Kind == LintMissingLock {
+			missing = append(missing, f)
+		}
+	}
+	if len(missing) != 1 || missing[0].TaskIDs[0] != "task-003" {
+		t.Errorf("missing-lock findings = %v, want one for task-003", missing)
+	}
+}
+
+func TestLintPlanOverlappingLocks(t *testing.T) {
+	plan := []tasks.Task{
+		{ID: "task-001", FileLocks: []string{"pkg/"}},
+		{ID: "task-002", FileLocks: []string{"pkg/auth/"}},
+		{ID: "task-003", FileLocks: []string{"pkg/auth/"}, Dependencies: []string{"task-002"}},
+		{ID: "task-004", FileLocks: []string{"cmd/"}},
+	}
+	findings := LintPlan(plan, PlanLintOptions{})
+
+	var overlaps []string
+	for _, f := range findings {
+		if f.Kind == LintOverlapLock {
+			overlaps = append(overlaps, strings.Join(f.TaskIDs, "+"))
+		}
+	}
+	// task-002/task-003 are ordered by a dependency, so only task-001 overlaps.
+	want := "task-001+task-002,task-001+task-003"
+	if got := strings.Join(overlaps, ","); got != want {
+		t.Errorf("overlaps = %s, want %s", got, want)
+	}
+}
+
+func TestLintPlanBroadLocks(t *testing.T) {
+	var files []string
+	for i := 0; i < 8; i++ {
+		files = append(files, "internal/x"+string(rune('a'+i))+".go")
+	}
+	files = append(files, "README.md", "go.mod")
+
+	plan := []tasks.Task{
+		{ID: "task-001", FileLocks: []string{"internal/"}},
+		{ID: "task-002", FileLocks: []string{"README.md"}},
+	}
+	findings := LintPlan(plan, PlanLintOptions{Files: files, BroadLockRatio: 0.5})
+	kinds := findingKinds(findings)
+	if kinds[LintBroadLock] != 1 {
+		t.Fatalf("broad-lock findings = %d, want 1: %v", kinds[LintBroadLock], findings)
+	}
+}
+
+func TestLintPlanCriticalPathAndCohesion(t *testing.T) {
+	plan := []tasks.Task{
+		{ID: "a", FileLocks: []string{"a/"}, CohesionGroup: "g"},
+		{ID: "b", FileLocks: []string{"b/"}, Dependencies: []string{"a"}},
+		{ID: "c", FileLocks: []string{"c/"}, Dependencies: []string{"b"}, CohesionGroup: "g"},
+		{ID: "d", FileLocks: []string{"d/"}},
+	}
+	findings := LintPlan(plan, PlanLintOptions{Concurrency: 4, MaxWaveCycles: 2})
+
+	var idle []string
+	for _, f := range findings {
+		if f.Kind == LintIdleWorkers {
+			idle = append(idle, f.Message)
+		}
+	}
+	if len(idle) != 2 {
+		t.Fatalf("idle-workers findings = %v, want critical path and wave limit", idle)
+	}
+	if !strings.Contains(idle[0], "critical path is 3 tasks (a -> b -> c)") {
+		t.Errorf("unexpected critical path message: %s", idle[0])
+	}
+	if !strings.Contains(idle[1], "exceeds max_wave_cycles (2)") {
+		t.Errorf("unexpected wave limit message: %s", idle[1])
+	}
+	if findingKinds(findings)[LintCohesionLayer] != 1 {
+		t.Errorf("expected cohesion-layers finding, got %v", findings)
+	}
+}
+
+func TestLintPlanCleanPlan(t *testing.T) {
+	plan := []tasks.Task{
+		{ID: "task-001", FileLocks: []string{"a/"}},
+		{ID: "task-002", FileLocks: []string{"b/"}},
+	}
+	if findings := LintPlan(plan, PlanLintOptions{Concurrency: 4, MaxWaveCycles: 5}); len(findings) != 0 {
+		t.Errorf("expected no findings, got %v", findings)
+	}
+}
+
+func TestPlanLintAutoFixOnReplan(t *testing.T) {
+	cfg := testOrchestratorConfig(t)
+	cfg.Planning.Lint.AutoFix = true
+
+	spawner := &agent.MockSpawner{
+		PlannerResult: &agent.MockResult{
+			Output: `{"tasks":[
+				{"id":"task-001","title":"A","description":"a","priority":1,"file_locks":["pkg/"]},
+				{"id":"task-002","title":"B","description":"b","priority":1,"file_locks":["pkg/auth/"]}
+			]}`,
+		},
+	}
+	prompter := &ui.ScriptedPrompter{
+		PlanDecisions: []ui.PlanDecision{ui.PlanReplan, ui.PlanAbort},
+	}
+
+	taskStore := tasks.NewTaskStore(cfg.Project.TasksFile)
+	orch := New(cfg, spawner, prompter, taskStore, nil)
+	if err := orch.Run(context.Background(), "Test"); err != ErrPlanRejected {
+		t.Fatalf("Run: %v, want ErrPlanRejected", err)
+	}
+
+	if len(spawner.PlannerCalls) != 2 {
+		t.Fatalf("planner calls = %d, want 2", len(spawner.PlannerCalls))
+	}
+	replanContext := spawner.PlannerCalls[1].PriorContext
+	if !strings.Contains(replanContext, "Plan lint findings to fix:") || !strings.Contains(replanContext, "- [overlapping-locks]") {
+		t.Errorf("re-plan context missing lint findings: %q", replanContext)
+	}
+}
@@ -0,0 +1,159 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kylegalloway/blueflame/internal/agent"
+	"github.com/kylegalloway/blueflame/internal/config"
+	"github.com/kylegalloway/blueflame/internal/tasks"
+	"github.com/kylegalloway/blueflame/internal/ui"
+)
+
+// reviewError marks a validator that crashed or returned unparseable output.
+const reviewError = "error"
+
+// taskValidation holds the validator runs for one task.
+type taskValidation struct {
+	taskID string
+	policy string
+	runs   []validatorRun
+}
+
+// validatorRun is one validator's result for a task.
+type validatorRun struct {
+	label  string
+	model  string
+	result agent.AgentResult
+}
+
+// crashed returns the first run if every validator exited non-zero, or nil.
+func (tv *taskValidation) crashed() *validatorRun {
+	if len(tv.runs) == 0 {
+		return nil
+	}
+	for _, run := range tv.runs {
+		if run.result.ExitCode == 0 {
+			return nil
+		}
+	}
+	return &tv.runs[0]
+}
+
+// rateLimited returns the first run's rate limit, if any run hit one.
+func (tv *taskValidation) rateLimited() *agent.RateLimit {
+	for _, run := range tv.runs {
+		if run.result.Failure == agent.FailureRateLimited {
+			return run.result.RateLimit
+		}
+	}
+	return nil
+}
+
+// verdicts parses each run into a review and collects the issues all
+// validators raised. Crashed validators and unparseable output are
+// recorded with status "error".
+func (tv *taskValidation) verdicts() ([]tasks.ValidatorReview, []tasks.Issue) {
+	reviews := make([]tasks.ValidatorReview, 0, len(tv.runs))
+	var issues [][]tasks.Issue
+	for _, run := range tv.runs {
+		r := tasks.ValidatorReview{Validator: run.label, Model: run.model}
+		if run.result.ExitCode != 0 {
+			r.Status = reviewError
+			r.Notes = fmt.Sprintf("exit code %d", run.result.ExitCode)
+		} else if out, err := agent.ParseValidatorOutput(run.result.RawStdout); err != nil {
+			r.Status = reviewError
+			r.Notes = "validator output parse error: " + err.Error()
+		} else {
+			r.Status = out.Status
+			r.Notes = out.Notes
+			issues = append(issues, out.Issues)
+		}
+		reviews = append(reviews, r)
+	}
+	return reviews, tasks.MergeIssues(issues...)
+}
+
+// decideQuorum combines validator verdicts under a quorum policy:
+//
+//   - unanimous: every validator must pass; errors count as failures.
+//   - majority: more than half must pass; errors count as failures.
+//   - any-fail-blocks: any "fail" blocks, errors abstain, and at least one
+//     validator must pass.
+//
+// A single review's notes are returned as-is; a quorum's notes summarize
+// the vote and each validator's notes.
+func decideQuorum(policy string, reviews []tasks.ValidatorReview) (status, notes string) {
+	var passes, fails int
+	for _, r := range reviews {
+		switch r.Status {
+		case "pass":
+			passes++
+		case "fail":
+			fails++
+		}
+	}
+
+	pass := false
+	switch policy {
+	case config.QuorumMajority:
+		pass = passes*2 > len(reviews)
+	case config.QuorumAnyFailBlocks:
+		pass = fails == 0 && passes > 0
+	default:
+		pass = passes == len(reviews)
+	}
+	status = "fail"
+	if pass {
+		status = "pass"
+	}
+
+	if len(reviews) == 1 {
+		return status, reviews[0].Notes
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s quorum: %d of %d validators passed", policy, passes, len(reviews))
+	for _, r := range reviews {
+		fmt.Fprintf(&b, "; %s: %s", r.Validator, r.Status)
+		if r.Notes != "" {
+			fmt.Fprintf(&b, " (%s)", r.Notes)
+		}
+	}
+	return status, b.String()
+}
+
+// changesetReviews collects the quorum verdicts of a changeset's tasks for
+// display at review.
+func (o *Orchestrator) changesetReviews(cs Changeset) []ui.TaskReviews {
+	var out []ui.TaskReviews
+	for _, id := range cs.TaskIDs {
+		task := o.taskStore.FindTask(id)
+		if task == nil || len(task.Result.Reviews) == 0 {
+			continue
+		}
+		tr := ui.TaskReviews{TaskID: id, Disagreement: task.Result.Disagreement()}
+		for _, r := range task.Result.Reviews {
+			tr.Reviews = append(tr.Reviews, ui.ValidatorReview{
+				Validator: r.Validator,
+				Status:    r.Status,
+				Notes:     r.Notes,
+			})
+		}
+		out = append(out, tr)
+	}
+	return out
+}
+
+// changesetIssues lists the issues the last review raised on a changeset's
+// tasks, prefixed with the task ID.
+func (o *Orchestrator) changesetIssues(cs Changeset) []string {
+	var out []string
+	for _, id := range cs.TaskIDs {
+		if task := o.taskStore.FindTask(id); task != nil {
+			for _, issue := range task.Result.Issues {
+				out = append(out, fmt.Sprintf("%s: %s", id, issue))
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,153 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kylegalloway/blueflame/internal/agent"
+	"github.com/kylegalloway/blueflame/internal/config"
+	"github.com/kylegalloway/blueflame/internal/tasks"
+	"github.com/kylegalloway/blueflame/internal/ui"
+)
+
+func TestDecideQuorum(t *testing.T) {
+	pass := tasks.ValidatorReview{Validator: "v", Status: "pass"}
+	fail := tasks.ValidatorReview{Validator: "v", Status: "fail"}
+	errored := tasks.ValidatorReview{Validator: "v", Status: reviewError}
+
+	tests := []struct {
+		policy  string
+		reviews []tasks.ValidatorReview
+		want    string
+	}{
+		{config.QuorumUnanimous, []tasks.ValidatorReview{pass, pass}, "pass"},
+		{config.QuorumUnanimous, []tasks.ValidatorReview{pass, errored}, "fail"},
+		{config.QuorumMajority, []tasks.ValidatorReview{pass, pass, fail}, "pass"},
+		{config.QuorumMajority, []tasks.ValidatorReview{pass, fail}, "fail"},
+		{config.QuorumAnyFailBlocks, []tasks.ValidatorReview{pass, errored}, "pass"},
+		{config.QuorumAnyFailBlocks, []tasks.ValidatorReview{pass, pass, fail}, "fail"},
+		{config.QuorumAnyFailBlocks, []tasks.ValidatorReview{errored}, "fail"},
+	}
+	for _, tt := range tests {
+		if got, _ := decideQuorum(tt.policy, tt.reviews); got != tt.want {
+			t.Errorf("decideQuorum(%s, %v) = %s, want %s", tt.policy, tt.reviews, got, tt.want)
+		}
+	}
+}
+
+func runQuorumSession(t *testing.T, policy string) (*tasks.TaskStore, *ui.ScriptedPrompter) {
+	t.Helper()
+	cfg := testOrchestratorConfig(t)
+	cfg.Limits.MaxWaveCycles = 1
+	cfg.Validation.Quorum.Overrides = map[string]config.QuorumRule{
+		"grp": {Validators: 3, Models: []string{"haiku", "sonnet", "opus"}, Policy: policy},
+	}
+
+	spawner := &agent.MockSpawner{
+		PlannerResult: &agent.MockResult{
+			Output: `{"tasks":[{"id":"task-001","title":"Test","description":"desc","priority":1,"cohesion_group":"grp","file_locks":["a/"]}]}`,
+		},
+		WorkerResults: map[string]agent.MockResult{
+			"task-001": {Output: `{"result":"done"}`},
+		},
+		ValidatorModelResults: map[string]agent.MockResult{
+			"haiku":  {Output: `{"status":"pass","notes":"looks good","issues":[{"file":"a/a.go","severity":"low","message":"typo"}]}`},
+			"sonnet": {Output: `{"status":"fail","notes":"missing error check"}`},
+			"opus":   {Output: `{"status":"pass","notes":"fine"}`},
+		},
+	}
+	prompter := &ui.ScriptedPrompter{
+		PlanDecisions:      []ui.PlanDecision{ui.PlanApprove},
+		ChangesetDecisions: []ui.ChangesetDecision{ui.ChangesetSkip},
+		SessionDecisions:   []ui.SessionDecision{ui.SessionStop},
+	}
+
+	taskStore := tasks.NewTaskStore(cfg.Project.TasksFile)
+	orch := New(cfg, spawner, prompter, taskStore, nil)
+	if err := orch.Run(context.Background(), "Test task"); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return taskStore, prompter
+}
+
+func TestValidatorQuorumMajorityShowsDisagreement(t *testing.T) {
+	taskStore, prompter := runQuorumSession(t, config.QuorumMajority)
+
+	task := taskStore.FindTask("task-001")
+	if task.Result.Status != "pass" || len(task.Result.Reviews) != 3 {
+		t.Fatalf("result = %+v, want pass with 3 reviews", task.Result)
+	}
+	if len(prompter.Changesets) != 1 {
+		t.Fatalf("changesets reviewed = %d, want 1", len(prompter.Changesets))
+	}
+	reviews := prompter.Changesets[0].Reviews
+	if len(reviews) != 1 || !reviews[0].Disagreement {
+		t.Fatalf("changeset reviews = %+v, want one disagreement", reviews)
+	}
+	if r := reviews[0].Reviews[1]; r.Validator != "validator 2 (sonnet)" || r.Notes != "missing error check" {
+		t.Errorf("second review = %+v", r)
+	}
+	if issues := prompter.Changesets[0].Issues; len(issues) != 1 || issues[0] != "task-001: a/a.go [low] typo" {
+		t.Errorf("changeset issues = %v", issues)
+	}
+}
+
+func TestValidatorQuorumUnanimousBlocks(t *testing.T) {
+	taskStore, prompter := runQuorumSession(t, config.QuorumUnanimous)
+
+	task := taskStore.FindTask("task-001")
+	if task.Result.Status != "fail" {
+		t.Errorf("result status = %q, want fail", task.Result.Status)
+	}
+	if !strings.Contains(task.Result.Notes, "unanimous quorum: 2 of 3 validators passed") {
+		t.Errorf("notes = %q", task.Result.Notes)
+	}
+	if len(prompter.Changesets) != 0 {
+		t.Errorf("failed task should not reach changeset review, got %d", len(prompter.Changesets))
+	}
+}
+
+func TestValidationFailureRequeuesWithIssues(t *testing.T) {
+	cfg := testOrchestratorConfig(t)
+	cfg.Limits.MaxWaveCycles = 1
+
+	spawner := &agent.MockSpawner{
+		PlannerResult: &agent.MockResult{
+			Output: `{"tasks":[{"id":"task-001","title":"Test","description":"desc","priority":1,"file_locks":["a/"]}]}`,
+		},
+		WorkerResults: map[string]agent.MockResult{
+			"task-001": {Output: `{"result":"done"}`},
+		},
+		ValidatorResults: map[string]agent.MockResult{
+			"task-001": {Output: `{"status":"fail","notes":"not done","issues":[
+				{"file":"a/a.go","line":3,"severity":"high","category":"tests","message":"no tests"},
+				"unclear naming"]}`},
+		},
+	}
+	prompter := &ui.ScriptedPrompter{
+		PlanDecisions:    []ui.PlanDecision{ui.PlanApprove},
+		SessionDecisions: []ui.SessionDecision{ui.SessionStop},
+	}
+
+	taskStore := tasks.NewTaskStore(cfg.Project.TasksFile)
+	orch := New(cfg, spawner, prompter, taskStore, nil)
+	if err := orch.Run(context.Background(), "Test task"); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	task := taskStore.FindTask("task-001")
+	if task.Status != tasks.StatusPending || task.RetryCount != 1 {
+		t.Fatalf("task = %s (retries %d), want requeued", task.Status, task.RetryCount)
+	}
+	last := task.History[len(task.History)-1]
+	if last.Result != "validation_failed" || len(last.Issues) != 2 || last.Issues[0].File != "a/a.go" {
+		t.Errorf("history entry = %+v, want validation_failed with 2 issues", last)
+	}
+	if len(task.Result.Issues) != 0 {
+		t.Errorf("requeued task kept %d superseded issues", len(task.Result.Issues))
+	}
+	if summary := orch.SessionSummary(); summary.Issues != 0 {
+		t.Errorf("summary counts superseded issues: %d %v", summary.Issues, summary.IssueSeverity)
+	}
+}
@@ -0,0 +1,84 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kylegalloway/blueflame/internal/agent"
+	"github.com/kylegalloway/blueflame/internal/config"
+)
+
+// errPlannerRateLimited reports that planning stopped at a rate limit
+// recorded with noteRateLimit; the session pauses and plans again.
+var errPlannerRateLimited = errors.New("planner rate limited")
+
+// noteRateLimit records that taskID's agent hit a limit. When several
+// agents hit limits in one wave, the latest reset wins.
+func (o *Orchestrator) noteRateLimit(taskID string, rl *agent.RateLimit) {
+	if rl == nil {
+		rl = &agent.RateLimit{}
+	}
+	o.ui.Warn(fmt.Sprintf("%s: rate limited (%s); will retry after the limit resets", taskID, rl.Message))
+	if o.rateLimit == nil || rl.ResetAt.After(o.rateLimit.ResetAt) {
+		o.rateLimit = rl
+	}
+}
+
+// pauseForRateLimit waits until the pending rate limit resets. The state
+// file records the reset time so an interrupted or exited session resumes
+// with the pause. It returns ErrRateLimited when the policy is to exit, the
+// wait exceeds max_wait, or the session has paused max_pauses times in a
+// row.
+func (o *Orchestrator) pauseForRateLimit(ctx context.Context) error {
+	rl := o.rateLimit
+	cfg := o.config.Limits.RateLimit
+	now := time.Now()
+	until := rl.ResetAt
+	if until.IsZero() {
+		until = now.Add(cfg.DefaultWait)
+	}
+	wait := until.Sub(now)
+
+	o.state.Phase = "rate_limited"
+	o.state.RateLimitedUntil = &until
+	o.persistState()
+	if err := o.taskStore.Save(); err != nil {
+		o.ui.Warn(fmt.Sprintf("save tasks before rate-limit pause: %v", err))
+	}
+
+	if cfg.OnLimit == config.RateLimitExit || (cfg.MaxWait > 0 && wait > cfg.MaxWait) {
+		return fmt.Errorf("%w: limit resets at %s", ErrRateLimited, until.Format("Jan 2 15:04 MST"))
+	}
+	if cfg.MaxPauses > 0 && o.rateLimitPauses >= cfg.MaxPauses {
+		return fmt.Errorf("%w: still limited after %d pauses in a row", ErrRateLimited, o.rateLimitPauses)
+	}
+	o.rateLimitPauses++
+
+	for wait > 0 {
+		o.ui.Info(fmt.Sprintf("Rate limited; resuming in %v", wait.Round(time.Second)))
+		step := min(wait, time.Minute)
+		if err := o.sleep(ctx, step); err != nil {
+			return err
+		}
+		wait -= step
+	}
+
+	o.rateLimit = nil
+	o.state.RateLimitedUntil = nil
+	o.persistState()
+	return nil
+}
+
+// sleepContext sleeps for d or until ctx is cancelled.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,80 @@
+package orchestrator
+
+import (
+	"slices"
+	"time"
+
+	"github.com/kylegalloway/blueflame/internal/agent"
+	"github.com/kylegalloway/blueflame/internal/ledger"
+	"github.com/kylegalloway/blueflame/internal/ui"
+)
+
+// resourceSummary totals the ledger's measured resource usage by role and
+// recommends a development concurrency from the workers' usage. Runs
+// without measurements are skipped.
+func (o *Orchestrator) resourceSummary(entries []ledger.Entry) ([]ui.ResourceLine, *ui.ConcurrencyRecommendation) {
+	byRole := make(map[string]*ui.ResourceLine)
+	var workerCPU, workerWall time.Duration
+	for _, e := range entries {
+		if e.PeakMemoryBytes == 0 && e.CPUMS == 0 {
+			continue
+		}
+		line := byRole[e.Role]
+		if line == nil {
+			line = &ui.ResourceLine{Role: e.Role}
+			byRole[e.Role] = line
+		}
+		line.Runs++
+		line.PeakMemoryBytes = max(line.PeakMemoryBytes, e.PeakMemoryBytes)
+		line.CPUTime += time.Duration(e.CPUMS) * time.Millisecond
+		line.BlockReadBytes += e.BlockReadBytes
+		line.BlockWriteBytes += e.BlockWriteBytes
+		if e.Role == agent.RoleWorker {
+			workerCPU += time.Duration(e.CPUMS) * time.Millisecond
+			workerWall += time.Duration(e.DurationMS) * time.Millisecond
+		}
+	}
+	if len(byRole) == 0 {
+		return nil, nil
+	}
+
+	workers := byRole[agent.RoleWorker]
+	var lines []ui.ResourceLine
+	for _, role := range agent.Roles {
+		if line := byRole[role]; line != nil {
+			lines = append(lines, *line)
+			delete(byRole, role)
+		}
+	}
+	var others []string
+	for role := range byRole {
+		others = append(others, role)
+	}
+	slices.Sort(others)
+	for _, role := range others {
+		lines = append(lines, *byRole[role])
+	}
+
+	if workers == nil {
+		return lines, nil
+	}
+	var avgCores float64
+	if workerWall > 0 {
+		avgCores = workerCPU.Seconds() / workerWall.Seconds()
+	}
+	host := o.hostResources
+	if host == nil {
+		host = agent.HostResources
+	}
+	ramMB, cpus := host()
+	advice, ok := agent.RecommendConcurrency(workers.PeakMemoryBytes, avgCores, ramMB, cpus)
+	if !ok {
+		return lines, nil
+	}
+	return lines, &ui.ConcurrencyRecommendation{
+		Configured:       o.config.Concurrency.Development,
+		Development:      advice.Development,
+		MinRAMPerAgentMB: advice.MinRAMPerAgentMB,
+		LimitedBy:        advice.LimitedBy,
+	}
+}
@@ -0,0 +1,373 @@
+// Package policy evaluates Blue Flame's permission rules. A policy is an
+// ordered list of rules, each matching on an agent's role, the tool it
+// calls, the path or Bash command it acts on, and its task; the first rule
+// that matches a call decides it. The watcher hook, PostCheck and the
+// spawner's tool flags all read the same compiled policy, so they can't
+// disagree.
+package policy
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/kylegalloway/blueflame/internal/config"
+)
+
+// Effect is what a matching rule does with a tool call.
+type Effect string
+
+const (
+	Allow Effect = "allow"
+	Deny  Effect = "deny"
+	// Ask needs a human's approval before the call runs.
+	Ask Effect = "ask"
+)
+
+// NoMatch is the rule reported when no rule matches a call, which is
+// denied.
+const NoMatch = "no_matching_rule"
+
+// WriteTools are the tools that modify the files they name.
+var WriteTools = []string{"Write", "Edit", "MultiEdit", "NotebookEdit"}
+
+// knownTools are Claude's built-in tools, considered for the spawner's
+// tool flags alongside any tool a rule names.
+var knownTools = []string{"Read", "Write", "Edit", "Glob", "Grep", "Bash", "WebFetch", "WebSearch", "NotebookEdit", "Task"}
+
+// Rule is one compiled rule. Empty conditions match anything; a rule with
+// Paths or Commands only matches calls that have a path or command, except
+// that a deny or ask rule with Paths also matches a write tool call with no
+// path.
+type Rule struct {
+	Name   string   `json:"name"`
+	Effect Effect   `json:"effect"`
+	Roles  []string `json:"roles,omitempty"`
+	Tools  []string `json:"tools,omitempty"`
+	// Paths are globs (see MatchPath). With OutsidePaths the rule matches
+	// a path that matches none of them.
+	Paths        []string `json:"paths,omitempty"`
+	OutsidePaths bool     `json:"outside_paths,omitempty"`
+	// Commands are regular expressions for Bash commands. With
+	// OutsideCommands the rule matches a command that matches none of them.
+	Commands        []string `json:"commands,omitempty"`
+	OutsideCommands bool     `json:"outside_commands,omitempty"`
+	// Tasks are task ID globs.
+	Tasks  []string `json:"tasks,omitempty"`
+	Reason string   `json:"reason,omitempty"`
+}
+
+// String describes the rule's conditions, as in "deny tools Bash, commands
+// not matching ^make(\s|$)".
+func (r Rule) String() string {
+	conds := []string{string(r.Effect)}
+	list := func(label string, values []string, outside bool) {
+		if len(values) == 0 && !outside {
+			return
+		}
+		if outside {
+			label += " not matching"
+		}
+		conds = append(conds, label+" "+strings.Join(values, " | "))
+	}
+	list("roles", r.Roles, false)
+	list("tools", r.Tools, false)
+	list("tasks", r.Tasks, false)
+	list("paths", r.Paths, r.OutsidePaths)
+	list("commands", r.Commands, r.OutsideCommands)
+	if len(conds) == 1 {
+		return conds[0] + " everything"
+	}
+	return conds[0] + " " + strings.Join(conds[1:], ", ")
+}
+
+// conditional reports whether the rule depends on a call's path or
+// command, not just who makes it and with which tool.
+func (r Rule) conditional() bool {
+	return len(r.Paths) > 0 || len(r.Commands) > 0 || r.OutsidePaths || r.OutsideCommands
+}
+
+// Policy is an ordered set of rules.
+type Policy struct {
+	Rules []Rule `json:"rules"`
+
+	commands map[string]*regexp.Regexp
+}
+
+// Request is one tool call to judge.
+type Request struct {
+	Role   string
+	Tool   string
+	TaskID string
+	// Path is slash-separated and relative to the agent's worktree.
+	Path    string
+	Command string
+}
+
+// Decision is the outcome of evaluating a request.
+type Decision struct {
+	Effect Effect
+	Rule   string
+	Reason string
+}
+
+// New compiles rules, checking their patterns.
+func New(rules []Rule) (*Policy, error) {
+	p := &Policy{Rules: rules, commands: make(map[string]*regexp.Regexp)}
+	for _, r := range rules {
+		switch r.Effect {
+		case Allow, Deny, Ask:
+		default:
+			return nil, fmt.Errorf("rule %s: unknown effect %q", r.Name, r.Effect)
+		}
+		for _, c := range r.Commands {
+			re, err := regexp.Compile(c)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: command %q: %w", r.Name, c, err)
+			}
+			p.commands[c] = re
+		}
+		for _, g := range append(slices.Clone(r.Paths), r.Tasks...) {
+			if err := checkGlob(g); err != nil {
+				return nil, fmt.Errorf("rule %s: %w", r.Name, err)
+			}
+		}
+	}
+	return p, nil
+}
+
+// FromConfig converts the policy.rules section of blueflame.yaml. Rules
+// without a name are named after their position.
+func FromConfig(rules []config.PolicyRule) []Rule {
+	out := make([]Rule, 0, len(rules))
+	for i, r := range rules {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("policy.rules[%d]", i)
+		}
+		out = append(out, Rule{
+			Name:     name,
+			Effect:   Effect(r.Effect),
+			Roles:    r.Roles,
+			Tools:    r.Tools,
+			Paths:    r.Paths,
+			Commands: r.Commands,
+			Tasks:    r.Tasks,
+			Reason:   r.Reason,
+		})
+	}
+	return out
+}
+
+// Evaluate returns the decision of the first rule matching req, or a
+// denial when none does.
+func (p *Policy) Evaluate(req Request) Decision {
+	i := p.Match(req)
+	if i < 0 {
+		return Decision{Effect: Deny, Rule: NoMatch, Reason: "no permission rule allows this"}
+	}
+	r := p.Rules[i]
+	return Decision{Effect: r.Effect, Rule: r.Name, Reason: r.Reason}
+}
+
+// Match returns the index of the first rule matching req, or -1.
+func (p *Policy) Match(req Request) int {
+	for i, r := range p.Rules {
+		if p.matches(r, req) {
+			return i
+		}
+	}
+	return -1
+}
+
+// EvaluateChange judges a file an agent changed, by whatever means. Only
+// rules about paths that apply to writing decide it, and a change none of
+// them covers is allowed; req.Tool is ignored.
+func (p *Policy) EvaluateChange(req Request) Decision {
+	for _, r := range p.Rules {
+		if len(r.Paths) == 0 && !r.OutsidePaths {
+			continue
+		}
+		if len(r.Tools) > 0 && !slices.ContainsFunc(r.Tools, func(t string) bool { return slices.Contains(WriteTools, t) }) {
+			continue
+		}
+		if p.matchesTarget(r, req) {
+			return Decision{Effect: r.Effect, Rule: r.Name, Reason: r.Reason}
+		}
+	}
+	return Decision{Effect: Allow}
+}
+
+func (p *Policy) matches(r Rule, req Request) bool {
+	return r.matchesTool(req.Tool) && p.matchesTarget(r, req)
+}
+
+// matchesTarget checks every condition but the tool.
+func (p *Policy) matchesTarget(r Rule, req Request) bool {
+	if !r.matchesAgent(req.Role, req.TaskID) {
+		return false
+	}
+	if len(r.Paths) > 0 || r.OutsidePaths {
+		if req.Path == "" {
+			// A write whose path can't be seen might be writing anywhere,
+			// so it meets every restriction on paths but no permission.
+			if r.Effect == Allow || !slices.Contains(WriteTools, req.Tool) {
+				return false
+			}
+		} else if MatchAny(r.Paths, req.Path) == r.OutsidePaths {
+			return false
+		}
+	}
+	if len(r.Commands) > 0 || r.OutsideCommands {
+		if req.Command == "" || p.matchesCommand(r, req.Command) == r.OutsideCommands {
+			return false
+		}
+	}
+	return true
+}
+
+func (r Rule) matchesAgent(role, taskID string) bool {
+	if len(r.Roles) > 0 && !slices.Contains(r.Roles, role) {
+		return false
+	}
+	if len(r.Tasks) > 0 && !slices.ContainsFunc(r.Tasks, func(g string) bool {
+		ok, _ := path.Match(g, taskID)
+		return ok
+	}) {
+		return false
+	}
+	return true
+}
+
+func (r Rule) matchesTool(tool string) bool {
+	return len(r.Tools) == 0 || slices.Contains(r.Tools, tool)
+}
+
+func (p *Policy) matchesCommand(r Rule, command string) bool {
+	for _, c := range r.Commands {
+		re := p.commands[c]
+		if re == nil {
+			// Not built by New; a bad pattern matches nothing.
+			var err error
+			if re, err = regexp.Compile(c); err != nil {
+				continue
+			}
+		}
+		if re.MatchString(command) {
+			return true
+		}
+	}
+	return false
+}
+
+// Tools returns the tools the policy may let role use on task, for
+// --allowed-tools, and those it always denies by name, for
+// --disallowed-tools. A tool whose first applicable rule depends on the
+// path or command is allowed unless that rule denies; the hook then
+// decides each call.
+func (p *Policy) Tools(role, taskID string) (allowed, disallowed []string) {
+	var candidates []string
+	for _, r := range p.Rules {
+		for _, t := range r.Tools {
+			if !slices.Contains(candidates, t) {
+				candidates = append(candidates, t)
+			}
+		}
+	}
+	for _, t := range knownTools {
+		if !slices.Contains(candidates, t) {
+			candidates = append(candidates, t)
+		}
+	}
+
+	for _, tool := range candidates {
+		for _, r := range p.Rules {
+			if !r.matchesAgent(role, taskID) || !r.matchesTool(tool) {
+				continue
+			}
+			if r.conditional() {
+				if r.Effect == Deny {
+					continue
+				}
+				allowed = append(allowed, tool)
+			} else if r.Effect != Deny {
+				allowed = append(allowed, tool)
+			} else if slices.Contains(r.Tools, tool) {
+				disallowed = append(disallowed, tool)
+			}
+			break
+		}
+	}
+	return allowed, disallowed
+}
+
+// MatchPath reports whether the slash-separated name matches pattern. "**"
+// stands for any number of directories, other segments use path.Match
+// syntax, and a pattern without a slash matches the file name anywhere in
+// the tree.
+func MatchPath(pattern, name string) bool {
+	if matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/")) {
+		return true
+	}
+	return !strings.Contains(pattern, "/") && matchSegments([]string{pattern}, []string{path.Base(name)})
+}
+
+// MatchAny reports whether name matches any of the patterns.
+func MatchAny(patterns []string, name string) bool {
+	return slices.ContainsFunc(patterns, func(p string) bool { return MatchPath(p, name) })
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// checkGlob reports a malformed glob.
+func checkGlob(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("bad glob %q: %w", pattern, err)
+	}
+	return nil
+}
+
+// LockPatterns turns a task's file locks into path globs. A lock names a
+// file, or a directory and everything in it; "lock/**" covers both, since
+// "**" also matches nothing, and its slash anchors it at the worktree root.
+func LockPatterns(locks []string) []string {
+	out := make([]string, 0, len(locks))
+	for _, lock := range locks {
+		out = append(out, strings.TrimSuffix(lock, "/")+"/**")
+	}
+	return out
+}
+
+// shellOperators are the characters that end, chain, pipe, redirect or
+// substitute a shell command; "$" counts only before "(".
+const shellOperators = ";&|<>`\n\r"
+
+// CommandPrefix returns a regular expression matching command exactly or
+// followed by arguments, for allowlists of command prefixes. Arguments may
+// not contain shellOperators or "$(", so an allowed "go test" doesn't let
+// "go test ./... && rm -rf ~" through.
+func CommandPrefix(command string) string {
+	arg := `[^` + shellOperators + `$]|\$(?:$|[^(` + shellOperators + `])`
+	return `^` + regexp.QuoteMeta(strings.TrimSpace(command)) + `(?:[ \t](?:` + arg + `)*)?$`
+}
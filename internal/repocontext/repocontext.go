@@ -0,0 +1,351 @@
+package repocontext
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kylegalloway/blueflame/internal/tokens"
+)
+
+// maxFilesPerDir caps how many files the tree lists for one directory
+// before collapsing them into a count.
+const maxFilesPerDir = 12
+
+// Options bounds a repository context pack.
+type Options struct {
+	// MaxTokens is the approximate token budget for the whole pack.
+	MaxTokens int
+	// GitLogEntries is how many recent commits to include.
+	GitLogEntries int
+}
+
+// Section is one titled part of a context pack.
+type Section struct {
+	Title   string
+	Content string
+	// Truncated reports whether the content was cut to fit the budget.
+	Truncated bool
+}
+
+// Pack is a bounded summary of a repository for agent prompts.
+type Pack struct {
+	Sections []Section
+}
+
+// String renders the pack as markdown-style sections.
+func (p *Pack) String() string {
+	var b strings.Builder
+	for i, s := range p.Sections {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "## %s\n%s", s.Title, s.Content)
+	}
+	return b.String()
+}
+
+// Tokens returns the approximate token size of the rendered pack.
+func (p *Pack) Tokens() int {
+	return tokens.Estimate(p.String())
+}
+
+// Build assembles a repository context pack for the planner: the directory
+// tree (respecting .gitignore), top-level README and CLAUDE.md, recent
+// commits and, for Go modules, an exported-symbol index. The result is
+// trimmed to opts.MaxTokens.
+func Build(repoDir string, opts Options) (*Pack, error) {
+	files, err := ListFiles(repoDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sections in priority order; weights set each section's share of the budget.
+	var candidates []candidate
+	add := func(title, content string, weight int) {
+		if content = strings.TrimSpace(content); content != "" {
+			candidates = append(candidates, candidate{Section{Title: title, Content: content}, weight})
+		}
+	}
+
+	add("CLAUDE.md", readFirst(repoDir, "CLAUDE.md"), 2)
+	add("README", readFirst(repoDir, "README.md", "README", "README.rst", "README.txt"), 2)
+	add("Recent commits", gitLog(repoDir, opts.GitLogEntries), 1)
+	add("Directory tree", renderTree(files), 3)
+	if modPath := goModulePath(repoDir); modPath != "" {
+		add("Go packages (exported symbols)", goSymbolIndex(repoDir, modPath, files), 3)
+	}
+
+	return fit(candidates, opts.MaxTokens), nil
+}
+
+// candidate is a section awaiting budget allocation.
+type candidate struct {
+	Section
+	weight int
+}
+
+// fit trims candidates, in priority order, to maxTokens. Each candidate gets
+// a share of the remaining budget proportional to its weight; whatever a
+// candidate doesn't use rolls forward to the ones after it. A zero budget
+// keeps everything.
+func fit(candidates []candidate, maxTokens int) *Pack {
+	pack := &Pack{}
+	remaining := maxTokens
+	totalWeight := 0
+	for _, c := range candidates {
+		totalWeight += c.weight
+	}
+	for _, c := range candidates {
+		s := c.Section
+		if maxTokens > 0 {
+			share := remaining * c.weight / totalWeight
+			if share <= 0 {
+				s.Content = ""
+			} else if tokens.Estimate(s.Content) > share {
+				s.Content = tokens.Truncate(share, s.Content)
+				s.Truncated = true
+			}
+			remaining -= tokens.Estimate(s.Content)
+			totalWeight -= c.weight
+		}
+		if s.Content != "" {
+			pack.Sections = append(pack.Sections, s)
+		}
+	}
+	return pack
+}
+
+// ListFiles returns repo-relative file paths, honoring .gitignore when the
+// directory is a git repository.
+func ListFiles(repoDir string) ([]string, error) {
+	cmd := exec.Command("git", "ls-files", "--cached", "--others", "--exclude-standard")
+	cmd.Dir = repoDir
+	if output, err := cmd.Output(); err == nil {
+		var files []string
+		for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+			if line != "" {
+				files = append(files, line)
+			}
+		}
+		sort.Strings(files)
+		return files, nil
+	}
+
+	// Not a git repo: walk the tree, skipping hidden directories.
+	var files []string
+	err := filepath.WalkDir(repoDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && p != repoDir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() {
+			rel, _ := filepath.Rel(repoDir, p)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk repo: %w", err)
+	}
+	return files, nil
+}
+
+// readFirst returns the contents of the first existing file among names.
+func readFirst(repoDir string, names ...string) string {
+	for _, name := range names {
+		if data, err := os.ReadFile(filepath.Join(repoDir, name)); err == nil {
+			return string(data)
+		}
+	}
+	return ""
+}
+
+func gitLog(repoDir string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	cmd := exec.Command("git", "log", "--oneline", "--no-decorate", fmt.Sprintf("-n%d", n))
+	cmd.Dir = repoDir
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return string(output)
+}
+
+// renderTree renders files as an indented directory tree. Directories with
+// many files list a count instead of every name.
+func renderTree(files []string) string {
+	dirFiles := make(map[string][]string)
+	subdirs := make(map[string]map[string]bool)
+	for _, f := range files {
+		dir, name := path.Split(f)
+		dir = strings.TrimSuffix(dir, "/")
+		dirFiles[dir] = append(dirFiles[dir], name)
+		for dir != "" {
+			parent := path.Dir(dir)
+			if parent == "." {
+				parent = ""
+			}
+			if subdirs[parent] == nil {
+				subdirs[parent] = make(map[string]bool)
+			}
+			subdirs[parent][dir] = true
+			dir = parent
+		}
+	}
+
+	var b strings.Builder
+	var walk func(dir string, depth int)
+	walk = func(dir string, depth int) {
+		indent := strings.Repeat("  ", depth)
+		var dirs []string
+		for d := range subdirs[dir] {
+			dirs = append(dirs, d)
+		}
+		sort.Strings(dirs)
+		for _, d := range dirs {
+			fmt.Fprintf(&b, "%s%s/\n", indent, path.Base(d))
+			walk(d, depth+1)
+		}
+		names := dirFiles[dir]
+		if len(names) > maxFilesPerDir {
+			fmt.Fprintf(&b, "%s(%d files)\n", indent, len(names))
+			return
+		}
+		for _, n := range names {
+			fmt.Fprintf(&b, "%s%s\n", indent, n)
+		}
+	}
+	walk("", 0)
+	return b.String()
+}
+
+// goModulePath returns the module path from go.mod, or "" if there is none.
+func goModulePath(repoDir string) string {
+	f, err := os.Open(filepath.Join(repoDir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "module ") {
+			return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module ")), `"`)
+		}
+	}
+	return ""
+}
+
+// goSymbolIndex lists each Go package with its exported declarations.
+func goSymbolIndex(repoDir, modPath string, files []string) string {
+	byDir := make(map[string][]string)
+	for _, f := range files {
+		if !strings.HasSuffix(f, ".go") || strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		if strings.HasPrefix(f, "vendor/") || strings.Contains(f, "testdata/") {
+			continue
+		}
+		byDir[path.Dir(f)] = append(byDir[path.Dir(f)], f)
+	}
+
+	var dirs []string
+	for d := range byDir {
+		dirs = append(dirs, d)
+	}
+	sort.Strings(dirs)
+
+	var b strings.Builder
+	fset := token.NewFileSet()
+	for _, dir := range dirs {
+		var pkgName string
+		var symbols []string
+		for _, f := range byDir[dir] {
+			file, err := parser.ParseFile(fset, filepath.Join(repoDir, f), nil, parser.SkipObjectResolution)
+			if err != nil {
+				continue
+			}
+			pkgName = file.Name.Name
+			symbols = append(symbols, exportedSymbols(file)...)
+		}
+		if pkgName == "" {
+			continue
+		}
+		importPath := modPath
+		if dir != "." {
+			importPath = modPath + "/" + dir
+		}
+		sort.Strings(symbols)
+		fmt.Fprintf(&b, "%s (package %s)", importPath, pkgName)
+		if len(symbols) > 0 {
+			fmt.Fprintf(&b, ": %s", strings.Join(symbols, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func exportedSymbols(file *ast.File) []string {
+	var symbols []string
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if !d.Name.IsExported() {
+				continue
+			}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				recv := receiverName(d.Recv.List[0].Type)
+				if !ast.IsExported(recv) {
+					continue
+				}
+				symbols = append(symbols, recv+"."+d.Name.Name)
+			} else {
+				symbols = append(symbols, d.Name.Name)
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if s.Name.IsExported() {
+						symbols = append(symbols, s.Name.Name)
+					}
+				case *ast.ValueSpec:
+					for _, n := range s.Names {
+						if n.IsExported() {
+							symbols = append(symbols, n.Name)
+						}
+					}
+				}
+			}
+		}
+	}
+	return symbols
+}
+
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(t.X)
+	case *ast.IndexExpr:
+		return receiverName(t.X)
+	case *ast.IndexListExpr:
+		return receiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
@@ -0,0 +1,212 @@
+package repocontext
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	p := filepath.Join(root, rel)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func initRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeFile(t, dir, "go.mod", "module example.com/demo\n\ngo 1.22\n")
+	writeFile(t, dir, "README.md", "# Demo\nA demo project.\n")
+	writeFile(t, dir, "CLAUDE.md", "Run go test ./... before committing.\n")
+	writeFile(t, dir, ".gitignore", "build/\n")
+	writeFile(t, dir, "build/out.bin", "binary")
+	writeFile(t, dir, "pkg/auth/auth.go", `package auth
+
+type Token struct{}
+
+func (t *Token) Valid() bool { return true }
+
+func Login() {}
+
+func helper() {}
+
+const MaxAge = 10
+`)
+	writeFile(t, dir, "pkg/auth/auth_test.go", "package auth\n\nfunc TestOnly() {}\n")
+
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "Add auth package"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	return dir
+}
+
+func TestBuildIncludesSections(t *testing.T) {
+	dir := initRepo(t)
+
+	pack, err := Build(dir, Options{MaxTokens: 4000, GitLogEntries: 5})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	out := pack.String()
+
+	for _, want := range []string{
+		"## CLAUDE.md\nRun go test",
+		"## README\n# Demo",
+		"Add auth package",
+		"pkg/\n  auth/\n    auth.go",
+		"example.com/demo/pkg/auth (package auth): Login, MaxAge, Token, Token.Valid",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("pack missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "out.bin") {
+		t.Error("pack should respect .gitignore")
+	}
+	if strings.Contains(out, "helper") || strings.Contains(out, "TestOnly") {
+		t.Error("symbol index should list only exported, non-test symbols")
+	}
+}
+
+func TestBuildRespectsTokenBudget(t *testing.T) {
+	dir := initRepo(t)
+	writeFile(t, dir, "README.md", strings.Repeat("Lots of documentation here.\n", 2000))
+
+	pack, err := Build(dir, Options{MaxTokens: 500, GitLogEntries: 5})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if got := pack.Tokens(); got > 550 {
+		t.Errorf("pack is ~%d tokens, want about 500", got)
+	}
+
+	var readme *Section
+	for i := range pack.Sections {
+		if pack.Sections[i].Title == "README" {
+			readme = &pack.Sections[i]
+		}
+	}
+	if readme == nil || !readme.Truncated {
+		t.Fatal("expected README to be truncated")
+	}
+	// Small sections keep their content even when a large one is cut.
+	if !strings.Contains(pack.String(), "Run go test") {
+		t.Error("CLAUDE.md should survive trimming")
+	}
+}
+
+func TestBuildWithoutGit(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "main.py", "print('hi')\n")
+	writeFile(t, dir, ".cache/junk", "x")
+
+	pack, err := Build(dir, Options{MaxTokens: 1000, GitLogEntries: 5})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	out := pack.String()
+	if !strings.Contains(out, "main.py") {
+		t.Errorf("tree missing main.py:\n%s", out)
+	}
+	if strings.Contains(out, "junk") || strings.Contains(out, "Recent commits") {
+		t.Errorf("unexpected content:\n%s", out)
+	}
+}
+
+func TestBuildTaskGoRelations(t *testing.T) {
+	dir := initRepo(t)
+	writeFile(t, dir, "pkg/store/store.go", "package store\n\nfunc Save() {}\n")
+	writeFile(t, dir, "pkg/auth/session.go", `package auth
+
+import "example.com/demo/pkg/store"
+
+func Persist() { store.Save() }
+`)
+	writeFile(t, dir, "cmd/demo/main.go", `package main
+
+import "example.com/demo/pkg/auth"
+
+func main() { auth.Login() }
+`)
+	writeFile(t, dir, "pkg/unrelated/x.go", "package unrelated\n")
+
+	pack, err := BuildTask(dir, []string{"pkg/auth/"}, TaskOptions{MaxTokens: 4000})
+	if err != nil {
+		t.Fatalf("BuildTask: %v", err)
+	}
+
+	titles := make(map[string]bool)
+	for _, s := range pack.Sections {
+		titles[s.Title] = true
+	}
+	for _, want := range []string{
+		"pkg/auth/auth.go (owned)",
+		"pkg/auth/session.go (owned)",
+		"pkg/auth/auth_test.go (test)",
+		"pkg/store/store.go (imported)",
+		"cmd/demo/main.go (importer)",
+	} {
+		if !titles[want] {
+			t.Errorf("missing section %q; got %v", want, titles)
+		}
+	}
+	if titles["pkg/unrelated/x.go (importer)"] || titles["pkg/unrelated/x.go (imported)"] {
+		t.Error("unrelated package should not be included")
+	}
+	if pack.Sections[0].Title != "pkg/auth/auth.go (owned)" {
+		t.Errorf("owned files should come first, got %q", pack.Sections[0].Title)
+	}
+}
+
+func TestBuildTaskHeuristicsAndBudget(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "app/auth.py", strings.Repeat("def login(): pass\n", 500))
+	writeFile(t, dir, "app/db.py", "def connect(): pass\n")
+	writeFile(t, dir, "tests/test_auth.py", "def test_login(): pass\n")
+	writeFile(t, dir, "tests/test_db.py", "def test_connect(): pass\n")
+
+	pack, err := BuildTask(dir, []string{"app/auth.py"}, TaskOptions{MaxTokens: 400})
+	if err != nil {
+		t.Fatalf("BuildTask: %v", err)
+	}
+	out := pack.String()
+	for _, want := range []string{"app/auth.py (owned)", "tests/test_auth.py (test)", "app/db.py (sibling)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "test_db.py") {
+		t.Error("tests for other files should not be included")
+	}
+	if !pack.Sections[0].Truncated {
+		t.Error("large owned file should be truncated")
+	}
+	if got := pack.Tokens(); got > 450 {
+		t.Errorf("pack is ~%d tokens, want about 400", got)
+	}
+}
+
+func TestBuildTaskNoLocks(t *testing.T) {
+	pack, err := BuildTask(t.TempDir(), nil, TaskOptions{MaxTokens: 100})
+	if err != nil {
+		t.Fatalf("BuildTask: %v", err)
+	}
+	if len(pack.Sections) != 0 {
+		t.Errorf("expected empty pack, got %d sections", len(pack.Sections))
+	}
+}
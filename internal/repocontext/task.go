@@ -0,0 +1,278 @@
+package repocontext
+
+import (
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxExcerptFileBytes skips files too large to be worth pre-reading.
+const maxExcerptFileBytes = 256 * 1024
+
+// Relation labels describing why a file is in a task context pack.
+const (
+	RelationOwned    = "owned"
+	RelationTest     = "test"
+	RelationImported = "imported"
+	RelationImporter = "importer"
+	RelationSibling  = "sibling"
+)
+
+// relationWeights sets each relation's share of the budget; owned files
+// come first and get the most.
+var relationWeights = map[string]int{
+	RelationOwned:    4,
+	RelationTest:     2,
+	RelationImported: 1,
+	RelationImporter: 1,
+	RelationSibling:  1,
+}
+
+// TaskOptions bounds a per-task context pack.
+type TaskOptions struct {
+	// MaxTokens is the approximate token budget for all excerpts.
+	MaxTokens int
+}
+
+// BuildTask pre-reads the files a task owns (its file locks) plus related
+// files, and returns excerpts trimmed to opts.MaxTokens. For Go modules the
+// related files are the packages the owned files import, the packages that
+// import them, and their tests. Other repos use path heuristics: test files
+// named after an owned file, then sibling files in the same directory.
+func BuildTask(repoDir string, fileLocks []string, opts TaskOptions) (*Pack, error) {
+	if len(fileLocks) == 0 {
+		return &Pack{}, nil
+	}
+	files, err := ListFiles(repoDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var owned []string
+	for _, f := range files {
+		if matchesLock(f, fileLocks) {
+			owned = append(owned, f)
+		}
+	}
+
+	var related map[string]string
+	if modPath := goModulePath(repoDir); modPath != "" {
+		related = goRelatedFiles(repoDir, modPath, files, owned)
+	} else {
+		related = heuristicRelatedFiles(files, owned)
+	}
+
+	var candidates []candidate
+	add := func(f, relation string) {
+		content, ok := readExcerpt(filepath.Join(repoDir, f))
+		if !ok {
+			return
+		}
+		candidates = append(candidates, candidate{
+			Section{Title: fmt.Sprintf("%s (%s)", f, relation), Content: content},
+			relationWeights[relation],
+		})
+	}
+	for _, f := range owned {
+		// Owned tests are excerpted with the other tests, after the code.
+		if isTestFile(f) {
+			related[f] = RelationTest
+			continue
+		}
+		add(f, RelationOwned)
+	}
+	for _, relation := range []string{RelationTest, RelationImported, RelationImporter, RelationSibling} {
+		var group []string
+		for f, r := range related {
+			if r == relation {
+				group = append(group, f)
+			}
+		}
+		sort.Strings(group)
+		for _, f := range group {
+			add(f, relation)
+		}
+	}
+
+	return fit(candidates, opts.MaxTokens), nil
+}
+
+// matchesLock reports whether f falls under one of the task's file locks.
+// Locks are file paths, directories (with or without a trailing slash) or
+// globs.
+func matchesLock(f string, locks []string) bool {
+	for _, lock := range locks {
+		lock = strings.TrimPrefix(filepath.ToSlash(lock), "./")
+		dir := strings.TrimSuffix(lock, "/")
+		if f == lock || strings.HasPrefix(f, dir+"/") {
+			return true
+		}
+		if ok, _ := path.Match(lock, f); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// goRelatedFiles maps related files to their relation, using Go imports.
+func goRelatedFiles(repoDir, modPath string, files, owned []string) map[string]string {
+	related := make(map[string]string)
+	isOwned := make(map[string]bool, len(owned))
+	ownedDirs := make(map[string]bool)
+	for _, f := range owned {
+		isOwned[f] = true
+		if strings.HasSuffix(f, ".go") {
+			ownedDirs[path.Dir(f)] = true
+		}
+	}
+	pkgDir := func(importPath string) (string, bool) {
+		if importPath == modPath {
+			return ".", true
+		}
+		if rel, ok := strings.CutPrefix(importPath, modPath+"/"); ok {
+			return rel, true
+		}
+		return "", false
+	}
+
+	byDir := make(map[string][]string)
+	for _, f := range files {
+		if strings.HasSuffix(f, ".go") && !strings.HasPrefix(f, "vendor/") {
+			byDir[path.Dir(f)] = append(byDir[path.Dir(f)], f)
+		}
+	}
+	set := func(f, relation string) {
+		if !isOwned[f] && related[f] == "" {
+			related[f] = relation
+		}
+	}
+
+	// Tests for owned packages.
+	for dir := range ownedDirs {
+		for _, f := range byDir[dir] {
+			if strings.HasSuffix(f, "_test.go") {
+				set(f, RelationTest)
+			}
+		}
+	}
+
+	fset := token.NewFileSet()
+	imports := func(f string) []string {
+		file, err := parser.ParseFile(fset, filepath.Join(repoDir, f), nil, parser.ImportsOnly)
+		if err != nil {
+			return nil
+		}
+		var dirs []string
+		for _, imp := range file.Imports {
+			p, err := strconv.Unquote(imp.Path.Value)
+			if err != nil {
+				continue
+			}
+			if dir, ok := pkgDir(p); ok {
+				dirs = append(dirs, dir)
+			}
+		}
+		return dirs
+	}
+
+	// Packages the owned files import.
+	for _, f := range owned {
+		if !strings.HasSuffix(f, ".go") || strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		for _, dir := range imports(f) {
+			if ownedDirs[dir] {
+				continue
+			}
+			for _, g := range byDir[dir] {
+				if !strings.HasSuffix(g, "_test.go") {
+					set(g, RelationImported)
+				}
+			}
+		}
+	}
+
+	// Files that import an owned package.
+	for _, f := range files {
+		if !strings.HasSuffix(f, ".go") || strings.HasSuffix(f, "_test.go") || isOwned[f] {
+			continue
+		}
+		for _, dir := range imports(f) {
+			if ownedDirs[dir] {
+				set(f, RelationImporter)
+				break
+			}
+		}
+	}
+	return related
+}
+
+// heuristicRelatedFiles maps related files to their relation for non-Go
+// repos: tests named after an owned file, then siblings of owned files.
+func heuristicRelatedFiles(files, owned []string) map[string]string {
+	related := make(map[string]string)
+	isOwned := make(map[string]bool, len(owned))
+	stems := make(map[string]bool)
+	ownedDirs := make(map[string]bool)
+	for _, f := range owned {
+		isOwned[f] = true
+		stems[fileStem(f)] = true
+		ownedDirs[path.Dir(f)] = true
+	}
+	for _, f := range files {
+		if isOwned[f] {
+			continue
+		}
+		if isTestFile(f) && stems[testSubject(f)] {
+			related[f] = RelationTest
+		} else if ownedDirs[path.Dir(f)] {
+			related[f] = RelationSibling
+		}
+	}
+	return related
+}
+
+// fileStem returns the base name without its extension.
+func fileStem(f string) string {
+	base := path.Base(f)
+	if i := strings.IndexByte(base, '.'); i > 0 {
+		return base[:i]
+	}
+	return base
+}
+
+func isTestFile(f string) bool {
+	base := path.Base(f)
+	stem := fileStem(f)
+	return strings.HasPrefix(stem, "test_") || strings.HasSuffix(stem, "_test") ||
+		strings.Contains(base, ".test.") || strings.Contains(base, ".spec.")
+}
+
+// testSubject returns the stem of the file a test file is named after,
+// e.g. "auth" for test_auth.py, auth_test.go or auth.spec.ts.
+func testSubject(f string) string {
+	stem := fileStem(f)
+	stem = strings.TrimPrefix(stem, "test_")
+	return strings.TrimSuffix(stem, "_test")
+}
+
+// readExcerpt reads a text file for inclusion, skipping large or binary files.
+func readExcerpt(p string) (string, bool) {
+	info, err := os.Stat(p)
+	if err != nil || info.Size() > maxExcerptFileBytes {
+		return "", false
+	}
+	data, err := os.ReadFile(p)
+	if err != nil || bytes.IndexByte(data, 0) >= 0 {
+		return "", false
+	}
+	content := strings.TrimSpace(string(data))
+	return content, content != ""
+}
@@ -17,6 +17,10 @@ type OrchestratorState struct {
 	SessionTokens int     `json:"session_tokens"`
 	StartTime    time.Time `json:"start_time"`
 	LastSave     time.Time `json:"last_save"`
+
+	// RateLimitedUntil is set while the session is paused for a rate or
+	// usage limit.
+	RateLimitedUntil *time.Time `json:"rate_limited_until,omitempty"`
 }
 
 // Manager handles crash recovery state persistence.
@@ -0,0 +1,67 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Issue is one problem a validator found in a task's changes.
+type Issue struct {
+	File     string `yaml:"file,omitempty" json:"file,omitempty"`
+	Line     int    `yaml:"line,omitempty" json:"line,omitempty"`
+	Severity string `yaml:"severity,omitempty" json:"severity,omitempty"`
+	Category string `yaml:"category,omitempty" json:"category,omitempty"`
+	Message  string `yaml:"message" json:"message"`
+}
+
+// UnmarshalJSON accepts either an issue object or a plain string, which
+// older validator prompts produce, taken as the message.
+func (i *Issue) UnmarshalJSON(data []byte) error {
+	var msg string
+	if err := json.Unmarshal(data, &msg); err == nil {
+		*i = Issue{Message: msg}
+		return nil
+	}
+	type plain Issue
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return fmt.Errorf("parse issue: %w", err)
+	}
+	*i = Issue(p)
+	return nil
+}
+
+// String renders the issue as "file:line [severity/category] message",
+// leaving out parts that are unset.
+func (i Issue) String() string {
+	var b strings.Builder
+	if i.File != "" {
+		b.WriteString(i.File)
+		if i.Line > 0 {
+			fmt.Fprintf(&b, ":%d", i.Line)
+		}
+		b.WriteString(" ")
+	}
+	if tags := strings.Trim(i.Severity+"/"+i.Category, "/"); tags != "" {
+		fmt.Fprintf(&b, "[%s] ", tags)
+	}
+	b.WriteString(i.Message)
+	return b.String()
+}
+
+// MergeIssues combines issue lists, dropping duplicates reported by more
+// than one validator.
+func MergeIssues(lists ...[]Issue) []Issue {
+	seen := make(map[Issue]bool)
+	var out []Issue
+	for _, list := range lists {
+		for _, i := range list {
+			if !seen[i] {
+				seen[i] = true
+				out = append(out, i)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,47 @@
+package tasks
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestIssueUnmarshalJSON(t *testing.T) {
+	var issues []Issue
+	data := `["missing error handling", {"file":"auth.go","line":12,"severity":"high","category":"correctness","message":"nil deref"}]`
+	if err := json.Unmarshal([]byte(data), &issues); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("len(issues) = %d, want 2", len(issues))
+	}
+	if issues[0].Message != "missing error handling" || issues[0].File != "" {
+		t.Errorf("string issue = %+v", issues[0])
+	}
+	if got := issues[1].String(); got != "auth.go:12 [high/correctness] nil deref" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestIssueString(t *testing.T) {
+	tests := []struct {
+		issue Issue
+		want  string
+	}{
+		{Issue{Message: "no tests"}, "no tests"},
+		{Issue{File: "a.go", Message: "unused"}, "a.go unused"},
+		{Issue{Category: "style", Message: "long line"}, "[style] long line"},
+	}
+	for _, tt := range tests {
+		if got := tt.issue.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestMergeIssues(t *testing.T) {
+	a := Issue{File: "a.go", Message: "x"}
+	b := Issue{File: "b.go", Message: "y"}
+	if got := MergeIssues([]Issue{a, b}, []Issue{b}); len(got) != 2 {
+		t.Errorf("MergeIssues = %v, want 2 issues", got)
+	}
+}
@@ -44,12 +44,71 @@ type Task struct {
 	RetryCount     int           `yaml:"retry_count"`
 	Result         TaskResult    `yaml:"result"`
 	History        []HistoryEntry `yaml:"history,omitempty"`
+
+	// Optional per-task overrides of the worker settings, bounded by
+	// limits.task_overrides.
+	Model         string        `yaml:"model,omitempty"`
+	BudgetUSD     float64       `yaml:"budget_usd,omitempty"`
+	BudgetTokens  int           `yaml:"budget_tokens,omitempty"`
+	Timeout       time.Duration `yaml:"timeout,omitempty"`
+	ExtraCommands []string      `yaml:"extra_allowed_commands,omitempty"`
+	ExtraTools    []string      `yaml:"extra_allowed_tools,omitempty"`
+
+	// Complexity is the planner's effort estimate. Route names the
+	// models.routing band it selected; clear it to re-route after editing
+	// the estimate.
+	Complexity *Complexity `yaml:"complexity,omitempty"`
+	Route      string      `yaml:"route,omitempty"`
+}
+
+// Complexity is a planner's estimate of how hard a task is.
+type Complexity struct {
+	Files int    `yaml:"files,omitempty" json:"files,omitempty"`
+	Risk  string `yaml:"risk,omitempty" json:"risk,omitempty"` // low, medium or high
+	Size  string `yaml:"size,omitempty" json:"size,omitempty"` // small, medium or large
+}
+
+func (c Complexity) String() string {
+	return fmt.Sprintf("%d file(s), risk %s, size %s", c.Files, orUnknown(c.Risk), orUnknown(c.Size))
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
+
+// HasOverrides reports whether any per-task override is set.
+func (t *Task) HasOverrides() bool {
+	return t.Model != "" || t.BudgetUSD > 0 || t.BudgetTokens > 0 || t.Timeout > 0 ||
+		len(t.ExtraCommands) > 0 || len(t.ExtraTools) > 0
 }
 
 // TaskResult holds validation results.
 type TaskResult struct {
-	Status string `yaml:"status,omitempty"`
-	Notes  string `yaml:"notes,omitempty"`
+	Status  string            `yaml:"status,omitempty"`
+	Notes   string            `yaml:"notes,omitempty"`
+	Issues  []Issue           `yaml:"issues,omitempty"`
+	Reviews []ValidatorReview `yaml:"reviews,omitempty"`
+}
+
+// ValidatorReview is one validator's verdict when a quorum reviews a task.
+type ValidatorReview struct {
+	Validator string `yaml:"validator"`
+	Model     string `yaml:"model,omitempty"`
+	Status    string `yaml:"status"`
+	Notes     string `yaml:"notes,omitempty"`
+}
+
+// Disagreement reports whether the reviewers reached different verdicts.
+func (r TaskResult) Disagreement() bool {
+	for _, rv := range r.Reviews {
+		if rv.Status != r.Reviews[0].Status {
+			return true
+		}
+	}
+	return false
 }
 
 // HistoryEntry records a prior attempt.
@@ -60,6 +119,8 @@ type HistoryEntry struct {
 	Result          string    `yaml:"result"`
 	Notes           string    `yaml:"notes"`
 	RejectionReason string    `yaml:"rejection_reason,omitempty"`
+	Issues          []Issue   `yaml:"issues,omitempty"`
+	Route           string    `yaml:"route,omitempty"`
 	CostUSD         float64   `yaml:"cost_usd"`
 	TokensUsed      int       `yaml:"tokens_used"`
 }
@@ -115,6 +176,9 @@ func (t *Task) Requeue(notes string, entry HistoryEntry) error {
 		return fmt.Errorf("cannot requeue task %s: status is %q, want %q or %q",
 			t.ID, t.Status, StatusFailed, StatusDone)
 	}
+	if entry.Route == "" {
+		entry.Route = t.Route
+	}
 	t.History = append(t.History, entry)
 	if len(t.History) > MaxHistoryEntries {
 		t.History = t.History[len(t.History)-MaxHistoryEntries:]
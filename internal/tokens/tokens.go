@@ -0,0 +1,35 @@
+// Package tokens sizes prompt text in tokens, by a rough
+// characters-per-token ratio rather than a real tokenizer.
+package tokens
+
+import "strings"
+
+// CharsPerToken is the rough characters-per-token ratio used for sizing.
+const CharsPerToken = 4
+
+// Marker ends text that Truncate cut.
+const Marker = "\n... (truncated)"
+
+// Estimate returns a rough token count for s.
+func Estimate(s string) int {
+	return (len(s) + CharsPerToken - 1) / CharsPerToken
+}
+
+// Truncate trims s to at most maxTokens tokens, Marker included, cutting
+// at a line boundary when one falls in the second half of what is kept.
+// A maxTokens of zero or less means no limit; a budget too small for the
+// marker leaves nothing.
+func Truncate(maxTokens int, s string) string {
+	if maxTokens <= 0 || Estimate(s) <= maxTokens {
+		return s
+	}
+	limit := maxTokens*CharsPerToken - len(Marker)
+	if limit <= 0 {
+		return ""
+	}
+	cut := s[:limit]
+	if i := strings.LastIndexByte(cut, '\n'); i > limit/2 {
+		cut = cut[:i]
+	}
+	return cut + Marker
+}
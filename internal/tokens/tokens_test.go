@@ -0,0 +1,40 @@
+package tokens
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimate(t *testing.T) {
+	for s, want := range map[string]int{"": 0, "abc": 1, "abcd": 1, "abcde": 2} {
+		if got := Estimate(s); got != want {
+			t.Errorf("Estimate(%q) = %d, want %d", s, got, want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := Truncate(10, "short"); got != "short" {
+		t.Errorf("short input changed: %q", got)
+	}
+	if got := Truncate(0, "anything"); got != "anything" {
+		t.Errorf("zero limit should not truncate: %q", got)
+	}
+	if got := Truncate(2, strings.Repeat("x", 100)); got != "" {
+		t.Errorf("budget smaller than the marker should leave nothing: %q", got)
+	}
+
+	long := strings.Repeat("abcdefgh\n", 100)
+	for _, max := range []int{10, 20, 57} {
+		got := Truncate(max, long)
+		if !strings.HasSuffix(got, Marker) {
+			t.Errorf("Truncate(%d): missing marker: %q", max, got)
+		}
+		if n := Estimate(got); n > max {
+			t.Errorf("Truncate(%d) = %d tokens, marker included", max, n)
+		}
+		if body := strings.TrimSuffix(got, Marker); !strings.HasSuffix(body, "abcdefgh") {
+			t.Errorf("Truncate(%d) should cut at a line boundary: %q", max, body)
+		}
+	}
+}
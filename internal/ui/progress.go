@@ -2,7 +2,9 @@ package ui
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"text/tabwriter"
 	"time"
 )
 
@@ -33,6 +35,71 @@ type CostSummary struct {
 	Duration       time.Duration
 	CostLimit      float64
 	TokenLimit     int
+	// Issues counts open validator issues across tasks; IssueSeverity
+	// breaks them down by severity.
+	Issues        int
+	IssueSeverity map[string]int
+
+	// TokenClasses breaks all tokens used down by class. Cost breakdowns
+	// come from the session's cost ledger, most expensive first, and
+	// LedgerPath is where it was written.
+	TokenClasses []TokenClass
+	ByRole       []CostLine
+	ByModel      []CostLine
+	ByTask       []CostLine
+	LedgerPath   string
+
+	// Estimate is the approved plan's estimate, nil when planning was
+	// skipped.
+	Estimate *CostEstimate
+
+	// Resources breaks measured resource usage down by role, and
+	// Recommendation sizes concurrency from the workers' usage; nil when
+	// nothing was measured.
+	Resources      []ResourceLine
+	Recommendation *ConcurrencyRecommendation
+}
+
+// ResourceLine is one role's measured resource usage.
+type ResourceLine struct {
+	Role string
+	Runs int
+	// PeakMemoryBytes is the largest single run's peak; CPUTime and block
+	// I/O are totals.
+	PeakMemoryBytes int64
+	CPUTime         time.Duration
+	BlockReadBytes  int64
+	BlockWriteBytes int64
+}
+
+// ConcurrencyRecommendation suggests concurrency settings from measured
+// usage.
+type ConcurrencyRecommendation struct {
+	Configured       int
+	Development      int
+	MinRAMPerAgentMB int
+	LimitedBy        string
+}
+
+// CostEstimate is an estimated cost with its 80% interval.
+type CostEstimate struct {
+	Mean float64
+	Low  float64
+	High float64
+}
+
+// TokenClass is the number of tokens used in one class, like cache_read.
+type TokenClass struct {
+	Name   string
+	Tokens int
+}
+
+// CostLine is one row of a cost breakdown.
+type CostLine struct {
+	Label   string
+	Runs    int
+	CostUSD float64
+	Tokens  int
 }
 
 // FormatProgress returns a single-line progress string for display during waves.
@@ -54,17 +121,197 @@ func FormatCostSummary(cs CostSummary) string {
 	b.WriteString(fmt.Sprintf("  Completed: %d\n", cs.TasksCompleted))
 	b.WriteString(fmt.Sprintf("  Merged:    %d\n", cs.TasksMerged))
 	b.WriteString(fmt.Sprintf("  Failed:    %d\n", cs.TasksFailed))
+	if cs.Issues > 0 {
+		b.WriteString(fmt.Sprintf("  Issues:    %d%s\n", cs.Issues, formatSeverities(cs.IssueSeverity)))
+	}
 	b.WriteString("\nCost:\n")
 	b.WriteString(fmt.Sprintf("  Total:     $%.4f\n", cs.TotalCost))
+	if e := cs.Estimate; e != nil {
+		b.WriteString(fmt.Sprintf("  Estimate:  $%.2f ($%.2f - $%.2f), actual %s\n", e.Mean, e.Low, e.High, e.compare(cs.TotalCost)))
+	}
 	if cs.CostLimit > 0 {
 		pct := (cs.TotalCost / cs.CostLimit) * 100
 		b.WriteString(fmt.Sprintf("  Limit:     $%.2f (%.1f%% used)\n", cs.CostLimit, pct))
 	}
 	b.WriteString(fmt.Sprintf("  Tokens:    %d\n", cs.TotalTokens))
+	if classes := formatTokenClasses(cs.TokenClasses); classes != "" {
+		b.WriteString(fmt.Sprintf("  Usage:     %s\n", classes))
+	}
 	if cs.TokenLimit > 0 {
 		pct := (float64(cs.TotalTokens) / float64(cs.TokenLimit)) * 100
 		b.WriteString(fmt.Sprintf("  Limit:     %d (%.1f%% used)\n", cs.TokenLimit, pct))
 	}
+	writeCostLines(&b, "By role", cs.ByRole)
+	writeCostLines(&b, "By model", cs.ByModel)
+	writeCostLines(&b, "By task", cs.ByTask)
+	writeResources(&b, cs.Resources, cs.Recommendation)
+	if cs.LedgerPath != "" {
+		b.WriteString(fmt.Sprintf("\nLedger:     %s\n", cs.LedgerPath))
+	}
 	b.WriteString("=======================\n")
 	return b.String()
 }
+
+// compare describes where an actual cost fell relative to the estimate.
+func (e *CostEstimate) compare(actual float64) string {
+	switch {
+	case actual < e.Low:
+		return "below range"
+	case actual > e.High:
+		return "above range"
+	default:
+		return "within range"
+	}
+}
+
+// formatTokenClasses renders token classes as "input: 10, output: 5, ...",
+// or "" when no tokens were used.
+func formatTokenClasses(classes []TokenClass) string {
+	var parts []string
+	total := 0
+	for _, c := range classes {
+		parts = append(parts, fmt.Sprintf("%s: %d", c.Name, c.Tokens))
+		total += c.Tokens
+	}
+	if total == 0 {
+		return ""
+	}
+	return strings.Join(parts, ", ")
+}
+
+// writeCostLines renders a cost breakdown as an aligned table.
+func writeCostLines(b *strings.Builder, title string, lines []CostLine) {
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n%s:\n", title)
+	tw := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	for _, l := range lines {
+		label := l.Label
+		if label == "" {
+			label = "(unknown)"
+		}
+		fmt.Fprintf(tw, "  %s\t$%.4f\t%d tokens\t%d run(s)\n", label, l.CostUSD, l.Tokens, l.Runs)
+	}
+	tw.Flush()
+}
+
+// writeResources renders measured resource usage by role and the
+// concurrency recommendation.
+func writeResources(b *strings.Builder, lines []ResourceLine, rec *ConcurrencyRecommendation) {
+	if len(lines) == 0 {
+		return
+	}
+	b.WriteString("\nResources:\n")
+	tw := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	for _, l := range lines {
+		fmt.Fprintf(tw, "  %s\tpeak %s\tCPU %v\tI/O %s read, %s written\t%d run(s)\n",
+			l.Role, formatBytes(l.PeakMemoryBytes), l.CPUTime.Round(time.Second),
+			formatBytes(l.BlockReadBytes), formatBytes(l.BlockWriteBytes), l.Runs)
+	}
+	tw.Flush()
+	if rec != nil {
+		fmt.Fprintf(b, "  Recommended: concurrency.development: %d (configured %d, bound by %s), adaptive_min_ram_per_agent_mb: %d\n",
+			rec.Development, rec.Configured, rec.LimitedBy, rec.MinRAMPerAgentMB)
+	}
+}
+
+// formatBytes renders a byte count as "512 KB", "1.5 GB" and so on.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, suffix := float64(n)/unit, "KB"
+	for _, next := range []string{"MB", "GB", "TB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, next
+	}
+	return fmt.Sprintf("%.1f %s", value, suffix)
+}
+
+// formatSeverities renders issue counts as " (high: 1, low: 2)", most
+// severe first.
+func formatSeverities(counts map[string]int) string {
+	var parts []string
+	for _, sev := range []string{"high", "medium", "low"} {
+		if n := counts[sev]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%s: %d", sev, n))
+		}
+	}
+	var other []string
+	for sev, n := range counts {
+		if sev != "high" && sev != "medium" && sev != "low" && n > 0 {
+			if sev == "" {
+				sev = "unrated"
+			}
+			other = append(other, fmt.Sprintf("%s: %d", sev, n))
+		}
+	}
+	sort.Strings(other)
+	parts = append(parts, other...)
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+// FormatPlanComparison renders ensemble plans side by side: one column per
+// plan with its metrics, followed by each plan's task list.
+func FormatPlanComparison(candidates []PlanCandidate) string {
+	var b strings.Builder
+	b.WriteString("\n=== Plan Comparison ===\n")
+	tw := tabwriter.NewWriter(&b, 0, 0, 3, ' ', 0)
+	row := func(name string, cell func(i int, c PlanCandidate) string) {
+		fmt.Fprintf(tw, "%s", name)
+		for i, c := range candidates {
+			fmt.Fprintf(tw, "\t%s", cell(i, c))
+		}
+		fmt.Fprintln(tw)
+	}
+	row("", func(i int, c PlanCandidate) string { return fmt.Sprintf("Plan %d", i+1) })
+	row("Source", func(_ int, c PlanCandidate) string { return c.Label })
+	row("Tasks", func(_ int, c PlanCandidate) string { return fmt.Sprintf("%d", len(c.Tasks)) })
+	row("Critical path", func(_ int, c PlanCandidate) string { return fmt.Sprintf("%d", c.Depth) })
+	row("Parallelism", func(_ int, c PlanCandidate) string { return fmt.Sprintf("%.1f", c.Parallelism) })
+	row("Lock overlaps", func(_ int, c PlanCandidate) string { return fmt.Sprintf("%d", c.Overlaps) })
+	row("Judge rank", func(_ int, c PlanCandidate) string {
+		if c.JudgeRank == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%d", c.JudgeRank)
+	})
+	row("Score", func(_ int, c PlanCandidate) string { return fmt.Sprintf("%.2f", c.Score) })
+	tw.Flush()
+
+	for i, c := range candidates {
+		fmt.Fprintf(&b, "\nPlan %d:\n", i+1)
+		for _, t := range c.Tasks {
+			fmt.Fprintf(&b, "  - %s\n", t)
+		}
+	}
+	return b.String()
+}
+
+// FormatValidatorReviews lists each validator's verdict and notes for tasks
+// reviewed by a quorum, flagging tasks where the validators disagreed.
+func FormatValidatorReviews(reviews []TaskReviews) string {
+	var b strings.Builder
+	for _, tr := range reviews {
+		marker := ""
+		if tr.Disagreement {
+			marker = " [VALIDATORS DISAGREE]"
+		}
+		fmt.Fprintf(&b, "  Validation of %s%s:\n", tr.TaskID, marker)
+		for _, r := range tr.Reviews {
+			fmt.Fprintf(&b, "    %s: %s", r.Validator, r.Status)
+			if r.Notes != "" {
+				fmt.Fprintf(&b, " - %s", r.Notes)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
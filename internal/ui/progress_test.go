@@ -70,6 +70,39 @@ func TestFormatCostSummary(t *testing.T) {
 	}
 }
 
+func TestFormatCostSummaryBreakdowns(t *testing.T) {
+	cs := CostSummary{
+		SessionID:    "ses-test",
+		TotalCost:    1.50,
+		ByRole:       []CostLine{{Label: "worker", Runs: 2, CostUSD: 1.25, Tokens: 9000}, {Label: "planner", Runs: 1, CostUSD: 0.25}},
+		ByTask:       []CostLine{{Label: "task-001", Runs: 3, CostUSD: 1.50}},
+		TokenClasses: []TokenClass{{Name: "input", Tokens: 100}, {Name: "cache_read", Tokens: 9000}},
+		LedgerPath:   ".blueflame/sessions/ses-test/ledger.jsonl",
+	}
+
+	got := FormatCostSummary(cs)
+	for _, want := range []string{"Usage:     input: 100, cache_read: 9000", "By role:", "worker   $1.2500  9000 tokens  2 run(s)", "By task:", "task-001", "Ledger:     .blueflame/sessions/ses-test/ledger.jsonl"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "By model:") {
+		t.Errorf("empty breakdowns should be omitted:\n%s", got)
+	}
+}
+
+func TestFormatCostSummaryEstimate(t *testing.T) {
+	cs := CostSummary{
+		SessionID: "ses-test",
+		TotalCost: 4.10,
+		Estimate:  &CostEstimate{Mean: 3.00, Low: 2.20, High: 3.80},
+	}
+	got := FormatCostSummary(cs)
+	if want := "Estimate:  $3.00 ($2.20 - $3.80), actual above range"; !strings.Contains(got, want) {
+		t.Errorf("missing %q in:\n%s", want, got)
+	}
+}
+
 func TestFormatCostSummaryNoLimits(t *testing.T) {
 	cs := CostSummary{
 		SessionID:  "ses-test",
@@ -83,3 +116,82 @@ func TestFormatCostSummaryNoLimits(t *testing.T) {
 		t.Errorf("should not show limit percentage: %s", got)
 	}
 }
+
+func TestFormatPlanComparison(t *testing.T) {
+	out := FormatPlanComparison([]PlanCandidate{
+		{Label: "planner 1 (opus)", Tasks: []string{"task-001: Auth", "task-002: Docs"}, Depth: 1, Parallelism: 2, JudgeRank: 1, Score: 0.9},
+		{Label: "planner 2 (sonnet)", Tasks: []string{"task-001: Everything"}, Depth: 1, Parallelism: 1, Overlaps: 1, Score: 0.4},
+	})
+	for _, want := range []string{"Plan 1", "Plan 2", "planner 1 (opus)", "0.90", "task-001: Everything"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("comparison missing %q:\n%s", want, out)
+		}
+	}
+	// Metrics are laid out in columns, one row per metric.
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "Judge rank") {
+			if fields := strings.Fields(line); fields[2] != "1" || fields[3] != "-" {
+				t.Errorf("judge rank row = %q, want 1 and -", line)
+			}
+		}
+	}
+}
+
+func TestFormatValidatorReviews(t *testing.T) {
+	out := FormatValidatorReviews([]TaskReviews{
+		{TaskID: "task-001", Disagreement: true, Reviews: []ValidatorReview{
+			{Validator: "validator 1 (haiku)", Status: "pass", Notes: "looks good"},
+			{Validator: "validator 2 (sonnet)", Status: "fail", Notes: "missing nil check"},
+		}},
+		{TaskID: "task-002", Reviews: []ValidatorReview{
+			{Validator: "validator 1 (haiku)", Status: "pass"},
+		}},
+	})
+	want := "  Validation of task-001 [VALIDATORS DISAGREE]:\n" +
+		"    validator 1 (haiku): pass - looks good\n" +
+		"    validator 2 (sonnet): fail - missing nil check\n" +
+		"  Validation of task-002:\n" +
+		"    validator 1 (haiku): pass\n"
+	if out != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out, want)
+	}
+	if FormatValidatorReviews(nil) != "" {
+		t.Error("no reviews should render nothing")
+	}
+}
+
+func TestFormatCostSummaryIssues(t *testing.T) {
+	got := FormatCostSummary(CostSummary{
+		SessionID:     "ses-test",
+		Issues:        4,
+		IssueSeverity: map[string]int{"low": 2, "high": 1, "": 1},
+	})
+	if !strings.Contains(got, "Issues:    4 (high: 1, low: 2, unrated: 1)") {
+		t.Errorf("summary missing issue breakdown:\n%s", got)
+	}
+	if strings.Contains(FormatCostSummary(CostSummary{}), "Issues:") {
+		t.Error("summary without issues should not show an issues line")
+	}
+}
+
+func TestFormatCostSummaryResources(t *testing.T) {
+	out := FormatCostSummary(CostSummary{
+		SessionID: "ses-1",
+		Resources: []ResourceLine{
+			{Role: "worker", Runs: 3, PeakMemoryBytes: 1536 << 20, CPUTime: 95 * time.Second, BlockWriteBytes: 40 << 20},
+		},
+		Recommendation: &ConcurrencyRecommendation{Configured: 4, Development: 6, MinRAMPerAgentMB: 1920, LimitedBy: "memory"},
+	})
+	for _, want := range []string{
+		"Resources:",
+		"worker  peak 1.5 GB  CPU 1m35s  I/O 0 B read, 40.0 MB written  3 run(s)",
+		"concurrency.development: 6 (configured 4, bound by memory), adaptive_min_ram_per_agent_mb: 1920",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("summary missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(FormatCostSummary(CostSummary{}), "Resources:") {
+		t.Error("summary without measurements should omit resources")
+	}
+}
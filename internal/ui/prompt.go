@@ -2,10 +2,13 @@ package ui
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/kylegalloway/blueflame/internal/state"
 )
@@ -20,6 +23,21 @@ const (
 	PlanAbort
 )
 
+// PlanSynthesize is returned by PlanSelect to ask a synthesizer agent to
+// merge the candidate plans instead of picking one.
+const PlanSynthesize = -1
+
+// PlanCandidate summarizes one plan from a planner ensemble for comparison.
+type PlanCandidate struct {
+	Label       string
+	Tasks       []string // "id: title" per task
+	Depth       int      // critical path length in tasks
+	Parallelism float64  // tasks per dependency layer
+	Overlaps    int      // overlapping lock pairs
+	JudgeRank   int      // 1 = best; 0 if no judge ran
+	Score       float64
+}
+
 // ChangesetDecision represents the human's decision on a changeset.
 type ChangesetDecision int
 
@@ -55,6 +73,26 @@ const (
 	ValidatorRetryTask
 )
 
+// ToolApprovalDecision represents the human's answer to an agent's tool
+// call that the permission policy asks about.
+type ToolApprovalDecision int
+
+const (
+	ToolApproveOnce ToolApprovalDecision = iota
+	ToolApproveSession
+	ToolDeny
+)
+
+// ToolApprovalRequest describes a tool call awaiting approval.
+type ToolApprovalRequest struct {
+	AgentID string
+	TaskID  string
+	Tool    string
+	Target  string // path or Bash command
+	Rule    string
+	Reason  string
+}
+
 // ChangesetInfo describes a changeset for review.
 type ChangesetInfo struct {
 	Index         int
@@ -68,6 +106,22 @@ type ChangesetInfo struct {
 	Diff          string
 	Deferred      bool
 	DeferredNote  string
+	Reviews       []TaskReviews
+	Issues        []string
+}
+
+// TaskReviews holds the verdicts of a validator quorum on one task.
+type TaskReviews struct {
+	TaskID       string
+	Disagreement bool
+	Reviews      []ValidatorReview
+}
+
+// ValidatorReview is one validator's verdict.
+type ValidatorReview struct {
+	Validator string
+	Status    string
+	Notes     string
 }
 
 // SessionState describes the current session state for the continuation prompt.
@@ -86,18 +140,41 @@ type SessionState struct {
 // Prompter is the interface for human interaction.
 type Prompter interface {
 	PlanApproval(taskCount int, estimatedCost string) (PlanDecision, string)
+	PlanSelect(candidates []PlanCandidate) int
 	ChangesetReview(cs ChangesetInfo) (ChangesetDecision, string)
 	SessionContinuation(state SessionState) SessionDecision
 	ValidatorFailed(taskID string, err error) ValidatorFailureDecision
 	CrashRecoveryPrompt(rs *state.OrchestratorState) CrashRecoveryDecision
+	// ToolApproval asks about an agent's tool call. It gives up and denies
+	// the call when ctx is done.
+	ToolApproval(ctx context.Context, req ToolApprovalRequest) ToolApprovalDecision
 	Warn(msg string)
 	Info(msg string)
 }
 
-// TerminalPrompter implements Prompter using terminal I/O.
+// TerminalPrompter implements Prompter using terminal I/O. Tool approvals
+// arrive from agents while the main loop may be prompting too, so prompts
+// take turns, and only one goroutine ever reads from reader.
 type TerminalPrompter struct {
 	reader *bufio.Reader
 	writer io.Writer
+
+	start sync.Once
+	// turn is held by the prompt using the terminal.
+	turn chan struct{}
+	// want asks the reading goroutine for a line, which it sends on lines.
+	// pending is set while a line has been asked for but not received,
+	// such as after a question timed out.
+	want    chan struct{}
+	lines   chan inputLine
+	pending bool
+}
+
+// inputLine is a line read from the terminal and when it was read.
+type inputLine struct {
+	text string
+	err  error
+	at   time.Time
 }
 
 // NewTerminalPrompter creates a TerminalPrompter using stdin/stdout.
@@ -108,9 +185,65 @@ func NewTerminalPrompter() *TerminalPrompter {
 	}
 }
 
+func (p *TerminalPrompter) setup() {
+	p.start.Do(func() {
+		p.turn = make(chan struct{}, 1)
+		p.want = make(chan struct{})
+		p.lines = make(chan inputLine)
+		go func() {
+			for range p.want {
+				text, err := p.reader.ReadString('\n')
+				p.lines <- inputLine{text: text, err: err, at: time.Now()}
+			}
+		}()
+	})
+}
+
+// acquire waits for the terminal, giving up when ctx is done.
+func (p *TerminalPrompter) acquire(ctx context.Context) error {
+	p.setup()
+	select {
+	case p.turn <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *TerminalPrompter) release() {
+	<-p.turn
+}
+
+// readLine reads the answer to the question just asked, giving up when ctx
+// is done. A line read before the question was asked answered an earlier
+// question that timed out; it is reported and skipped rather than taken
+// as this question's answer. The caller must hold the terminal.
+func (p *TerminalPrompter) readLine(ctx context.Context) (string, error) {
+	asked := time.Now()
+	for {
+		if !p.pending {
+			p.want <- struct{}{}
+			p.pending = true
+		}
+		select {
+		case in := <-p.lines:
+			p.pending = false
+			if in.at.Before(asked) && in.err == nil {
+				fmt.Fprintf(p.writer, "(ignored %q: that question had timed out)\n", strings.TrimSpace(in.text))
+				continue
+			}
+			return in.text, in.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
 func (p *TerminalPrompter) PlanApproval(taskCount int, estimatedCost string) (PlanDecision, string) {
+	p.acquire(context.Background())
+	defer p.release()
 	fmt.Fprintf(p.writer, "\n(a)pprove / (e)dit tasks.yaml / (r)e-plan / (q)uit? ")
-	line, _ := p.reader.ReadString('\n')
+	line, _ := p.readLine(context.Background())
 	switch strings.TrimSpace(strings.ToLower(line)) {
 	case "a", "approve":
 		return PlanApprove, ""
@@ -118,7 +251,7 @@ func (p *TerminalPrompter) PlanApproval(taskCount int, estimatedCost string) (Pl
 		return PlanEdit, ""
 	case "r", "re-plan", "replan":
 		fmt.Fprintf(p.writer, "What should change? ")
-		feedback, _ := p.reader.ReadString('\n')
+		feedback, _ := p.readLine(context.Background())
 		return PlanReplan, strings.TrimSpace(feedback)
 	case "q", "quit":
 		return PlanAbort, ""
@@ -127,7 +260,40 @@ func (p *TerminalPrompter) PlanApproval(taskCount int, estimatedCost string) (Pl
 	}
 }
 
+// PlanSelect shows ensemble plans side by side and returns the index of the
+// chosen one, or PlanSynthesize. Candidates arrive best-scored first, so an
+// empty answer picks the first.
+func (p *TerminalPrompter) PlanSelect(candidates []PlanCandidate) int {
+	p.acquire(context.Background())
+	defer p.release()
+	fmt.Fprint(p.writer, FormatPlanComparison(candidates))
+	for {
+		fmt.Fprintf(p.writer, "\nPick a plan [1-%d], (s)ynthesize, or Enter for plan 1: ", len(candidates))
+		line, err := p.readLine(context.Background())
+		answer := strings.TrimSpace(strings.ToLower(line))
+		switch answer {
+		case "":
+			return 0
+		case "s", "synthesize":
+			return PlanSynthesize
+		}
+		var n int
+		if _, scanErr := fmt.Sscanf(answer, "%d", &n); scanErr == nil && n >= 1 && n <= len(candidates) {
+			return n - 1
+		}
+		if err != nil {
+			return 0
+		}
+	}
+}
+
 func (p *TerminalPrompter) ChangesetReview(cs ChangesetInfo) (ChangesetDecision, string) {
+	p.acquire(context.Background())
+	defer p.release()
+	return p.changesetReview(cs)
+}
+
+func (p *TerminalPrompter) changesetReview(cs ChangesetInfo) (ChangesetDecision, string) {
 	if cs.Deferred {
 		fmt.Fprintf(p.writer, "\nChangeset %d/%d: [%s] %s\n  NOTE: %s\n",
 			cs.Index, cs.Total, cs.CohesionGroup, cs.Description, cs.DeferredNote)
@@ -138,20 +304,27 @@ func (p *TerminalPrompter) ChangesetReview(cs ChangesetInfo) (ChangesetDecision,
 		cs.Index, cs.Total, cs.CohesionGroup, cs.Description,
 		cs.FilesChanged, cs.LinesAdded, cs.LinesRemoved,
 		strings.Join(cs.TaskIDs, ", "))
+	fmt.Fprint(p.writer, FormatValidatorReviews(cs.Reviews))
+	if len(cs.Issues) > 0 {
+		fmt.Fprintf(p.writer, "  Validator issues:\n")
+		for _, issue := range cs.Issues {
+			fmt.Fprintf(p.writer, "    - %s\n", issue)
+		}
+	}
 	fmt.Fprintf(p.writer, "  (a)pprove / (r)eject / (v)iew diff / (s)kip? ")
 
-	line, _ := p.reader.ReadString('\n')
+	line, _ := p.readLine(context.Background())
 	switch strings.TrimSpace(strings.ToLower(line)) {
 	case "a", "approve":
 		return ChangesetApprove, ""
 	case "r", "reject":
 		fmt.Fprintf(p.writer, "  Rejection reason: ")
-		reason, _ := p.reader.ReadString('\n')
+		reason, _ := p.readLine(context.Background())
 		return ChangesetReject, strings.TrimSpace(reason)
 	case "v", "view":
 		fmt.Fprintln(p.writer, cs.Diff)
 		// Re-prompt after viewing
-		return p.ChangesetReview(cs)
+		return p.changesetReview(cs)
 	case "s", "skip":
 		return ChangesetSkip, ""
 	default:
@@ -160,6 +333,8 @@ func (p *TerminalPrompter) ChangesetReview(cs ChangesetInfo) (ChangesetDecision,
 }
 
 func (p *TerminalPrompter) SessionContinuation(state SessionState) SessionDecision {
+	p.acquire(context.Background())
+	defer p.release()
 	fmt.Fprintf(p.writer, "\nWave cycle %d complete.\n", state.WaveCycle)
 	fmt.Fprintf(p.writer, "  Approved: %d changeset(s)\n", state.Approved)
 	fmt.Fprintf(p.writer, "  Re-queued: %d task(s)", state.Requeued)
@@ -178,7 +353,7 @@ func (p *TerminalPrompter) SessionContinuation(state SessionState) SessionDecisi
 	}
 
 	fmt.Fprintf(p.writer, "\n  (c)ontinue / (r)e-plan / (s)top? ")
-	line, _ := p.reader.ReadString('\n')
+	line, _ := p.readLine(context.Background())
 	switch strings.TrimSpace(strings.ToLower(line)) {
 	case "c", "continue":
 		return SessionContinue
@@ -192,9 +367,11 @@ func (p *TerminalPrompter) SessionContinuation(state SessionState) SessionDecisi
 }
 
 func (p *TerminalPrompter) ValidatorFailed(taskID string, err error) ValidatorFailureDecision {
+	p.acquire(context.Background())
+	defer p.release()
 	fmt.Fprintf(p.writer, "\nValidator failed for %s: %v\n", taskID, err)
 	fmt.Fprintf(p.writer, "  (m)anual review / (s)kip task / (r)etry? ")
-	line, _ := p.reader.ReadString('\n')
+	line, _ := p.readLine(context.Background())
 	switch strings.TrimSpace(strings.ToLower(line)) {
 	case "m", "manual":
 		return ValidatorManualReview
@@ -208,11 +385,13 @@ func (p *TerminalPrompter) ValidatorFailed(taskID string, err error) ValidatorFa
 }
 
 func (p *TerminalPrompter) CrashRecoveryPrompt(rs *state.OrchestratorState) CrashRecoveryDecision {
+	p.acquire(context.Background())
+	defer p.release()
 	fmt.Fprintf(p.writer, "\nPrevious session found: %s\n", rs.SessionID)
 	fmt.Fprintf(p.writer, "  Wave cycle: %d, phase: %s\n", rs.WaveCycle, rs.Phase)
 	fmt.Fprintf(p.writer, "  Cost so far: $%.2f (%d tokens)\n", rs.SessionCost, rs.SessionTokens)
 	fmt.Fprintf(p.writer, "\n(r)esume / (f)resh? ")
-	line, _ := p.reader.ReadString('\n')
+	line, _ := p.readLine(context.Background())
 	switch strings.TrimSpace(strings.ToLower(line)) {
 	case "r", "resume":
 		return RecoveryResume
@@ -221,6 +400,36 @@ func (p *TerminalPrompter) CrashRecoveryPrompt(rs *state.OrchestratorState) Cras
 	}
 }
 
+func (p *TerminalPrompter) ToolApproval(ctx context.Context, req ToolApprovalRequest) ToolApprovalDecision {
+	if p.acquire(ctx) != nil {
+		return ToolDeny
+	}
+	defer p.release()
+	fmt.Fprintf(p.writer, "\nAgent %s", req.AgentID)
+	if req.TaskID != "" {
+		fmt.Fprintf(p.writer, " (task %s)", req.TaskID)
+	}
+	fmt.Fprintf(p.writer, " asks to use %s:\n  %s\n", req.Tool, req.Target)
+	fmt.Fprintf(p.writer, "  Rule %s", req.Rule)
+	if req.Reason != "" {
+		fmt.Fprintf(p.writer, ": %s", req.Reason)
+	}
+	fmt.Fprintf(p.writer, "\n  (o)nce / (s)ession / (d)eny? ")
+	line, err := p.readLine(ctx)
+	if err != nil && ctx.Err() != nil {
+		fmt.Fprintf(p.writer, "\n  No answer in time; denied.\n")
+		return ToolDeny
+	}
+	switch strings.TrimSpace(strings.ToLower(line)) {
+	case "o", "once":
+		return ToolApproveOnce
+	case "s", "session":
+		return ToolApproveSession
+	default:
+		return ToolDeny
+	}
+}
+
 func (p *TerminalPrompter) Warn(msg string) {
 	fmt.Fprintf(p.writer, "WARNING: %s\n", msg)
 }
@@ -232,19 +441,25 @@ func (p *TerminalPrompter) Info(msg string) {
 // ScriptedPrompter implements Prompter with predetermined decisions for testing.
 type ScriptedPrompter struct {
 	PlanDecisions      []PlanDecision
+	PlanSelections     []int
 	ChangesetDecisions []ChangesetDecision
 	SessionDecisions   []SessionDecision
 	ValidatorDecisions []ValidatorFailureDecision
 	RecoveryDecisions  []CrashRecoveryDecision
+	ToolDecisions      []ToolApprovalDecision
 	RejectionReasons   []string
 	ReplanFeedback     []string
 	Messages           []string
+	Changesets         []ChangesetInfo
+	ToolRequests       []ToolApprovalRequest
 
 	planIdx      int
+	selectIdx    int
 	changesetIdx int
 	sessionIdx   int
 	validatorIdx int
 	recoveryIdx  int
+	toolIdx      int
 }
 
 func (p *ScriptedPrompter) PlanApproval(taskCount int, estimatedCost string) (PlanDecision, string) {
@@ -260,7 +475,19 @@ func (p *ScriptedPrompter) PlanApproval(taskCount int, estimatedCost string) (Pl
 	return PlanAbort, ""
 }
 
+func (p *ScriptedPrompter) PlanSelect(candidates []PlanCandidate) int {
+	if p.selectIdx < len(p.PlanSelections) {
+		d := p.PlanSelections[p.selectIdx]
+		p.selectIdx++
+		if d == PlanSynthesize || (d >= 0 && d < len(candidates)) {
+			return d
+		}
+	}
+	return 0
+}
+
 func (p *ScriptedPrompter) ChangesetReview(cs ChangesetInfo) (ChangesetDecision, string) {
+	p.Changesets = append(p.Changesets, cs)
 	if cs.Deferred {
 		return ChangesetSkip, ""
 	}
@@ -303,6 +530,16 @@ func (p *ScriptedPrompter) CrashRecoveryPrompt(rs *state.OrchestratorState) Cras
 	return RecoveryFresh
 }
 
+func (p *ScriptedPrompter) ToolApproval(ctx context.Context, req ToolApprovalRequest) ToolApprovalDecision {
+	p.ToolRequests = append(p.ToolRequests, req)
+	if p.toolIdx < len(p.ToolDecisions) {
+		d := p.ToolDecisions[p.toolIdx]
+		p.toolIdx++
+		return d
+	}
+	return ToolDeny
+}
+
 // NewScriptedPrompterFromFile creates a ScriptedPrompter by reading decisions from a file.
 // File format: one decision per line (approve/reject/continue/stop/etc.)
 func NewScriptedPrompterFromFile(path string) *ScriptedPrompter {
@@ -326,6 +563,8 @@ func NewScriptedPrompterFromFile(path string) *ScriptedPrompter {
 			p.PlanDecisions = append(p.PlanDecisions, PlanReplan)
 		case "plan-abort":
 			p.PlanDecisions = append(p.PlanDecisions, PlanAbort)
+		case "plan-synthesize":
+			p.PlanSelections = append(p.PlanSelections, PlanSynthesize)
 		case "changeset-approve":
 			p.ChangesetDecisions = append(p.ChangesetDecisions, ChangesetApprove)
 		case "changeset-reject":
@@ -342,6 +581,17 @@ func NewScriptedPrompterFromFile(path string) *ScriptedPrompter {
 			p.RecoveryDecisions = append(p.RecoveryDecisions, RecoveryResume)
 		case "recovery-fresh":
 			p.RecoveryDecisions = append(p.RecoveryDecisions, RecoveryFresh)
+		case "tool-approve":
+			p.ToolDecisions = append(p.ToolDecisions, ToolApproveOnce)
+		case "tool-approve-session":
+			p.ToolDecisions = append(p.ToolDecisions, ToolApproveSession)
+		case "tool-deny":
+			p.ToolDecisions = append(p.ToolDecisions, ToolDeny)
+		default:
+			var n int
+			if _, err := fmt.Sscanf(strings.ToLower(line), "plan-pick-%d", &n); err == nil && n >= 1 {
+				p.PlanSelections = append(p.PlanSelections, n-1)
+			}
 		}
 	}
 	return p
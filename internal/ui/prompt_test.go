@@ -1,8 +1,13 @@
 package ui
 
 import (
+	"bufio"
+	"context"
+	"io"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/kylegalloway/blueflame/internal/state"
 )
@@ -175,3 +180,83 @@ approve
 		t.Errorf("PlanDecisions len = %d, want 1", len(p.PlanDecisions))
 	}
 }
+
+func TestScriptedPrompterPlanSelectFromFile(t *testing.T) {
+	path := t.TempDir() + "/decisions.txt"
+	if err := os.WriteFile(path, []byte("plan-pick-2\nplan-synthesize\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := NewScriptedPrompterFromFile(path)
+
+	candidates := []PlanCandidate{{Label: "a"}, {Label: "b"}}
+	if got := p.PlanSelect(candidates); got != 1 {
+		t.Errorf("first PlanSelect = %d, want 1", got)
+	}
+	if got := p.PlanSelect(candidates); got != PlanSynthesize {
+		t.Errorf("second PlanSelect = %d, want PlanSynthesize", got)
+	}
+	if got := p.PlanSelect(candidates); got != 0 {
+		t.Errorf("default PlanSelect = %d, want 0", got)
+	}
+}
+
+func TestScriptedPrompterToolApprovalFromFile(t *testing.T) {
+	path := t.TempDir() + "/decisions.txt"
+	if err := os.WriteFile(path, []byte("tool-approve-session\ntool-deny\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := NewScriptedPrompterFromFile(path)
+
+	req := ToolApprovalRequest{AgentID: "worker-1", Tool: "Bash", Target: "go mod tidy"}
+	for i, want := range []ToolApprovalDecision{ToolApproveSession, ToolDeny, ToolDeny} {
+		if d := p.ToolApproval(context.Background(), req); d != want {
+			t.Errorf("decision %d = %d, want %d", i+1, d, want)
+		}
+	}
+	if len(p.ToolRequests) != 3 || p.ToolRequests[0] != req {
+		t.Errorf("ToolRequests = %+v", p.ToolRequests)
+	}
+}
+
+func TestTerminalPrompterToolApproval(t *testing.T) {
+	for answer, want := range map[string]ToolApprovalDecision{"o\n": ToolApproveOnce, "session\n": ToolApproveSession, "\n": ToolDeny} {
+		var out strings.Builder
+		p := &TerminalPrompter{reader: bufio.NewReader(strings.NewReader(answer)), writer: &out}
+		d := p.ToolApproval(context.Background(), ToolApprovalRequest{AgentID: "worker-1", TaskID: "task-001", Tool: "Bash", Target: "go mod tidy", Rule: "confirm_deps"})
+		if d != want {
+			t.Errorf("answer %q = %d, want %d", answer, d, want)
+		}
+		if !strings.Contains(out.String(), "worker-1 (task task-001) asks to use Bash:\n  go mod tidy") {
+			t.Errorf("prompt = %q", out.String())
+		}
+	}
+}
+
+func TestTerminalPrompterToolApprovalTimesOut(t *testing.T) {
+	in, typed := io.Pipe()
+	defer typed.Close()
+	var out strings.Builder
+	p := &TerminalPrompter{reader: bufio.NewReader(in), writer: &out}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if d := p.ToolApproval(ctx, ToolApprovalRequest{AgentID: "worker-1", Tool: "Bash", Target: "go mod tidy"}); d != ToolDeny {
+		t.Errorf("unanswered approval = %d, want ToolDeny", d)
+	}
+	if !strings.Contains(out.String(), "No answer in time; denied.") {
+		t.Errorf("prompt = %q", out.String())
+	}
+
+	// The human answers the withdrawn question, then the next one.
+	if _, err := typed.Write([]byte("o\n")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	go typed.Write([]byte("c\n"))
+	if d := p.SessionContinuation(SessionState{WaveCycle: 1}); d != SessionContinue {
+		t.Errorf("SessionContinuation = %d, want SessionContinue", d)
+	}
+	if !strings.Contains(out.String(), `(ignored "o": that question had timed out)`) {
+		t.Errorf("late answer not reported: %q", out.String())
+	}
+}
@@ -0,0 +1,493 @@
+package e2e
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kylegalloway/blueflame/internal/agent"
+	"github.com/kylegalloway/blueflame/internal/config"
+	"github.com/kylegalloway/blueflame/internal/locks"
+	"github.com/kylegalloway/blueflame/internal/orchestrator"
+	"github.com/kylegalloway/blueflame/internal/state"
+	"github.com/kylegalloway/blueflame/internal/tasks"
+	"github.com/kylegalloway/blueflame/internal/ui"
+	"github.com/kylegalloway/blueflame/internal/worktree"
+)
+
+// fakeClaudeDir holds the built fakeclaude binary, installed as "claude",
+// and blueflame itself, whose hook subcommand is the watcher.
+var fakeClaudeDir string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "blueflame-fakeclaude-*")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "create temp dir: %v\n", err)
+		os.Exit(1)
+	}
+	for name, pkg := range map[string]string{
+		"claude":    "github.com/kylegalloway/blueflame/test/fakeclaude",
+		"blueflame": "github.com/kylegalloway/blueflame/cmd/blueflame",
+	} {
+		build := exec.Command("go", "build", "-o", filepath.Join(dir, name), pkg)
+		if out, err := build.CombinedOutput(); err != nil {
+			fmt.Fprintf(os.Stderr, "build %s: %s: %v\n", name, out, err)
+			os.RemoveAll(dir)
+			os.Exit(1)
+		}
+	}
+	fakeClaudeDir = dir
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// harness wires a real ProductionSpawner, worktree, lock and lifecycle
+// managers against a scratch git repo, with fakeclaude first on PATH.
+type harness struct {
+	t        *testing.T
+	repo     string
+	stateDir string
+	fakeDir  string
+	cfg      *config.Config
+	store    *tasks.TaskStore
+	wt       *worktree.Manager
+}
+
+func newHarness(t *testing.T, scenario string) *harness {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	repo := t.TempDir()
+	for _, args := range [][]string{
+		{"init"},
+		{"config", "user.email", "test@test.com"},
+		{"config", "user.name", "Test"},
+		{"checkout", "-b", "main"},
+	} {
+		git(t, repo, args...)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "README.md"), []byte("# e2e\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, ".gitignore"), []byte(".blueflame/\n.trees/\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, repo, "add", ".")
+	git(t, repo, "commit", "-m", "initial commit")
+
+	fakeDir := t.TempDir()
+	scenarioPath := filepath.Join(fakeDir, "scenario.yaml")
+	if err := os.WriteFile(scenarioPath, []byte(scenario), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", fakeClaudeDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("BLUEFLAME_FAKE_SCENARIO", scenarioPath)
+	t.Setenv("BLUEFLAME_FAKE_STATE_DIR", fakeDir)
+
+	stateDir := filepath.Join(repo, ".blueflame")
+	cfg := &config.Config{
+		SchemaVersion: 1,
+		Project: config.ProjectConfig{
+			Name:        "e2e",
+			Repo:        repo,
+			BaseBranch:  "main",
+			WorktreeDir: ".trees",
+			TasksFile:   ".blueflame/tasks.yaml",
+		},
+		Concurrency: config.ConcurrencyConfig{
+			Planning:    1,
+			Development: 2,
+			Validation:  1,
+			Merge:       1,
+		},
+		Limits: config.LimitsConfig{
+			AgentTimeout:      60 * time.Second,
+			HeartbeatInterval: time.Second,
+			MaxRetries:        2,
+			MaxWaveCycles:     3,
+			TokenBudget: config.TokenBudget{
+				PlannerUSD:   0.40,
+				WorkerUSD:    1.50,
+				ValidatorUSD: 0.15,
+				MergerUSD:    0.50,
+			},
+		},
+		Sandbox: config.SandboxConfig{
+			MaxCPUSeconds: 60,
+			MaxOpenFiles:  1024,
+			// Network namespaces need privileges the test runner may not have.
+			AllowNetwork: true,
+		},
+		Models: config.ModelsConfig{
+			Planner:   "sonnet",
+			Worker:    "sonnet",
+			Validator: "haiku",
+			Merger:    "sonnet",
+		},
+		Permissions: config.PermissionsConfig{
+			AllowedTools: []string{"Read", "Write", "Edit", "Bash"},
+			BlockedTools: []string{"WebFetch"},
+		},
+	}
+
+	return &harness{
+		t:        t,
+		repo:     repo,
+		stateDir: stateDir,
+		fakeDir:  fakeDir,
+		cfg:      cfg,
+		store:    tasks.NewTaskStore(filepath.Join(repo, cfg.Project.TasksFile)),
+		wt:       worktree.NewManager(repo, cfg.Project.WorktreeDir, cfg.Project.BaseBranch),
+	}
+}
+
+// run executes a full orchestrator session. withHooks enables the watcher
+// hook.
+func (h *harness) run(prompter ui.Prompter, withHooks bool) (*orchestrator.Orchestrator, error) {
+	h.t.Helper()
+	hooksDir := filepath.Join(h.stateDir, "hooks")
+	spawner := &agent.ProductionSpawner{
+		PromptRenderer: &agent.DefaultPromptRenderer{},
+		HooksDir:       hooksDir,
+	}
+	orch := orchestrator.New(h.cfg, spawner, prompter, h.store, state.NewManager(h.stateDir))
+	orch.SetWorktreeManager(h.wt)
+	orch.SetLockManager(locks.NewManager(filepath.Join(h.stateDir, "locks")))
+	orch.SetLifecycleManager(agent.NewLifecycleManager(agent.LifecycleConfig{
+		PersistPath:       filepath.Join(h.stateDir, "agents.json"),
+		HeartbeatInterval: h.cfg.Limits.HeartbeatInterval,
+		AgentTimeout:      h.cfg.Limits.AgentTimeout,
+		AuditDir:          filepath.Join(h.stateDir, "audit"),
+	}))
+	if withHooks {
+		orch.SetHooksDir(hooksDir, filepath.Join(fakeClaudeDir, "blueflame"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	return orch, orch.Run(ctx, "Add a greeting package")
+}
+
+// invocations returns every recorded fakeclaude call.
+func (h *harness) invocations() []map[string]any {
+	h.t.Helper()
+	f, err := os.Open(filepath.Join(h.fakeDir, "invocations.jsonl"))
+	if err != nil {
+		h.t.Fatalf("open invocations: %v", err)
+	}
+	defer f.Close()
+	var out []map[string]any
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var inv map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &inv); err != nil {
+			h.t.Fatalf("parse invocation: %v", err)
+		}
+		out = append(out, inv)
+	}
+	return out
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %s: %v", args, out, err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+const twoTaskScenario = `
+responses:
+  planner:
+    - result: |
+        {"tasks":[
+          {"id":"task-001","title":"Greeting","description":"Add greet.go","priority":1,"cohesion_group":"greet","file_locks":["greet/"]},
+          {"id":"task-002","title":"Farewell","description":"Add bye.go","priority":2,"cohesion_group":"bye","file_locks":["bye/"]}
+        ]}
+      cost_usd: 0.05
+      usage: {input_tokens: 500, output_tokens: 200}
+  worker/task-001:
+    - commits:
+        - message: "feat(task-001): add greeting"
+          files:
+            greet/greet.go: "package greet\n"
+      cost_usd: 0.30
+      usage: {input_tokens: 3000, output_tokens: 900}
+  worker/task-002:
+    - commits:
+        - message: "feat(task-002): add farewell"
+          files:
+            bye/bye.go: "package bye\n"
+      cost_usd: 0.20
+      usage: {input_tokens: 2000, output_tokens: 600}
+  validator:
+    - result: '{"status":"pass","notes":"looks good"}'
+      cost_usd: 0.02
+  merger:
+    - result: "merged"
+      cost_usd: 0.01
+`
+
+func TestE2EFullWaveWithFakeClaude(t *testing.T) {
+	h := newHarness(t, twoTaskScenario)
+	prompter := &ui.ScriptedPrompter{
+		PlanDecisions:      []ui.PlanDecision{ui.PlanApprove},
+		ChangesetDecisions: []ui.ChangesetDecision{ui.ChangesetApprove, ui.ChangesetApprove},
+	}
+
+	orch, err := h.run(prompter, false)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	for _, f := range []string{"greet/greet.go", "bye/bye.go"} {
+		if _, err := os.Stat(filepath.Join(h.repo, f)); err != nil {
+			t.Errorf("%s not merged into main: %v", f, err)
+		}
+	}
+
+	if err := h.store.Load(); err != nil {
+		t.Fatalf("load tasks: %v", err)
+	}
+	for _, task := range h.store.Tasks() {
+		if task.Status != tasks.StatusMerged {
+			t.Errorf("task %s status = %q, want %q", task.ID, task.Status, tasks.StatusMerged)
+		}
+	}
+
+	// planner + 2 workers + 2 validators + 2 mergers
+	summary := orch.SessionSummary()
+	wantCost := 0.05 + 0.30 + 0.20 + 2*0.02 + 2*0.01
+	if diff := summary.TotalCost - wantCost; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("TotalCost = %f, want %f", summary.TotalCost, wantCost)
+	}
+	if summary.TotalTokens != 700+3900+2600 {
+		t.Errorf("TotalTokens = %d, want %d", summary.TotalTokens, 700+3900+2600)
+	}
+
+	roles := map[string]int{}
+	for _, inv := range h.invocations() {
+		roles[inv["role"].(string)]++
+		if inv["role"] == agent.RoleWorker && !strings.Contains(inv["dir"].(string), ".trees") {
+			t.Errorf("worker ran in %v, want a worktree", inv["dir"])
+		}
+	}
+	want := map[string]int{"planner": 1, "worker": 2, "validator": 2, "merger": 2}
+	for role, n := range want {
+		if roles[role] != n {
+			t.Errorf("%s invocations = %d, want %d", role, roles[role], n)
+		}
+	}
+}
+
+func TestE2EWorkerRetryAfterFailure(t *testing.T) {
+	h := newHarness(t, `
+responses:
+  planner:
+    - result: '{"tasks":[{"id":"task-001","title":"Greeting","description":"Add greet.go","priority":1,"file_locks":["greet/"]}]}'
+  worker/task-001:
+    - exit_code: 1
+      stderr: "simulated crash"
+      cost_usd: 0.10
+    - commits:
+        - message: "feat(task-001): add greeting"
+          files:
+            greet/greet.go: "package greet\n"
+      cost_usd: 0.30
+  validator:
+    - result: '{"status":"pass","notes":"ok"}'
+  merger:
+    - result: "merged"
+`)
+	prompter := &ui.ScriptedPrompter{
+		PlanDecisions:      []ui.PlanDecision{ui.PlanApprove},
+		ChangesetDecisions: []ui.ChangesetDecision{ui.ChangesetApprove},
+		SessionDecisions:   []ui.SessionDecision{ui.SessionContinue},
+	}
+
+	if _, err := h.run(prompter, false); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if err := h.store.Load(); err != nil {
+		t.Fatalf("load tasks: %v", err)
+	}
+	task := h.store.FindTask("task-001")
+	if task.Status != tasks.StatusMerged {
+		t.Errorf("status = %q, want %q", task.Status, tasks.StatusMerged)
+	}
+	if task.RetryCount != 1 {
+		t.Errorf("RetryCount = %d, want 1", task.RetryCount)
+	}
+	if len(task.History) != 1 || task.History[0].CostUSD != 0.10 {
+		t.Errorf("History = %+v, want one failed attempt costing 0.10", task.History)
+	}
+}
+
+func TestE2ERejectedChangesetIsRedoneFromBase(t *testing.T) {
+	h := newHarness(t, `
+responses:
+  planner:
+    - result: '{"tasks":[{"id":"task-001","title":"Greeting","description":"Add greet.go","priority":1,"file_locks":["greet/"]}]}'
+  worker/task-001:
+    - commits:
+        - message: "feat(task-001): add greeting"
+          files:
+            greet/greet.go: "package greet // first\n"
+    - commits:
+        - message: "feat(task-001): add greeting"
+          files:
+            greet/greet.go: "package greet // second\n"
+  validator:
+    - result: '{"status":"pass","notes":"ok"}'
+  merger:
+    - result: "merged"
+`)
+	prompter := &ui.ScriptedPrompter{
+		PlanDecisions:      []ui.PlanDecision{ui.PlanApprove},
+		ChangesetDecisions: []ui.ChangesetDecision{ui.ChangesetReject, ui.ChangesetApprove},
+		RejectionReasons:   []string{"wrong comment"},
+		SessionDecisions:   []ui.SessionDecision{ui.SessionContinue, ui.SessionContinue},
+	}
+
+	if _, err := h.run(prompter, false); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if err := h.store.Load(); err != nil {
+		t.Fatalf("load tasks: %v", err)
+	}
+	task := h.store.FindTask("task-001")
+	if task.Status != tasks.StatusMerged || task.RetryCount != 1 {
+		t.Fatalf("task = %s (retries %d), want merged after one redo", task.Status, task.RetryCount)
+	}
+	if len(task.History) != 1 || task.History[0].Result != "rejected" {
+		t.Errorf("History = %+v, want one rejected attempt", task.History)
+	}
+	if got := git(t, h.repo, "show", "main:greet/greet.go"); got != "package greet // second" {
+		t.Errorf("merged greet.go = %q, want the second attempt", got)
+	}
+}
+
+func TestE2EWatcherBlocksOutOfScopeWrite(t *testing.T) {
+	h := newHarness(t, `
+responses:
+  planner:
+    - result: '{"tasks":[{"id":"task-001","title":"Greeting","description":"Add greet.go","priority":1,"file_locks":["greet/"]}]}'
+  worker/task-001:
+    - commits:
+        - message: "feat(task-001): add greeting"
+          files:
+            greet/greet.go: "package greet\n"
+            secrets/leak.txt: "nope\n"
+  validator:
+    - result: '{"status":"pass","notes":"ok"}'
+  merger:
+    - result: "merged"
+`)
+	prompter := &ui.ScriptedPrompter{
+		PlanDecisions:      []ui.PlanDecision{ui.PlanApprove},
+		ChangesetDecisions: []ui.ChangesetDecision{ui.ChangesetApprove},
+	}
+
+	if _, err := h.run(prompter, true); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(h.repo, "greet/greet.go")); err != nil {
+		t.Errorf("in-scope file not merged: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(h.repo, "secrets/leak.txt")); err == nil {
+		t.Error("out-of-scope file should have been blocked by the watcher")
+	}
+
+	var blocked []any
+	var validatorArgs string
+	for _, inv := range h.invocations() {
+		switch inv["role"] {
+		case agent.RoleWorker:
+			blocked, _ = inv["blocked"].([]any)
+		case agent.RoleValidator:
+			validatorArgs = fmt.Sprint(inv["args"])
+		}
+	}
+	if len(blocked) != 1 || blocked[0] != "secrets/leak.txt" {
+		t.Errorf("blocked = %v, want [secrets/leak.txt]", blocked)
+	}
+	// The validator is told about the block.
+	if !strings.Contains(validatorArgs, "Self-reported") || !strings.Contains(validatorArgs, "Blocked:") || !strings.Contains(validatorArgs, "secrets/leak.txt") {
+		t.Errorf("validator prompt lacks the worker's audit summary: %s", validatorArgs)
+	}
+
+	logs, _ := filepath.Glob(filepath.Join(h.stateDir, "hooks", "logs", "*.audit.jsonl"))
+	if len(logs) == 0 {
+		t.Fatal("watcher wrote no audit log")
+	}
+	// Every agent's log records its environment; the worker's also has the
+	// block.
+	var data []byte
+	for _, log := range logs {
+		b, err := os.ReadFile(log)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(data, b...)
+	}
+	if !strings.Contains(string(data), `"decision":"block"`) {
+		t.Errorf("audit log missing block decision:\n%s", data)
+	}
+}
+
+func TestE2EWatcherAsksForApproval(t *testing.T) {
+	h := newHarness(t, `
+responses:
+  planner:
+    - result: '{"tasks":[{"id":"task-001","title":"Greeting","description":"Add greet.go","priority":1,"file_locks":["greet/"]}]}'
+  worker/task-001:
+    - commits:
+        - message: "feat(task-001): add greeting"
+          files:
+            greet/greet.go: "package greet\n"
+            docs/greet.md: "# Greet\n"
+  validator:
+    - result: '{"status":"pass","notes":"ok"}'
+  merger:
+    - result: "merged"
+`)
+	h.cfg.Policy.Rules = []config.PolicyRule{
+		{Name: "confirm_docs", Effect: "ask", Tools: []string{"Write"}, Paths: []string{"docs/**"}, Reason: "docs are outside the plan"},
+	}
+	prompter := &ui.ScriptedPrompter{
+		PlanDecisions:      []ui.PlanDecision{ui.PlanApprove},
+		ChangesetDecisions: []ui.ChangesetDecision{ui.ChangesetApprove},
+		ToolDecisions:      []ui.ToolApprovalDecision{ui.ToolApproveOnce},
+	}
+
+	if _, err := h.run(prompter, true); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if len(prompter.ToolRequests) != 1 {
+		t.Fatalf("tool approval requests = %+v, want one", prompter.ToolRequests)
+	}
+	if req := prompter.ToolRequests[0]; req.TaskID != "task-001" || req.Tool != "Write" || req.Target != "docs/greet.md" || req.Rule != "confirm_docs" {
+		t.Errorf("request = %+v", req)
+	}
+	if _, err := os.Stat(filepath.Join(h.repo, "docs/greet.md")); err != nil {
+		t.Errorf("approved file not merged: %v", err)
+	}
+}
@@ -0,0 +1,429 @@
+// Command fakeclaude impersonates the claude CLI for hermetic end-to-end tests.
+//
+// It accepts the flags ProductionSpawner passes, identifies the calling agent
+// from the BLUEFLAME_ROLE / BLUEFLAME_TASK_ID environment (falling back to the
+// prompt text), and replays a response from a YAML scenario file:
+//
+//	responses:
+//	  planner:
+//	    - result: '{"tasks":[...]}'
+//	      cost_usd: 0.10
+//	  worker/task-001:
+//	    - exit_code: 1
+//	      stderr: "simulated crash"
+//	    - commits:
+//	        - message: "feat(task-001): add auth"
+//	          files:
+//	            pkg/auth/auth.go: "package auth\n"
+//	      usage: {input_tokens: 1200, output_tokens: 300}
+//	  validator/task-001:
+//	    - result: '{"status":"pass","notes":"ok"}'
+//
+// Responses are keyed by "role/task" with a fallback to "role". Repeated
+// invocations for the same key consume the list in order; the last response
+// is reused once the list is exhausted.
+//
+// Environment:
+//
+//	BLUEFLAME_FAKE_SCENARIO   path to the scenario file (required)
+//	BLUEFLAME_FAKE_STATE_DIR  directory for call counters and invocations.jsonl
+//	                          (defaults to the scenario file's directory)
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	envScenario = "BLUEFLAME_FAKE_SCENARIO"
+	envStateDir = "BLUEFLAME_FAKE_STATE_DIR"
+
+	envAgentID = "BLUEFLAME_AGENT_ID"
+	envRole    = "BLUEFLAME_ROLE"
+	envTaskID  = "BLUEFLAME_TASK_ID"
+)
+
+// Scenario is the top-level scenario file.
+type Scenario struct {
+	Responses map[string][]Response `yaml:"responses"`
+}
+
+// Response describes what a single fake invocation does.
+type Response struct {
+	// Files are written to the working directory without being committed.
+	Files map[string]string `yaml:"files"`
+	// Commits are made in order, each staging only its own files.
+	Commits  []Commit       `yaml:"commits"`
+	ExitCode int            `yaml:"exit_code"`
+	Result   string         `yaml:"result"`
+	IsError  bool           `yaml:"is_error"`
+	CostUSD  float64        `yaml:"cost_usd"`
+	NumTurns int            `yaml:"num_turns"`
+	Usage    map[string]any `yaml:"usage"`
+	Stderr   string         `yaml:"stderr"`
+	Delay    time.Duration  `yaml:"delay"`
+	// Raw, when set, is printed verbatim instead of the JSON envelope.
+	Raw string `yaml:"raw"`
+}
+
+// Commit is a commit the fake agent makes in its working directory.
+type Commit struct {
+	Message string            `yaml:"message"`
+	Files   map[string]string `yaml:"files"`
+}
+
+// Invocation is appended to invocations.jsonl for every call.
+type Invocation struct {
+	Role     string   `json:"role"`
+	TaskID   string   `json:"task_id,omitempty"`
+	AgentID  string   `json:"agent_id,omitempty"`
+	Key      string   `json:"key"`
+	Call     int      `json:"call"`
+	Dir      string   `json:"dir"`
+	Model    string   `json:"model"`
+	Args     []string `json:"args"`
+	Blocked  []string `json:"blocked,omitempty"`
+	ExitCode int      `json:"exit_code"`
+}
+
+// options holds the claude CLI flags that ProductionSpawner passes.
+type options struct {
+	print           bool
+	model           string
+	allowedTools    string
+	disallowedTools string
+	outputFormat    string
+	maxBudgetUSD    float64
+	maxTokens       int
+	systemPrompt    string
+	prompt          string
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	opts, err := parseFlags(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fakeclaude: %v\n", err)
+		return 2
+	}
+
+	scenarioPath := os.Getenv(envScenario)
+	if scenarioPath == "" {
+		fmt.Fprintf(os.Stderr, "fakeclaude: %s is not set\n", envScenario)
+		return 2
+	}
+	scenario, err := loadScenario(scenarioPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fakeclaude: %v\n", err)
+		return 2
+	}
+	stateDir := os.Getenv(envStateDir)
+	if stateDir == "" {
+		stateDir = filepath.Dir(scenarioPath)
+	}
+
+	role, taskID := identify(opts.prompt)
+	key, responses := scenario.lookup(role, taskID)
+	if len(responses) == 0 {
+		fmt.Fprintf(os.Stderr, "fakeclaude: no scenario response for role=%q task=%q\n", role, taskID)
+		return 2
+	}
+
+	call, err := nextCall(stateDir, key)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fakeclaude: %v\n", err)
+		return 2
+	}
+	resp := responses[min(call, len(responses)-1)]
+
+	dir, _ := os.Getwd()
+	inv := Invocation{
+		Role:    role,
+		TaskID:  taskID,
+		AgentID: os.Getenv(envAgentID),
+		Key:     key,
+		Call:    call,
+		Dir:     dir,
+		Model:   opts.model,
+		Args:    args,
+	}
+
+	if resp.Delay > 0 {
+		time.Sleep(resp.Delay)
+	}
+
+	exitCode, err := apply(resp, &inv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fakeclaude: %v\n", err)
+		exitCode = 1
+	}
+	inv.ExitCode = exitCode
+	if err := recordInvocation(stateDir, inv); err != nil {
+		fmt.Fprintf(os.Stderr, "fakeclaude: record invocation: %v\n", err)
+	}
+
+	if resp.Stderr != "" {
+		fmt.Fprintln(os.Stderr, resp.Stderr)
+	}
+	if opts.outputFormat == "json" || resp.Raw != "" {
+		writeOutput(resp, exitCode)
+	} else {
+		fmt.Println(resp.Result)
+	}
+	return exitCode
+}
+
+func parseFlags(args []string) (*options, error) {
+	var opts options
+	fs := flag.NewFlagSet("claude", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.BoolVar(&opts.print, "print", false, "non-interactive mode")
+	fs.StringVar(&opts.model, "model", "", "model")
+	fs.StringVar(&opts.allowedTools, "allowed-tools", "", "allowed tools")
+	fs.StringVar(&opts.disallowedTools, "disallowed-tools", "", "disallowed tools")
+	fs.StringVar(&opts.outputFormat, "output-format", "text", "output format")
+	fs.Float64Var(&opts.maxBudgetUSD, "max-budget-usd", 0, "budget in USD")
+	fs.IntVar(&opts.maxTokens, "max-tokens", 0, "token budget")
+	fs.StringVar(&opts.systemPrompt, "system-prompt", "", "system prompt")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != 1 {
+		return nil, fmt.Errorf("expected exactly one prompt argument, got %d", fs.NArg())
+	}
+	opts.prompt = fs.Arg(0)
+	return &opts, nil
+}
+
+var (
+	workerPromptRE    = regexp.MustCompile(`Implement task (\S+?):`)
+	validatorPromptRE = regexp.MustCompile(`Validate task (\S+?):`)
+)
+
+// identify determines the calling agent's role and task, preferring the
+// environment set by ProductionSpawner.
+func identify(prompt string) (role, taskID string) {
+	if role = os.Getenv(envRole); role != "" {
+		return role, os.Getenv(envTaskID)
+	}
+	if m := workerPromptRE.FindStringSubmatch(prompt); m != nil {
+		return "worker", m[1]
+	}
+	if m := validatorPromptRE.FindStringSubmatch(prompt); m != nil {
+		return "validator", m[1]
+	}
+	if strings.HasPrefix(prompt, "Merge ") {
+		return "merger", ""
+	}
+	return "planner", ""
+}
+
+func loadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read scenario: %w", err)
+	}
+	var s Scenario
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parse scenario: %w", err)
+	}
+	return &s, nil
+}
+
+func (s *Scenario) lookup(role, taskID string) (string, []Response) {
+	if taskID != "" {
+		key := role + "/" + taskID
+		if r, ok := s.Responses[key]; ok {
+			return key, r
+		}
+	}
+	return role, s.Responses[role]
+}
+
+// nextCall returns the zero-based call index for key and advances the counter.
+func nextCall(stateDir, key string) (int, error) {
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		return 0, fmt.Errorf("create state dir: %w", err)
+	}
+	path := filepath.Join(stateDir, strings.ReplaceAll(key, "/", "__")+".calls")
+	n := 0
+	if data, err := os.ReadFile(path); err == nil {
+		n, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+	}
+	if err := os.WriteFile(path, []byte(strconv.Itoa(n+1)), 0o644); err != nil {
+		return 0, fmt.Errorf("write call counter: %w", err)
+	}
+	return n, nil
+}
+
+// apply performs the response's side effects in the working directory.
+func apply(resp Response, inv *Invocation) (int, error) {
+	if _, err := writeFiles(resp.Files, inv); err != nil {
+		return 1, err
+	}
+	for _, c := range resp.Commits {
+		written, err := writeFiles(c.Files, inv)
+		if err != nil {
+			return 1, err
+		}
+		if len(written) == 0 {
+			continue
+		}
+		if err := gitCommit(c.Message, written); err != nil {
+			return 1, err
+		}
+	}
+	return resp.ExitCode, nil
+}
+
+// writeFiles writes files after consulting the PreToolUse hooks from
+// .claude/settings.json, the same way the real CLI would for a Write call.
+// Files the hooks block are skipped and recorded on the invocation.
+func writeFiles(files map[string]string, inv *Invocation) ([]string, error) {
+	var written []string
+	for path, content := range files {
+		allowed, err := preToolUse("Write", map[string]any{"file_path": path, "content": content})
+		if err != nil {
+			return written, err
+		}
+		if !allowed {
+			inv.Blocked = append(inv.Blocked, path)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return written, fmt.Errorf("create dir for %s: %w", path, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return written, fmt.Errorf("write %s: %w", path, err)
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+type hookSettings struct {
+	Hooks struct {
+		PreToolUse []struct {
+			Type    string `json:"type"`
+			Command string `json:"command"`
+		} `json:"PreToolUse"`
+	} `json:"hooks"`
+}
+
+// preToolUse runs the configured PreToolUse hooks. A hook exiting 2 blocks
+// the tool call; any other non-zero exit is treated as a hook error.
+func preToolUse(tool string, input map[string]any) (bool, error) {
+	data, err := os.ReadFile(filepath.Join(".claude", "settings.json"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return true, nil
+		}
+		return false, fmt.Errorf("read settings: %w", err)
+	}
+	var settings hookSettings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return false, fmt.Errorf("parse settings: %w", err)
+	}
+
+	payload, err := json.Marshal(map[string]any{"tool_name": tool, "tool_input": input})
+	if err != nil {
+		return false, err
+	}
+	for _, h := range settings.Hooks.PreToolUse {
+		if h.Type != "command" || h.Command == "" {
+			continue
+		}
+		cmd := exec.Command("sh", "-c", h.Command)
+		cmd.Stdin = bytes.NewReader(payload)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		err := cmd.Run()
+		var exitErr *exec.ExitError
+		switch {
+		case err == nil:
+		case errors.As(err, &exitErr) && exitErr.ExitCode() == 2:
+			return false, nil
+		default:
+			return false, fmt.Errorf("hook %s: %s: %w", h.Command, strings.TrimSpace(stderr.String()), err)
+		}
+	}
+	return true, nil
+}
+
+func gitCommit(message string, files []string) error {
+	add := exec.Command("git", append([]string{"add", "--"}, files...)...)
+	if out, err := add.CombinedOutput(); err != nil {
+		return fmt.Errorf("git add: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	commit := exec.Command("git",
+		"-c", "user.name=fakeclaude",
+		"-c", "user.email=fakeclaude@example.invalid",
+		"commit", "-m", message)
+	if out, err := commit.CombinedOutput(); err != nil {
+		return fmt.Errorf("git commit: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
+
+func recordInvocation(stateDir string, inv Invocation) error {
+	data, err := json.Marshal(inv)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(stateDir, "invocations.jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// writeOutput prints the --output-format json envelope.
+func writeOutput(resp Response, exitCode int) {
+	if resp.Raw != "" {
+		fmt.Print(resp.Raw)
+		return
+	}
+	subtype := "success"
+	if exitCode != 0 || resp.IsError {
+		subtype = "error_during_execution"
+	}
+	usage := resp.Usage
+	if usage == nil {
+		usage = map[string]any{"input_tokens": 0, "output_tokens": 0}
+	}
+	numTurns := resp.NumTurns
+	if numTurns == 0 {
+		numTurns = 1
+	}
+	out := map[string]any{
+		"type":           "result",
+		"subtype":        subtype,
+		"result":         resp.Result,
+		"is_error":       resp.IsError || exitCode != 0,
+		"total_cost_usd": resp.CostUSD,
+		"duration_ms":    int(resp.Delay.Milliseconds()),
+		"num_turns":      numTurns,
+		"usage":          usage,
+		"session_id":     "fake-" + strconv.Itoa(os.Getpid()),
+	}
+	data, _ := json.Marshal(out)
+	fmt.Println(string(data))
+}